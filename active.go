@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// activeSession is a session that has been started but not yet stopped.
+type activeSession struct {
+	project  string
+	note     string
+	tags     []string
+	start    time.Time
+	pauses   []pause
+	billable bool
+	issue    string
+	target   time.Duration // countdown target, 0 for none
+
+	interruptions []track.Interruption
+}
+
+// activeRecord is the on-disk layout of activeFile.
+type activeRecord struct {
+	Project string              `json:"project,omitempty"`
+	Note    string              `json:"note,omitempty"`
+	Tags    []string            `json:"tags,omitempty"`
+	Start   time.Time           `json:"start"`
+	Pauses  []track.PauseRecord `json:"pauses,omitempty"`
+	// Billable is nil for sessions started before the flag existed, which
+	// count as billable.
+	Billable *bool  `json:"billable,omitempty"`
+	Issue    string `json:"issue,omitempty"`
+	// Target is the countdown target, e.g. "45m0s".
+	Target        string                     `json:"target,omitempty"`
+	Interruptions []track.InterruptionRecord `json:"interruptions,omitempty"`
+}
+
+// paused reports whether the session is currently paused.
+func (a activeSession) paused() bool {
+	return len(a.pauses) > 0 && a.pauses[len(a.pauses)-1].End.IsZero()
+}
+
+// elapsed is the tracked time at now, excluding pauses.
+func (a activeSession) elapsed(now time.Time) time.Duration {
+	return now.Sub(a.start) - track.PausedTotal(a.pauses, now)
+}
+
+// finish turns the active session into a completed session ending at now,
+// closing any open pause.
+func (a activeSession) finish(now time.Time) session {
+	pauses := append([]pause{}, a.pauses...)
+	if a.paused() {
+		pauses[len(pauses)-1].End = now
+	}
+	return session{
+		ID:       track.NewID(),
+		Project:  a.project,
+		Note:     a.note,
+		Tags:     a.tags,
+		Start:    a.start,
+		End:      now,
+		Duration: a.elapsed(now),
+		Pauses:   pauses,
+		Billable: a.billable,
+		Issue:    a.issue,
+
+		Interruptions: slices.Clone(a.interruptions),
+	}
+}
+
+// record converts a to its on-disk layout.
+func (a activeSession) record() activeRecord {
+	rec := activeRecord{
+		Project:  a.project,
+		Note:     a.note,
+		Tags:     a.tags,
+		Start:    a.start,
+		Billable: &a.billable,
+		Issue:    a.issue,
+	}
+	if a.target > 0 {
+		rec.Target = a.target.String()
+	}
+	for _, p := range a.pauses {
+		rec.Pauses = append(rec.Pauses, track.PauseRecord{Start: p.Start, End: p.End})
+	}
+	for _, in := range a.interruptions {
+		rec.Interruptions = append(rec.Interruptions, track.InterruptionRecord{At: in.At, Note: in.Note})
+	}
+	return rec
+}
+
+// session converts rec back to an activeSession. A nil rec means nothing is
+// being tracked.
+func (rec *activeRecord) session() *activeSession {
+	if rec == nil {
+		return nil
+	}
+	a := &activeSession{
+		project:  rec.Project,
+		note:     rec.Note,
+		tags:     rec.Tags,
+		start:    rec.Start.Local(),
+		billable: rec.Billable == nil || *rec.Billable,
+		issue:    rec.Issue,
+	}
+	a.target, _ = time.ParseDuration(rec.Target)
+	for _, p := range rec.Pauses {
+		a.pauses = append(a.pauses, pause{Start: p.Start, End: p.End})
+	}
+	a.pauses = track.PausesInLocal(a.pauses)
+	for _, in := range rec.Interruptions {
+		a.interruptions = append(a.interruptions, track.Interruption{At: in.At, Note: in.Note})
+	}
+	a.interruptions = track.InterruptionsInLocal(a.interruptions)
+	return a
+}
+
+// loadActive returns the running session, asking the daemon if one is
+// running and reading activeFile otherwise. It returns nil if nothing is
+// being tracked.
+func loadActive() (*activeSession, error) {
+	resp, ok, err := callDaemon(daemonRequest{Op: "get"})
+	if !ok {
+		return loadActiveFile()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Active.session(), nil
+}
+
+// saveActive records a as the running session, through the daemon if one is
+// running.
+func saveActive(a activeSession) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	rec := a.record()
+	_, ok, err := callDaemon(daemonRequest{Op: "save", Active: &rec})
+	if !ok {
+		return saveActiveFile(a)
+	}
+	return err
+}
+
+// clearActive ends the running session, through the daemon if one is
+// running.
+func clearActive() error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	_, ok, err := callDaemon(daemonRequest{Op: "clear"})
+	if !ok {
+		return clearActiveFile()
+	}
+	return err
+}
+
+// loadActiveFile reads the running session from activeFile. It returns nil
+// if nothing is being tracked.
+func loadActiveFile() (*activeSession, error) {
+	data, err := readData(activeFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rec activeRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", activeFile, err)
+	}
+
+	return rec.session(), nil
+}
+
+// saveActiveFile records a as the running session in activeFile.
+func saveActiveFile(a activeSession) error {
+	data, err := json.MarshalIndent(a.record(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeData(activeFile, data)
+}
+
+// clearActiveFile removes activeFile, if present.
+func clearActiveFile() error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	err := os.Remove(activeFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+const defaultActivityWatchURL = "http://localhost:5600"
+
+// defaultActivityMinutes is the shortest stretch of work suggested as a
+// session unless configured otherwise.
+const defaultActivityMinutes = 5
+
+// activityWatchConfig is the "activitywatch" section of config.json, for
+// suggesting sessions from what an ActivityWatch server saw on screen.
+type activityWatchConfig struct {
+	// URL is the server, by default the one ActivityWatch runs locally.
+	URL string `json:"url,omitempty"`
+	// Hostname names the machine whose buckets are read, by default this
+	// one.
+	Hostname string `json:"hostname,omitempty"`
+	// Rules sort window events into sessions: the first rule matching an
+	// event decides its project. Events no rule matches are grouped by app.
+	Rules []activityRule `json:"rules,omitempty"`
+	// MinMinutes is the shortest stretch of work suggested as a session,
+	// and the longest break or detour to another app that does not end one.
+	MinMinutes int `json:"min_minutes,omitempty"`
+}
+
+// activityRule matches window events by app and title.
+type activityRule struct {
+	// App and Title are regular expressions; an empty one matches
+	// anything.
+	App     string   `json:"app,omitempty"`
+	Title   string   `json:"title,omitempty"`
+	Project string   `json:"project,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	// Ignore drops the events the rule matches, as if away.
+	Ignore bool `json:"ignore,omitempty"`
+
+	app, title *regexp.Regexp // compiled App and Title
+}
+
+// parse checks the rule and compiles its patterns.
+func (r *activityRule) parse() error {
+	if r.App == "" && r.Title == "" {
+		return errors.New("activitywatch: rule without an app or title")
+	}
+	var err error
+	if r.app, err = regexp.Compile(r.App); err != nil {
+		return fmt.Errorf("activitywatch: rule app %q: %w", r.App, err)
+	}
+	if r.title, err = regexp.Compile(r.Title); err != nil {
+		return fmt.Errorf("activitywatch: rule title %q: %w", r.Title, err)
+	}
+	return nil
+}
+
+func checkActivityWatch(cfg *activityWatchConfig) error {
+	if cfg.MinMinutes < 0 {
+		return fmt.Errorf("activitywatch: negative min_minutes %d", cfg.MinMinutes)
+	}
+	for i := range cfg.Rules {
+		if err := cfg.Rules[i].parse(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// minGap returns the shortest stretch of work suggested as a session.
+func (c activityWatchConfig) minGap() time.Duration {
+	if c.MinMinutes == 0 {
+		return defaultActivityMinutes * time.Minute
+	}
+	return time.Duration(c.MinMinutes) * time.Minute
+}
+
+// rule returns the index of the first rule matching a window of app titled
+// title, or -1.
+func (c activityWatchConfig) rule(app, title string) int {
+	return slices.IndexFunc(c.Rules, func(r activityRule) bool {
+		return r.app.MatchString(app) && r.title.MatchString(title)
+	})
+}
+
+// awEvent is an event in an ActivityWatch bucket.
+type awEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Duration  float64   `json:"duration"` // seconds
+	Data      struct {
+		App    string `json:"app"`
+		Title  string `json:"title"`
+		Status string `json:"status"` // "afk" or "not-afk"
+	} `json:"data"`
+}
+
+func (e awEvent) end() time.Time {
+	return e.Timestamp.Add(time.Duration(e.Duration * float64(time.Second)))
+}
+
+var activityWatchClient = &http.Client{Timeout: 30 * time.Second}
+
+// awGet fetches path from the server and decodes the JSON response into
+// out.
+func awGet(base, path string, out any) error {
+	resp, err := activityWatchClient.Get(base + "/api/0" + path)
+	if err != nil {
+		return fmt.Errorf("activitywatch: %w (is it running?)", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("activitywatch: GET %s: %s: %s", path, resp.Status, msg)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// awEvents fetches the window and AFK events of the configured machine
+// within r. The AFK events are nil if it has no AFK watcher.
+func awEvents(cfg activityWatchConfig, r dateRange) (windows, afk []awEvent, err error) {
+	base := cfg.URL
+	if base == "" {
+		base = defaultActivityWatchURL
+	}
+	host := cfg.Hostname
+	if host == "" {
+		if host, err = os.Hostname(); err != nil {
+			return nil, nil, err
+		}
+	}
+	var buckets map[string]struct {
+		Type     string `json:"type"`
+		Hostname string `json:"hostname"`
+	}
+	if err := awGet(base, "/buckets/", &buckets); err != nil {
+		return nil, nil, err
+	}
+	q := url.Values{"limit": {"-1"}}
+	if !r.From.IsZero() {
+		q.Set("start", r.From.Format(time.RFC3339))
+	}
+	if !r.To.IsZero() {
+		q.Set("end", r.To.Format(time.RFC3339))
+	}
+	found := false
+	for id, b := range buckets {
+		if b.Hostname != host || (b.Type != "currentwindow" && b.Type != "afkstatus") {
+			continue
+		}
+		var events []awEvent
+		if err := awGet(base, "/buckets/"+url.PathEscape(id)+"/events?"+q.Encode(), &events); err != nil {
+			return nil, nil, err
+		}
+		if b.Type == "currentwindow" {
+			windows, found = append(windows, events...), true
+		} else {
+			afk = append(afk, events...)
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("activitywatch: no window watcher for host %q; set activitywatch.hostname", host)
+	}
+	return windows, afk, nil
+}
+
+// activityPiece is a stretch of time spent in one window while not away.
+type activityPiece struct {
+	start, end time.Time
+	group      string // rule index or app the piece is grouped by
+	rule       int
+	app, title string
+}
+
+// activityPieces cuts the window events down to the time within r not
+// spent away, in start order, dropping those an ignore rule matches.
+func activityPieces(windows, afk []awEvent, r dateRange, cfg activityWatchConfig) []activityPiece {
+	var active []pause
+	for _, e := range afk {
+		if e.Data.Status == "not-afk" {
+			active = append(active, pause{Start: e.Timestamp, End: e.end()})
+		}
+	}
+
+	var pieces []activityPiece
+	for _, e := range windows {
+		rule := cfg.rule(e.Data.App, e.Data.Title)
+		if rule >= 0 && cfg.Rules[rule].Ignore {
+			continue
+		}
+		group := "app:" + e.Data.App
+		if rule >= 0 {
+			group = fmt.Sprint("rule:", rule)
+		}
+		spans := active
+		if afk == nil {
+			spans = []pause{{Start: e.Timestamp, End: e.end()}}
+		}
+		for _, a := range spans {
+			start, end := later(later(e.Timestamp, a.Start), r.From), earliest(e.end(), a.End, r.To)
+			if start.Before(end) {
+				pieces = append(pieces, activityPiece{start.Local(), end.Local(), group, rule, e.Data.App, e.Data.Title})
+			}
+		}
+	}
+	sort.Slice(pieces, func(i, j int) bool { return pieces[i].start.Before(pieces[j].start) })
+	return pieces
+}
+
+// earliest returns the earliest of times, ignoring zero ones.
+func earliest(times ...time.Time) time.Time {
+	var t time.Time
+	for _, u := range times {
+		if !u.IsZero() && (t.IsZero() || u.Before(t)) {
+			t = u
+		}
+	}
+	return t
+}
+
+// activityBlock is a run of pieces of one group, with the breaks between
+// them.
+type activityBlock struct {
+	first  activityPiece
+	end    time.Time
+	breaks []track.Pause
+	titles map[string]time.Duration
+}
+
+// suggestSessions joins pieces into sessions: pieces of one group less than
+// the minimum gap apart, and shorter detours to other windows between them,
+// make one session, and sessions shorter than the minimum are dropped.
+func suggestSessions(pieces []activityPiece, cfg config) []session {
+	gap := cfg.ActivityWatch.minGap()
+	var blocks []*activityBlock
+	var cur *activityBlock
+	for _, p := range pieces {
+		if cur != nil && p.start.Sub(cur.end) <= gap {
+			if p.group == cur.first.group {
+				if p.start.After(cur.end) {
+					cur.breaks = append(cur.breaks, track.Pause{Start: cur.end, End: p.start})
+				}
+				cur.end = later(cur.end, p.end)
+				cur.titles[p.title] += p.end.Sub(p.start)
+				continue
+			}
+			if p.end.Sub(p.start) < gap {
+				cur.end = later(cur.end, p.end)
+				continue
+			}
+		}
+		cur = &activityBlock{first: p, end: p.end, titles: map[string]time.Duration{p.title: p.end.Sub(p.start)}}
+		blocks = append(blocks, cur)
+	}
+
+	var sessions []session
+	for _, b := range blocks {
+		sess := session{ID: track.NewID(), Pauses: b.breaks}
+		if b.first.rule >= 0 {
+			rule := cfg.ActivityWatch.Rules[b.first.rule]
+			sess.Project, sess.Tags = rule.Project, slices.Clone(rule.Tags)
+		}
+		// The note is the title the most time went to.
+		var most time.Duration
+		for title, d := range b.titles {
+			if d > most || (d == most && title < sess.Note) {
+				sess.Note, most = title, d
+			}
+		}
+		title := sess.Note
+		if sess.Note == "" {
+			sess.Note = b.first.app
+		}
+		sess.Billable = cfg.billableByDefault(sess.Project)
+		cfg.categorize(&sess, title, "")
+		sess.SetBounds(b.first.start, b.end)
+		if sess.Duration >= gap {
+			sessions = append(sessions, sess)
+		}
+	}
+	return sessions
+}
+
+// runActivityWatch offers sessions suggested from ActivityWatch events for
+// the time not tracked yet, asking about each before adding it.
+func runActivityWatch(storageKind string, args []string) error {
+	if len(args) == 0 || args[0] != "pull" {
+		return errors.New("usage: activitywatch pull [-range RANGE] [-dry-run] [-yes]")
+	}
+	fs := flag.NewFlagSet("activitywatch pull", flag.ExitOnError)
+	rangeSpec := fs.String("range", "today", "pull today, week, month, last-month or FROM..TO")
+	dryRun := fs.Bool("dry-run", false, "only list the suggested sessions")
+	yes := fs.Bool("yes", false, "add every suggested session without asking")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	dates, err := parseRange(*rangeSpec, time.Now())
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	windows, afk, err := awEvents(cfg.ActivityWatch, dates)
+	if err != nil {
+		return err
+	}
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	// Time already tracked is not suggested again.
+	var suggested []session
+	for _, sess := range suggestSessions(activityPieces(windows, afk, dates, cfg.ActivityWatch), cfg) {
+		if !slices.ContainsFunc(history, func(h session) bool { return overlaps(h, sess) }) {
+			suggested = append(suggested, sess)
+		}
+	}
+	if *dryRun {
+		for _, sess := range suggested {
+			fmt.Println(describeSuggestion(sess, cfg))
+		}
+		fmt.Printf("Would offer %d sessions\n", len(suggested))
+		return nil
+	}
+	confirmed, err := confirmSessions(suggested, *yes, cfg)
+	if err != nil {
+		return err
+	}
+	if len(confirmed) == 0 {
+		fmt.Println("No sessions added")
+		return nil
+	}
+	history, _ = mergeSessions(history, confirmed, importTolerance, false)
+	if err := storage.Save(history); err != nil {
+		return err
+	}
+	if err := writeReport(historyFile, history, cfg); err != nil {
+		return err
+	}
+	fmt.Printf("Added %d of %d sessions from ActivityWatch\n", len(confirmed), len(suggested))
+	return nil
+}
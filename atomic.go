@@ -0,0 +1,17 @@
+package main
+
+import (
+	"os"
+
+	"time-tracking/pkg/track"
+)
+
+// writeFileAtomic writes data to path with track.WriteFileAtomic, so a crash
+// part way through leaves either the old file or the new one, unless nothing
+// may be written.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	return track.WriteFileAtomic(path, data, perm)
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// Actions recorded in the audit log.
+const (
+	auditEdit    = "edit"
+	auditDelete  = "delete"
+	auditMerge   = "merge"
+	auditSplit   = "split"
+	auditTrim    = "trim"
+	auditClear   = "clear"
+	auditRestore = "restore"
+	auditPurge   = "purge"
+	auditRepair  = "repair"
+)
+
+// auditEntry is one change to stored sessions: the sessions as they were
+// before it and as they are after. Deletions have no after, and edits have
+// one session on each side.
+type auditEntry struct {
+	Time   time.Time      `json:"time"`
+	Action string         `json:"action"`
+	Before []track.Record `json:"before,omitempty"`
+	After  []track.Record `json:"after,omitempty"`
+}
+
+// audit queues a change for the audit log. It is written along with the
+// history, so changes that are discarded unsaved never reach the log.
+func (m *model) audit(action string, before, after []session) {
+	entry := auditEntry{Time: time.Now().Round(0), Action: action}
+	for _, sess := range before {
+		entry.Before = append(entry.Before, track.ToRecord(sess))
+	}
+	for _, sess := range after {
+		entry.After = append(entry.After, track.ToRecord(sess))
+	}
+	m.unaudited = append(m.unaudited, entry)
+}
+
+// auditEdited queues an edit of the session at index i, which was before, if
+// it actually changed anything.
+func (m *model) auditEdited(i int, before session) {
+	if !identical(before, m.history[i]) {
+		m.audit(auditEdit, []session{before}, []session{m.history[i]})
+	}
+}
+
+// identical reports whether a and b agree in every field that is stored.
+func identical(a, b session) bool {
+	return a.ID == b.ID && a.Project == b.Project && a.Note == b.Note && slices.Equal(a.Tags, b.Tags) &&
+		a.Start.Equal(b.Start) && a.End.Equal(b.End) && a.Billable == b.Billable && a.Issue == b.Issue &&
+		slices.EqualFunc(a.Pauses, b.Pauses, func(p, q pause) bool { return p.Start.Equal(q.Start) && p.End.Equal(q.End) }) &&
+		slices.EqualFunc(a.Interruptions, b.Interruptions, func(p, q track.Interruption) bool { return p.At.Equal(q.At) && p.Note == q.Note })
+}
+
+// flushAudit appends the queued changes to auditFile.
+func (m *model) flushAudit() error {
+	if len(m.unaudited) == 0 {
+		return nil
+	}
+	entries := m.unaudited
+	m.unaudited = nil
+	return appendAudit(auditFile, entries)
+}
+
+// appendAudit adds entries to the end of the audit log at path, one JSON
+// object per line. The log is only ever appended to.
+func appendAudit(path string, entries []auditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	data, err := encodeAudit(entries)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// encodeAudit renders entries as lines of the audit log, encrypted one by
+// one if the history is.
+func encodeAudit(entries []auditEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(sealLine(data))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// loadAudit reads the audit log at path, oldest entry first. A missing log
+// has no entries.
+func loadAudit(path string) ([]auditEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []auditEntry
+	for n, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		data, err := openLine([]byte(line))
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, n+1, err)
+		}
+		var entry auditEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, n+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// describeRecord renders a session from the audit log on one line.
+func (c config) describeRecord(rec track.Record) string {
+	sess := track.FromRecord(rec)
+	return fmt.Sprintf("%s, %s – %s (%s)", track.ProjectLabel(sess.Project), sess.Start.Format("Jan 02 15:04"),
+		sess.End.Format("15:04"), c.duration(sess.Duration))
+}
+
+// auditChanges lists what an edit changed, e.g. `note: "" → "standup"`.
+func (c config) auditChanges(before, after track.Record) []string {
+	was, is := track.FromRecord(before), track.FromRecord(after)
+	var changes []string
+	if was.Project != is.Project {
+		changes = append(changes, fmt.Sprintf("project: %s → %s", track.ProjectLabel(was.Project), track.ProjectLabel(is.Project)))
+	}
+	if !was.Start.Equal(is.Start) || !was.End.Equal(is.End) {
+		changes = append(changes, fmt.Sprintf("time: %s – %s → %s – %s", was.Start.Format("Jan 02 15:04"), was.End.Format("15:04"),
+			is.Start.Format("Jan 02 15:04"), is.End.Format("15:04")))
+	}
+	if !slices.Equal(was.Tags, is.Tags) {
+		changes = append(changes, fmt.Sprintf("tags: %q → %q", track.FormatTags(was.Tags), track.FormatTags(is.Tags)))
+	}
+	if was.Note != is.Note {
+		changes = append(changes, fmt.Sprintf("note: %q → %q", was.Note, is.Note))
+	}
+	if was.Issue != is.Issue {
+		changes = append(changes, fmt.Sprintf("issue: %q → %q", issueRef(was.Issue), issueRef(is.Issue)))
+	}
+	if was.Billable != is.Billable {
+		changes = append(changes, fmt.Sprintf("billable: %t → %t", was.Billable, is.Billable))
+	}
+	if len(was.Pauses) != len(is.Pauses) {
+		changes = append(changes, fmt.Sprintf("pauses: %d → %d", len(was.Pauses), len(is.Pauses)))
+	}
+	return changes
+}
+
+// auditLines renders the audit log newest first, one header line per entry
+// followed by the sessions or fields it changed.
+func (m model) auditLines() []string {
+	var lines []string
+	for i := len(m.auditEntries) - 1; i >= 0; i-- {
+		entry := m.auditEntries[i]
+		lines = append(lines, projectHeaderStyle.Render(fmt.Sprintf("%s  %s", entry.Time.Local().Format("Mon Jan 02 15:04:05"), entry.Action)))
+		if entry.Action == auditEdit && len(entry.Before) == 1 && len(entry.After) == 1 {
+			lines = append(lines, historyItemStyle.Render("  "+m.config.describeRecord(entry.Before[0])))
+			for _, change := range m.config.auditChanges(entry.Before[0], entry.After[0]) {
+				lines = append(lines, normalStyle.Render("    "+change))
+			}
+			continue
+		}
+		for _, rec := range entry.Before {
+			lines = append(lines, historyItemStyle.Render("  − "+m.config.describeRecord(rec)))
+		}
+		for _, rec := range entry.After {
+			lines = append(lines, normalStyle.Render("  + "+m.config.describeRecord(rec)))
+		}
+	}
+	return lines
+}
+
+// openAudit shows the audit log, read afresh so it includes changes written
+// since it was last shown.
+func (m model) openAudit() (tea.Model, tea.Cmd) {
+	entries, err := loadAudit(auditFile)
+	if err != nil {
+		m.status = err.Error()
+		return m, nil
+	}
+	m.auditEntries = entries
+	m.auditOffset = 0
+	m.currentView = auditView
+	return m, nil
+}
+
+// auditPageSize is how many audit lines fit on screen, or all of them until
+// the terminal size is known.
+func (m model) auditPageSize() int {
+	if m.height == 0 {
+		return len(m.auditLines())
+	}
+	return max(1, m.height-8)
+}
+
+func (m model) updateAudit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	last := max(0, len(m.auditLines())-m.auditPageSize())
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Back):
+		m.currentView = menuView
+		m.cursor = 0
+		m.status = ""
+	case key.Matches(msg, m.keys.Up):
+		m.auditOffset = max(0, m.auditOffset-1)
+	case key.Matches(msg, m.keys.Down):
+		m.auditOffset = min(last, m.auditOffset+1)
+	case key.Matches(msg, m.keys.PageUp):
+		m.auditOffset = max(0, m.auditOffset-m.auditPageSize())
+	case key.Matches(msg, m.keys.PageDown):
+		m.auditOffset = min(last, m.auditOffset+m.auditPageSize())
+	case key.Matches(msg, m.keys.Top):
+		m.auditOffset = 0
+	case key.Matches(msg, m.keys.Bottom):
+		m.auditOffset = last
+	}
+	return m, nil
+}
+
+func (m model) viewAudit() string {
+	s := titleStyle.Render("📜 Audit log") + "\n\n"
+
+	lines := m.auditLines()
+	if len(lines) == 0 {
+		s += normalStyle.Render("No edits or deletions yet.") + "\n"
+	}
+	end := min(len(lines), m.auditOffset+m.auditPageSize())
+	s += strings.Join(lines[min(m.auditOffset, end):end], "\n") + "\n"
+	if len(lines) > end-m.auditOffset {
+		s += "\n" + helpStyle.Render(fmt.Sprintf("lines %d–%d of %d", m.auditOffset+1, end, len(lines))) + "\n"
+	}
+
+	if m.status != "" {
+		s += "\n" + normalStyle.Render(m.status) + "\n"
+	}
+	s += "\n" + helpLine(m.keys.Up, m.keys.Down, m.keys.PageDown, m.keys.Back, m.keys.Help, m.keys.Quit)
+	return s
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// Types of backup targets.
+const (
+	backupS3      = "s3"
+	backupWebDAV  = "webdav"
+	backupDropbox = "dropbox"
+)
+
+var backupClient = &http.Client{Timeout: 30 * time.Second}
+
+// backupTarget is a remote place in config.json that the data file is
+// uploaded to after every save.
+type backupTarget struct {
+	// Type is "s3", "webdav" or "dropbox".
+	Type string `json:"type"`
+	// URL is the file's WebDAV URL, or an S3 endpoint other than AWS's,
+	// e.g. a MinIO server.
+	URL string `json:"url,omitempty"`
+	// Path is the object key in an S3 bucket or the file in Dropbox. It
+	// defaults to the data file's name.
+	Path string `json:"path,omitempty"`
+	// Bucket and Region locate an S3 bucket. AccessKey and SecretKey sign
+	// requests to it; AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY override
+	// them.
+	Bucket    string `json:"bucket,omitempty"`
+	Region    string `json:"region,omitempty"`
+	AccessKey string `json:"access_key,omitempty"`
+	SecretKey string `json:"secret_key,omitempty"`
+	// User and Password log in to a WebDAV server.
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Token is a Dropbox access token; DROPBOX_TOKEN overrides it.
+	Token string `json:"token,omitempty"`
+}
+
+func checkBackups(targets []backupTarget) error {
+	for _, t := range targets {
+		switch t.Type {
+		case backupS3:
+			if t.Bucket == "" || t.Region == "" {
+				return errors.New("s3 backup needs a bucket and a region")
+			}
+		case backupWebDAV:
+			if t.URL == "" {
+				return errors.New("webdav backup needs the url of the file")
+			}
+		case backupDropbox:
+		default:
+			return fmt.Errorf("unknown backup type %q (want %s, %s or %s)", t.Type, backupS3, backupWebDAV, backupDropbox)
+		}
+	}
+	return nil
+}
+
+// path is where the data file at local is kept on the target.
+func (t backupTarget) path(local string) string {
+	if t.Path != "" {
+		return strings.TrimPrefix(t.Path, "/")
+	}
+	return filepath.Base(local)
+}
+
+// label names the target in messages, e.g. "s3://bucket/sessions.json".
+func (t backupTarget) label(local string) string {
+	switch t.Type {
+	case backupS3:
+		return "s3://" + t.Bucket + "/" + t.path(local)
+	case backupDropbox:
+		return "dropbox:/" + t.path(local)
+	}
+	return t.URL
+}
+
+// upload stores data on the target as the copy of the data file at local.
+func (t backupTarget) upload(local string, data []byte) error {
+	req, err := t.request(http.MethodPut, local, data)
+	if err != nil {
+		return err
+	}
+	_, err = t.do(req)
+	return err
+}
+
+// download fetches the copy of the data file at local from the target.
+func (t backupTarget) download(local string) ([]byte, error) {
+	req, err := t.request(http.MethodGet, local, nil)
+	if err != nil {
+		return nil, err
+	}
+	return t.do(req)
+}
+
+// request builds the request that uploads data to the target with PUT, or
+// downloads from it with GET.
+func (t backupTarget) request(method, local string, data []byte) (*http.Request, error) {
+	switch t.Type {
+	case backupS3:
+		return t.s3Request(method, local, data, time.Now())
+	case backupDropbox:
+		return t.dropboxRequest(method, local, data)
+	}
+	req, err := http.NewRequest(method, t.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if t.User != "" {
+		req.SetBasicAuth(t.User, t.Password)
+	}
+	return req, nil
+}
+
+func (t backupTarget) do(req *http.Request) ([]byte, error) {
+	resp, err := backupClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("backup: %s %s: %s: %s", req.Method, req.URL.Redacted(), resp.Status, bytes.TrimSpace(body[:min(len(body), 512)]))
+	}
+	return body, nil
+}
+
+// dropboxRequest uses the Dropbox content API, which takes both uploads and
+// downloads as POSTs.
+func (t backupTarget) dropboxRequest(method, local string, data []byte) (*http.Request, error) {
+	token := t.Token
+	if env := os.Getenv("DROPBOX_TOKEN"); env != "" {
+		token = env
+	}
+	if token == "" {
+		return nil, errors.New("no Dropbox access token: set token in the backup in config.json or DROPBOX_TOKEN")
+	}
+	arg := map[string]any{"path": "/" + t.path(local)}
+	endpoint := "https://content.dropboxapi.com/2/files/download"
+	if method == http.MethodPut {
+		arg["mode"] = "overwrite"
+		arg["mute"] = true
+		endpoint = "https://content.dropboxapi.com/2/files/upload"
+	}
+	header, err := json.Marshal(arg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Dropbox-API-Arg", string(header))
+	if method == http.MethodPut {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	return req, nil
+}
+
+// s3Request addresses the object path-style, which AWS and S3-compatible
+// servers all accept, and signs the request with AWS Signature Version 4.
+func (t backupTarget) s3Request(method, local string, data []byte, now time.Time) (*http.Request, error) {
+	accessKey, secretKey := t.AccessKey, t.SecretKey
+	if env := os.Getenv("AWS_ACCESS_KEY_ID"); env != "" {
+		accessKey = env
+	}
+	if env := os.Getenv("AWS_SECRET_ACCESS_KEY"); env != "" {
+		secretKey = env
+	}
+	if accessKey == "" || secretKey == "" {
+		return nil, errors.New("no S3 credentials: set access_key and secret_key in the backup in config.json or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	endpoint := strings.TrimSuffix(t.URL, "/")
+	if endpoint == "" {
+		endpoint = "https://s3." + t.Region + ".amazonaws.com"
+	}
+	path := "/" + s3Escape(t.Bucket) + "/" + s3Escape(t.path(local))
+	req, err := http.NewRequest(method, endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	day := stamp[:8]
+	payload := sha256.Sum256(data)
+	payloadHash := hex.EncodeToString(payload[:])
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonical := strings.Join([]string{
+		method, path, "",
+		"host:" + req.URL.Host, "x-amz-content-sha256:" + payloadHash, "x-amz-date:" + stamp, "",
+		"host;x-amz-content-sha256;x-amz-date", payloadHash,
+	}, "\n")
+	scope := day + "/" + t.Region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{day, t.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=%s",
+		accessKey, scope, hex.EncodeToString(hmacSHA256(key, toSign))))
+	return req, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape percent-encodes everything in an object key but unreserved
+// characters and slashes, as Signature Version 4 requires.
+func s3Escape(s string) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', strings.IndexByte("-._~/", b) >= 0:
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+// backupQueue uploads the data file in the background after each save, so
+// saving never waits on the network. Saves in quick succession are uploaded
+// once, by the last of them.
+type backupQueue struct {
+	targets []backupTarget
+	path    string
+	// report, when set, is told about failed uploads as they happen; they
+	// are otherwise returned by wait.
+	report func(error)
+
+	mu      sync.Mutex
+	latest  atomic.Int64
+	running sync.WaitGroup
+	errs    []error
+}
+
+// backups uploads the data file to the targets in config.json, if any.
+var backups = &backupQueue{}
+
+// schedule uploads the data file to every target once the uploads already
+// running have finished.
+func (q *backupQueue) schedule() {
+	if len(q.targets) == 0 {
+		return
+	}
+	n := q.latest.Add(1)
+	q.running.Add(1)
+	go func() {
+		defer q.running.Done()
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if q.latest.Load() != n {
+			// A later save will upload the newer file.
+			return
+		}
+		if err := q.upload(); err != nil {
+			if q.report != nil {
+				q.report(err)
+			} else {
+				q.errs = append(q.errs, err)
+			}
+		}
+	}()
+}
+
+func (q *backupQueue) upload() error {
+	data, err := os.ReadFile(q.path)
+	if err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	var errs []error
+	for _, t := range q.targets {
+		errs = append(errs, t.upload(q.path, data))
+	}
+	return errors.Join(errs...)
+}
+
+// wait blocks until the scheduled uploads are done and returns the errors of
+// those that failed.
+func (q *backupQueue) wait() error {
+	q.running.Wait()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	errs := q.errs
+	q.errs = nil
+	return errors.Join(errs...)
+}
+
+// backedUpStorage uploads the data file after every change written to the
+// storage it wraps.
+type backedUpStorage struct {
+	Storage
+}
+
+func (s *backedUpStorage) Save(history []session) error {
+	if err := s.Storage.Save(history); err != nil {
+		return err
+	}
+	backups.schedule()
+	return nil
+}
+
+func (s *backedUpStorage) Append(sess session) error {
+	if err := s.Storage.Append(sess); err != nil {
+		return err
+	}
+	backups.schedule()
+	return nil
+}
+
+func (s *backedUpStorage) Delete(id string) error {
+	if err := s.Storage.Delete(id); err != nil {
+		return err
+	}
+	backups.schedule()
+	return nil
+}
+
+// backupErrMsg reports a failed backup upload from the TUI.
+type backupErrMsg struct {
+	err error
+}
+
+// runPull fetches the data file from a backup target, e.g. to carry on with
+// the history on a new machine. A local history is only replaced with
+// -force, and kept next to it with a .bak suffix.
+func runPull(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("pull", flag.ExitOnError)
+	from := fs.String("from", "", "type of the backup target to pull from: s3, webdav or dropbox (default the first one)")
+	force := fs.Bool("force", false, "replace a local history with the backup")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkWritable(); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	local := dataFile
+	if storageKind == storageSQLite {
+		local = sqliteFile
+	}
+	target, err := cfg.backupTarget(*from)
+	if err != nil {
+		return err
+	}
+	if lockedElsewhere() {
+		return errors.New("another time-tracker has the history open; quit it first")
+	}
+	exists, err := fileExists(local)
+	if err != nil {
+		return err
+	}
+	if exists && !*force {
+		return fmt.Errorf("%s already exists; pass -force to replace it with the backup", local)
+	}
+
+	data, err := target.download(local)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err := os.Rename(local, local+".bak"); err != nil {
+			return err
+		}
+	}
+	if err := writeFileAtomic(local, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Pulled %s from %s\n", local, target.label(local))
+	if storageKind != storageJSON || sealed(data) {
+		return nil
+	}
+	history, err := track.Decode(local, data)
+	if err != nil {
+		return err
+	}
+	return writeReport(historyFile, history, cfg)
+}
+
+// backupTarget returns the first backup target of type kind, or the first
+// one at all if kind is empty.
+func (c config) backupTarget(kind string) (backupTarget, error) {
+	for _, t := range c.Backups {
+		if kind == "" || t.Type == kind {
+			return t, nil
+		}
+	}
+	if kind != "" {
+		return backupTarget{}, fmt.Errorf("no %s backup in %s", kind, configFile)
+	}
+	return backupTarget{}, fmt.Errorf("no backups in %s", configFile)
+}
+
+func fileExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
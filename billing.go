@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// rateFor returns the hourly rate for project: its own rate if one is set,
+// otherwise its client's, otherwise the global rate. Zero means the project
+// is not billed.
+func (c config) rateFor(project string) float64 {
+	if rate, ok := c.ProjectRates[project]; ok {
+		return rate
+	}
+	if rate := c.Clients[c.clientOf(project)].Rate; rate > 0 {
+		return rate
+	}
+	return c.Rate
+}
+
+// hasRates reports whether any hourly rate is configured, so earnings are
+// only shown to users who bill for their time.
+func (c config) hasRates() bool {
+	if c.Rate > 0 {
+		return true
+	}
+	for _, rate := range c.ProjectRates {
+		if rate > 0 {
+			return true
+		}
+	}
+	for _, cl := range c.Clients {
+		if cl.Rate > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// billableByDefault reports whether new sessions for project are billable,
+// which they are unless it is listed in non_billable.
+func (c config) billableByDefault(project string) bool {
+	return !slices.Contains(c.NonBillable, project)
+}
+
+// billableSplit totals the billable and the non-billable time in sessions,
+// rounded as for billing.
+func (c config) billableSplit(sessions []session) (billable, nonBillable time.Duration) {
+	var yes, no []session
+	for _, sess := range sessions {
+		if sess.Billable {
+			yes = append(yes, sess)
+		} else {
+			no = append(no, sess)
+		}
+	}
+	billable, _ = c.billed(yes)
+	nonBillable, _ = c.billed(no)
+	return billable, nonBillable
+}
+
+// earnings is what sess is worth at its project's rate. Non-billable sessions
+// earn nothing.
+func (c config) earnings(sess session) float64 {
+	if !sess.Billable {
+		return 0
+	}
+	return amountFor(sess.Duration, c.rateFor(sess.Project))
+}
+
+// totalEarnings sums the earnings of the sessions at indices in history, in
+// the home currency.
+func (c config) totalEarnings(history []session, indices []int) float64 {
+	var total float64
+	for _, i := range indices {
+		total += c.toHome(c.earnings(history[i]), c.currencyFor(history[i].Project))
+	}
+	return total
+}
+
+func amountFor(d time.Duration, rate float64) float64 {
+	return d.Hours() * rate
+}
+
+func formatMoney(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
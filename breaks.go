@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// defaultBreakSnooze is how long snoozing puts a break reminder off unless
+// configured otherwise.
+const defaultBreakSnooze = 10 * time.Minute
+
+// breakEvery is how long a session may run without a pause before a break
+// is suggested, or 0 if break_every is not set.
+func (c config) breakEvery() time.Duration {
+	// loadConfig has checked that BreakEvery parses.
+	d, _ := time.ParseDuration(c.BreakEvery)
+	return d
+}
+
+// breakSnooze is how long snoozing puts a break reminder off.
+func (c config) breakSnooze() time.Duration {
+	if d, _ := time.ParseDuration(c.BreakSnooze); d > 0 {
+		return d
+	}
+	return defaultBreakSnooze
+}
+
+func checkBreaks(every, snooze string) error {
+	for _, v := range []struct{ name, value string }{{"break_every", every}, {"break_snooze", snooze}} {
+		if v.value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(v.value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s %q (want e.g. 50m)", v.name, v.value)
+		}
+	}
+	return nil
+}
+
+// workingSince returns when the session last started or came back from a
+// pause.
+func (a activeSession) workingSince() time.Time {
+	since := a.start
+	for _, p := range a.pauses {
+		if !p.End.IsZero() {
+			since = later(since, p.End)
+		}
+	}
+	return since
+}
+
+// breakDue reports whether the running session has gone break_every without
+// a pause at now and the reminder is not snoozed.
+func (m model) breakDue(now time.Time) bool {
+	every := m.config.breakEvery()
+	if m.active == nil || m.active.paused() || every <= 0 || now.Before(m.breakSnoozed) {
+		return false
+	}
+	return now.Sub(m.active.workingSince()) >= every
+}
+
+// breakReminder returns a notification command when a break becomes due,
+// again once a snooze runs out.
+func (m *model) breakReminder() tea.Cmd {
+	now := time.Now()
+	if !m.breakDue(now) || !m.breakNotified.Before(later(m.active.workingSince(), m.breakSnoozed)) {
+		return nil
+	}
+	m.breakNotified = now
+	return tea.Batch(m.alertCmd(), m.notifyCmd("Time for a break", breakMessage(m.active.project, now.Sub(m.active.workingSince()), m.config)))
+}
+
+func breakMessage(project string, working time.Duration, cfg config) string {
+	return fmt.Sprintf("You've been working on %s for %s without a break.", track.ProjectLabel(project), cfg.durationLong(working.Truncate(time.Minute)))
+}
+
+// snoozeBreak puts off a due break reminder, reporting whether there was
+// one.
+func (m *model) snoozeBreak() bool {
+	now := time.Now()
+	if !m.breakDue(now) {
+		return false
+	}
+	m.breakSnoozed = now.Add(m.config.breakSnooze())
+	return true
+}
+
+// viewBreak flashes the break reminder while one is due, or renders
+// nothing.
+func (m model) viewBreak() string {
+	now := time.Now()
+	if !m.breakDue(now) {
+		return ""
+	}
+	line := fmt.Sprintf("☕ %s Press %s to snooze.", breakMessage(m.active.project, now.Sub(m.active.workingSince()), m.config), m.keys.Snooze.Help().Key)
+	style := warningStyle
+	if now.Second()%2 == 0 {
+		style = style.Reverse(true)
+	}
+	return style.Render(line) + "\n\n"
+}
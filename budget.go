@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// budgetWarning is the share of a budget past which it is flagged.
+const budgetWarning = 0.8
+
+// budget caps the time or money, in the home currency, spent on a project or
+// client; either or both may be set.
+type budget struct {
+	Hours float64 `json:"hours,omitempty"`
+	Money float64 `json:"money,omitempty"`
+}
+
+func (b budget) set() bool {
+	return b.Hours > 0 || b.Money > 0
+}
+
+func checkBudget(name string, b budget) error {
+	if b.Hours < 0 || b.Money < 0 {
+		return fmt.Errorf("budget for %s must not be negative", name)
+	}
+	return nil
+}
+
+// budgetUse is how much of a budget has been used.
+type budgetUse struct {
+	label   string
+	project string
+	client  string // key of the client for a client budget, else empty
+	budget  budget
+	time    time.Duration
+	money   float64
+}
+
+// share is the larger of the shares of the hours and money budgets used.
+func (u budgetUse) share() float64 {
+	var share float64
+	if u.budget.Hours > 0 {
+		share = u.time.Hours() / u.budget.Hours
+	}
+	if u.budget.Money > 0 {
+		share = max(share, u.money/u.budget.Money)
+	}
+	return share
+}
+
+// describe renders u as e.g. "website: 32h 10m of 40h, 80%".
+func (u budgetUse) describe(cfg config) string {
+	var parts []string
+	if u.budget.Hours > 0 {
+		parts = append(parts, fmt.Sprintf("%s of %gh", cfg.durationLong(u.time), u.budget.Hours))
+	}
+	if u.budget.Money > 0 {
+		parts = append(parts, fmt.Sprintf("%s of %s", cfg.money(u.money), cfg.money(u.budget.Money)))
+	}
+	return fmt.Sprintf("%s: %s, %.0f%%", u.label, strings.Join(parts, " and "), 100*u.share())
+}
+
+// budgetUses returns the use of every project and client budget by the
+// sessions in history, in order of label. Time is rounded as for billing.
+func (c config) budgetUses(history []session) []budgetUse {
+	var uses []budgetUse
+	use := func(u budgetUse, in func(session) bool) {
+		var sessions []session
+		for _, sess := range history {
+			if in(sess) {
+				sessions = append(sessions, sess)
+			}
+		}
+		u.time, u.money = c.billed(sessions)
+		uses = append(uses, u)
+	}
+	for project, b := range c.Budgets {
+		if b.set() {
+			use(budgetUse{label: track.ProjectLabel(project), project: project, budget: b},
+				func(sess session) bool { return sess.Project == project })
+		}
+	}
+	for key, cl := range c.Clients {
+		if cl.Budget.set() {
+			use(budgetUse{label: c.clientName(key), client: key, budget: cl.Budget},
+				func(sess session) bool { return c.clientOf(sess.Project) == key })
+		}
+	}
+	sort.Slice(uses, func(i, j int) bool {
+		if uses[i].label != uses[j].label {
+			return uses[i].label < uses[j].label
+		}
+		return uses[i].client < uses[j].client
+	})
+	return uses
+}
+
+// projectBudgetUses is budgetUses narrowed to the budgets project counts
+// towards: its own and its client's.
+func (c config) projectBudgetUses(history []session, project string) []budgetUse {
+	var uses []budgetUse
+	for _, u := range c.budgetUses(history) {
+		if u.client == "" && u.project == project || u.client != "" && u.client == c.clientOf(project) {
+			uses = append(uses, u)
+		}
+	}
+	return uses
+}
+
+// budgetCrossing returns a warning for every budget in after whose use went
+// past 80% or 100% since before, which lists the same budgets.
+func budgetCrossing(before, after []budgetUse, cfg config) []string {
+	var warnings []string
+	for i, u := range after {
+		if i >= len(before) {
+			break
+		}
+		was, is := before[i].share(), u.share()
+		switch {
+		case was < 1 && is >= 1:
+			warnings = append(warnings, "Over budget: "+u.describe(cfg))
+		case was < budgetWarning && is >= budgetWarning:
+			warnings = append(warnings, "Nearly over budget: "+u.describe(cfg))
+		}
+	}
+	return warnings
+}
+
+// renderBudgets lists the use of every budget by history, or renders nothing
+// when none are set.
+func renderBudgets(history []session, cfg config) string {
+	uses := cfg.budgetUses(history)
+	if len(uses) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\nBudgets\n")
+	for _, u := range uses {
+		mark := " "
+		switch {
+		case u.share() >= 1:
+			mark = "!"
+		case u.share() >= budgetWarning:
+			mark = "~"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s\n", mark, u.describe(cfg)))
+	}
+	return sb.String()
+}
+
+// withActive returns history with the running session, tracked up to
+// elapsed, added at the end.
+func (m model) withActive(elapsed time.Duration) []session {
+	if m.active == nil {
+		return m.history
+	}
+	sess := m.active.finish(time.Now())
+	sess.Duration = elapsed
+	return append(m.history[:len(m.history):len(m.history)], sess)
+}
+
+// budgetAlert returns a notification command when the running session pushes
+// a budget it counts towards past 80% or 100%, i.e. when it had not with
+// before tracked and has with m.elapsed.
+func (m model) budgetAlert(before time.Duration) tea.Cmd {
+	if m.active == nil || before == m.elapsed {
+		return nil
+	}
+	project := m.active.project
+	warnings := budgetCrossing(m.config.projectBudgetUses(m.withActive(before), project),
+		m.config.projectBudgetUses(m.withActive(m.elapsed), project), m.config)
+	if len(warnings) == 0 {
+		return nil
+	}
+	return tea.Batch(bellCmd(), m.notifyCmd("Budget", strings.Join(warnings, "\n")))
+}
+
+// viewBudget shows how much of the budgets the running session counts
+// towards are used, or renders nothing if there are none.
+func (m model) viewBudget() string {
+	if m.active == nil {
+		return ""
+	}
+	var s string
+	for _, u := range m.config.projectBudgetUses(m.withActive(m.elapsed), m.active.project) {
+		line := "Budget " + u.describe(m.config)
+		if u.share() >= budgetWarning {
+			s += warningStyle.Render("⚠ "+line) + "\n"
+		} else {
+			s += normalStyle.Render(line) + "\n"
+		}
+	}
+	if s == "" {
+		return ""
+	}
+	return s + "\n"
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// caldavConfig is the "caldav" section of config.json, for calendars on a
+// CalDAV server such as Nextcloud or Fastmail.
+type caldavConfig struct {
+	// URL is the calendar sessions are pushed to, e.g.
+	// "https://cloud.example.com/remote.php/dav/calendars/me/time-tracking/".
+	URL string `json:"url,omitempty"`
+	// Meetings is the calendar meetings are pulled from.
+	Meetings string `json:"meetings,omitempty"`
+	Username string `json:"username,omitempty"`
+	// Password is best an app password; CALDAV_PASSWORD overrides it.
+	Password string `json:"password,omitempty"`
+	// MeetingProject is the project pulled meetings are tracked under.
+	MeetingProject string `json:"meeting_project,omitempty"`
+}
+
+// caldavClient talks to a CalDAV server with HTTP basic authentication.
+type caldavClient struct {
+	cfg      caldavConfig
+	password string
+	http     *http.Client
+}
+
+func newCaldavClient(cfg caldavConfig) (*caldavClient, error) {
+	if cfg.URL == "" && cfg.Meetings == "" {
+		return nil, errors.New("no CalDAV calendar: set caldav.url or caldav.meetings in config.json")
+	}
+	for _, u := range []string{cfg.URL, cfg.Meetings} {
+		if parsed, err := url.Parse(u); u != "" && (err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http")) {
+			return nil, fmt.Errorf("caldav: invalid calendar URL %q", u)
+		}
+	}
+	password := cfg.Password
+	if env := os.Getenv("CALDAV_PASSWORD"); env != "" {
+		password = env
+	}
+	return &caldavClient{cfg: cfg, password: password, http: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// caldavCollection returns the calendar URL with the trailing slash that
+// resources inside it are named relative to.
+func caldavCollection(u string) string {
+	if !strings.HasSuffix(u, "/") {
+		u += "/"
+	}
+	return u
+}
+
+func (c *caldavClient) do(method, u string, header http.Header, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	if c.cfg.Username != "" {
+		req.SetBasicAuth(c.cfg.Username, c.password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("caldav: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("caldav: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return data, &caldavError{method, u, resp.StatusCode, resp.Status}
+	}
+	return data, nil
+}
+
+// caldavError is a request the server refused, e.g. a PUT of an event it
+// already has.
+type caldavError struct {
+	method, url string
+	code        int
+	status      string
+}
+
+func (e *caldavError) Error() string {
+	return fmt.Sprintf("caldav: %s %s: %s", e.method, e.url, e.status)
+}
+
+// davMultistatus is the answer to a REPORT.
+type davMultistatus struct {
+	Responses []struct {
+		Propstat []struct {
+			Data string `xml:"prop>calendar-data"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// events returns the events of the calendar at u within r. Recurring
+// events are expanded when r has both ends.
+func (c *caldavClient) events(u string, r dateRange) ([]icsEvent, error) {
+	var timeRange, expand string
+	if !r.From.IsZero() || !r.To.IsZero() {
+		var attrs string
+		if !r.From.IsZero() {
+			attrs += fmt.Sprintf(` start="%s"`, r.From.UTC().Format(icsTimeLayout))
+		}
+		if !r.To.IsZero() {
+			attrs += fmt.Sprintf(` end="%s"`, r.To.UTC().Format(icsTimeLayout))
+		}
+		timeRange = "<C:time-range" + attrs + "/>"
+		if !r.From.IsZero() && !r.To.IsZero() {
+			expand = "<C:expand" + attrs + "/>"
+		}
+	}
+	query := `<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><C:calendar-data>` + expand + `</C:calendar-data></D:prop>
+  <C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="VEVENT">` + timeRange + `</C:comp-filter></C:comp-filter></C:filter>
+</C:calendar-query>`
+	header := http.Header{"Depth": {"1"}, "Content-Type": {"application/xml; charset=utf-8"}}
+	data, err := c.do("REPORT", caldavCollection(u), header, []byte(query))
+	if err != nil {
+		return nil, err
+	}
+	var ms davMultistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("caldav: %s: %w", u, err)
+	}
+	var events []icsEvent
+	for _, resp := range ms.Responses {
+		for _, ps := range resp.Propstat {
+			events = append(events, readICSEvents(ps.Data)...)
+		}
+	}
+	return events, nil
+}
+
+// pushed returns the IDs of the sessions pushed to the calendar at URL
+// within r, known by the UIDs writeICS gives them.
+func (c *caldavClient) pushed(r dateRange) ([]string, error) {
+	if c.cfg.URL == "" {
+		return nil, errors.New("no calendar to push to: set caldav.url in config.json to a calendar made for your sessions")
+	}
+	events, err := c.events(c.cfg.URL, r)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range events {
+		if id, ok := strings.CutSuffix(e.UID, icsUIDSuffix); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// push stores sess as an event of its own, named after its ID. One already
+// stored there is left as it is.
+func (c *caldavClient) push(sess session, cfg config) error {
+	var buf bytes.Buffer
+	if err := writeICS(&buf, []session{sess}, cfg); err != nil {
+		return err
+	}
+	header := http.Header{"Content-Type": {"text/calendar; charset=utf-8"}, "If-None-Match": {"*"}}
+	_, err := c.do(http.MethodPut, caldavCollection(c.cfg.URL)+url.PathEscape(sess.ID)+".ics", header, buf.Bytes())
+	var cerr *caldavError
+	if errors.As(err, &cerr) && cerr.code == http.StatusPreconditionFailed {
+		return nil
+	}
+	return err
+}
+
+// meetings returns sessions for the finished meetings within r, leaving out
+// cancelled events, those the user declined and sessions pushed there.
+func (c *caldavClient) meetings(r dateRange, cfg config) ([]session, error) {
+	if c.cfg.Meetings == "" {
+		return nil, errors.New("no calendar to pull meetings from: set caldav.meetings in config.json")
+	}
+	events, err := c.events(c.cfg.Meetings, r)
+	if err != nil {
+		return nil, err
+	}
+	var sessions []session
+	for _, e := range events {
+		declined := c.cfg.Username != "" && slices.Contains(e.Declined, strings.ToLower(c.cfg.Username))
+		if e.Status == "CANCELLED" || declined || strings.HasSuffix(e.UID, icsUIDSuffix) || !finishedMeeting(e.Start, e.End) {
+			continue
+		}
+		sessions = append(sessions, meetingSession(e.Summary, e.Start, e.End, c.cfg.MeetingProject, cfg))
+	}
+	return sessions, nil
+}
+
+// runCaldav pushes sessions to a CalDAV calendar and pulls meetings from
+// one.
+func runCaldav(storageKind string, args []string) error {
+	return runCalendarSync(storageKind, "caldav", "CalDAV", args, func(cfg config) (calendarService, error) {
+		return newCaldavClient(cfg.CalDAV)
+	})
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+
+	"time-tracking/pkg/track"
+)
+
+// meetingTag is the tag given to sessions pulled from a calendar.
+const meetingTag = "meeting"
+
+// calendarService is a calendar service that finished sessions are pushed
+// to as events and meetings are pulled from, such as Google Calendar or a
+// CalDAV server.
+type calendarService interface {
+	// pushed returns the IDs of the sessions pushed as events within r.
+	pushed(r dateRange) ([]string, error)
+	push(sess session, cfg config) error
+	// meetings returns sessions for the finished meetings within r.
+	meetings(r dateRange, cfg config) ([]session, error)
+}
+
+// meetingSession pre-fills a session for a meeting from start to end, to
+// be confirmed before it is added.
+func meetingSession(title string, start, end time.Time, project string, cfg config) session {
+	sess := session{
+		ID:       track.NewID(),
+		Project:  project,
+		Note:     strings.TrimSpace(title),
+		Tags:     []string{meetingTag},
+		Billable: cfg.billableByDefault(project),
+	}
+	sess.SetBounds(start.Local(), end.Local())
+	cfg.categorize(&sess, sess.Note, "")
+	return sess
+}
+
+// finishedMeeting reports whether a meeting from start to end is over and
+// can be tracked as a session; all-day events have no start time.
+func finishedMeeting(start, end time.Time) bool {
+	return !start.IsZero() && end.After(start) && !end.After(time.Now())
+}
+
+// describeSuggestion describes a session offered to be added, such as a
+// meeting, with its note.
+func describeSuggestion(sess session, cfg config) string {
+	desc := cfg.describeRecord(track.ToRecord(sess))
+	if sess.Note != "" {
+		desc += fmt.Sprintf(" %q", sess.Note)
+	}
+	return desc
+}
+
+// confirmSessions asks about each of the sessions offered, such as
+// meetings, in turn whether to add it, unless all is set. Answering "a" adds
+// the rest, "q" skips them.
+func confirmSessions(offered []session, all bool, cfg config) ([]session, error) {
+	if all {
+		return offered, nil
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return nil, errors.New("cannot ask which sessions to add without a terminal; pass -yes to add them all or -dry-run to list them")
+	}
+	in := bufio.NewReader(os.Stdin)
+	var confirmed []session
+	for i, sess := range offered {
+		fmt.Printf("Add %s? [y/N/a/q] ", describeSuggestion(sess, cfg))
+		answer, err := in.ReadString('\n')
+		if err != nil && answer == "" {
+			return confirmed, nil
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			confirmed = append(confirmed, sess)
+		case "a", "all":
+			return append(confirmed, offered[i:]...), nil
+		case "q", "quit":
+			return confirmed, nil
+		}
+	}
+	return confirmed, nil
+}
+
+// runCalendarSync runs `NAME push` or `NAME pull` against the calendar
+// service connect opens, title naming it to the user: push adds finished
+// sessions not pushed yet as events, pull offers meetings not tracked yet as
+// sessions to confirm.
+func runCalendarSync(storageKind, name, title string, args []string, connect func(cfg config) (calendarService, error)) error {
+	if len(args) == 0 || (args[0] != "pull" && args[0] != "push") {
+		return fmt.Errorf("usage: %s pull|push [-range RANGE] [-dry-run] [-yes]", name)
+	}
+	fs := flag.NewFlagSet(name+" "+args[0], flag.ExitOnError)
+	rangeSpec := fs.String("range", "week", "sync today, week, month, last-month or FROM..TO")
+	dryRun := fs.Bool("dry-run", false, "only list what would be synced")
+	yes := fs.Bool("yes", false, "add every pulled meeting without asking")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	dates, err := parseRange(*rangeSpec, time.Now())
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	service, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "pull" {
+		meetings, err := service.meetings(dates, cfg)
+		if err != nil {
+			return err
+		}
+		_, result := mergeSessions(history, meetings, importTolerance, false)
+		if *dryRun {
+			result.preview(cfg)
+			fmt.Printf("Would offer %d meetings%s\n", len(result.added), result.summary())
+			return nil
+		}
+		confirmed, err := confirmSessions(result.added, *yes, cfg)
+		if err != nil {
+			return err
+		}
+		if len(confirmed) == 0 {
+			fmt.Println("No meetings added")
+			return nil
+		}
+		history, _ = mergeSessions(history, confirmed, importTolerance, false)
+		if err := storage.Save(history); err != nil {
+			return err
+		}
+		if err := writeReport(historyFile, history, cfg); err != nil {
+			return err
+		}
+		fmt.Printf("Added %d of %d meetings from %s\n", len(confirmed), len(result.added), title)
+		return nil
+	}
+
+	ids, err := service.pushed(dates)
+	if err != nil {
+		return err
+	}
+	pushed := 0
+	local := dates.Filter(history)
+	for _, sess := range local {
+		if slices.Contains(ids, sess.ID) {
+			continue
+		}
+		if *dryRun {
+			fmt.Printf("%s  %s  %s\n", sess.Start.Format(csvTimeLayout), track.FormatClock(sess.Duration), track.ProjectLabel(sess.Project))
+		} else if err := service.push(sess, cfg); err != nil {
+			return fmt.Errorf("pushed %d sessions before failing: %w", pushed, err)
+		}
+		pushed++
+	}
+	if *dryRun {
+		fmt.Printf("Would push %d of %d sessions\n", pushed, len(local))
+		return nil
+	}
+	fmt.Printf("Pushed %d of %d sessions to %s\n", pushed, len(local), title)
+	return nil
+}
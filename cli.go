@@ -0,0 +1,777 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"time-tracking/pkg/report"
+	"time-tracking/pkg/track"
+)
+
+var errNotTracking = errors.New("not tracking")
+
+// runCommand runs a non-interactive subcommand named by name with its own
+// arguments. storageKind selects the backend, as for the TUI.
+func runCommand(storageKind, name string, args []string) error {
+	switch name {
+	case "start":
+		return runStart(args)
+	case "stop":
+		return runStop(storageKind, args)
+	case "status":
+		return runStatus(args)
+	case "report":
+		return runReport(storageKind, args)
+	case "migrate":
+		return runMigrate(args)
+	case "rate":
+		return runRate(args)
+	case "invoice":
+		return runInvoice(storageKind, args)
+	case "daemon":
+		return runDaemon(storageKind, args)
+	case "export":
+		return runExport(storageKind, args)
+	case "import":
+		return runImport(storageKind, args)
+	case "toggl":
+		return runToggl(storageKind, args)
+	case "clockify":
+		return runClockify(storageKind, args)
+	case "harvest":
+		return runHarvest(storageKind, args)
+	case "gcal":
+		return runGcal(storageKind, args)
+	case "caldav":
+		return runCaldav(storageKind, args)
+	case "activitywatch":
+		return runActivityWatch(storageKind, args)
+	case "gaps":
+		return runGaps(storageKind, args)
+	case "interruptions":
+		return runInterruptions(storageKind, args)
+	case "estimate":
+		return runEstimate(storageKind, args)
+	case "close":
+		return runClose(args)
+	case "doctor":
+		return runDoctor(storageKind, args)
+	case "encrypt":
+		return runEncrypt(storageKind, args)
+	case "pull":
+		return runPull(storageKind, args)
+	case "push":
+		return runPush(storageKind, args)
+	case "plugins":
+		return runPlugins(args)
+	case "serve":
+		return runServe(storageKind, args)
+	case "sync":
+		return runSync(storageKind, args)
+	default:
+		return fmt.Errorf("unknown command %q (want start, stop, status, report, gaps, interruptions, estimate, close, doctor, encrypt, pull, push, plugins, serve, sync, migrate, rate, invoice, daemon, export, import, toggl, clockify, harvest, gcal, caldav or activitywatch)", name)
+	}
+}
+
+// parseFlags parses args into fs for a command that takes only flags,
+// rejecting anything else rather than silently ignoring it.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%s takes no arguments, got %q; see time-tracker %s -h", fs.Name(), strings.Join(fs.Args(), " "), fs.Name())
+	}
+	return nil
+}
+
+// runStart begins tracking a new session.
+func runStart(args []string) error {
+	fs := flag.NewFlagSet("start", flag.ExitOnError)
+	project := fs.String("project", "", "project to track")
+	note := fs.String("note", "", "note for the session")
+	tags := fs.String("tags", "", "comma or space separated tags, e.g. billable,meeting")
+	billable := fs.Bool("billable", true, "bill the session at the project's hourly rate (default false for projects in non_billable)")
+	targetSpec := fs.String("target", "", "count down from this long, e.g. 45m")
+	issueSpec := fs.String("issue", "", "link an issue or pull request: a URL, #42 or owner/repo#42; none for no link (default the issue the git branch is named after)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	target, err := parseTarget(*targetSpec)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	billableSet, issueSet := false, false
+	fs.Visit(func(f *flag.Flag) {
+		billableSet = billableSet || f.Name == "billable"
+		issueSet = issueSet || f.Name == "issue"
+	})
+	if !billableSet {
+		*billable = cfg.billableByDefault(strings.TrimSpace(*project))
+	}
+	issue := branchIssue()
+	if issueSet {
+		if issue, err = parseIssue(*issueSpec); err != nil {
+			return err
+		}
+	}
+
+	active, err := loadActive()
+	if err != nil {
+		return err
+	}
+	if active != nil {
+		return fmt.Errorf("already tracking %s since %s", track.ProjectLabel(active.project), active.start.Format("15:04"))
+	}
+
+	a := activeSession{
+		project:  strings.TrimSpace(*project),
+		note:     strings.TrimSpace(*note),
+		tags:     track.ParseTags(*tags),
+		start:    time.Now(),
+		billable: *billable,
+		issue:    issue,
+		target:   target,
+	}
+	cfg.categorizeActive(&a, gitBranch())
+	if billableSet {
+		a.billable = *billable
+	}
+	if err := saveActive(a); err != nil {
+		return err
+	}
+
+	fmt.Printf("Started tracking %s at %s\n", track.ProjectLabel(a.project), a.start.Format("15:04:05"))
+	if a.issue != "" {
+		fmt.Printf("Linked to %s\n", issueRef(a.issue))
+	}
+	// The session has started; a webhook or hook failure should not say
+	// otherwise.
+	if err := cfg.sendWebhooks(startEvent(a)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if err := cfg.runHook(startEvent(a)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return nil
+}
+
+// runStop ends the running session and adds it to the history.
+func runStop(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("stop", flag.ExitOnError)
+	note := fs.String("note", "", "replace the session note")
+	tags := fs.String("tags", "", "additional tags for the session")
+	issueSpec := fs.String("issue", "", "link an issue or pull request: a URL, #42 or owner/repo#42; none to unlink")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	active, err := loadActive()
+	if err != nil {
+		return err
+	}
+	if active == nil {
+		return errNotTracking
+	}
+	if *note != "" {
+		active.note = strings.TrimSpace(*note)
+	}
+	if *tags != "" {
+		active.tags = track.ParseTags(track.FormatTags(active.tags) + " " + *tags)
+	}
+	if *issueSpec != "" {
+		if active.issue, err = parseIssue(*issueSpec); err != nil {
+			return err
+		}
+	}
+
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	sessions := cfg.finishSessions(*active, time.Now())
+	if err := journal(sessions); err != nil {
+		return err
+	}
+	for _, sess := range sessions {
+		if err := storage.Append(sess); err != nil {
+			return err
+		}
+	}
+	if err := clearActive(); err != nil {
+		return err
+	}
+	if err := clearJournal(); err != nil {
+		return err
+	}
+
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+	sess := sessions[len(sessions)-1]
+	if err := writeReport(historyFile, history, cfg); err != nil {
+		return err
+	}
+
+	fmt.Printf("Stopped tracking %s after %s\n", track.ProjectLabel(sess.Project), cfg.durationLong(sess.Duration))
+	if len(sessions) > 1 {
+		fmt.Printf("Split at midnight into %d sessions\n", len(sessions))
+	}
+	// Append added the sessions at the end.
+	before := history[:max(0, len(history)-len(sessions))]
+	for _, warning := range budgetCrossing(cfg.projectBudgetUses(before, sess.Project), cfg.projectBudgetUses(history, sess.Project), cfg) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if err := cfg.sendWebhooks(stopEvent(sess)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if err := cfg.runHook(stopEvent(sess)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return nil
+}
+
+// statusInfo describes the current tracking state for `status -format`.
+type statusInfo struct {
+	State          string     `json:"state"` // "tracking", "paused" or "idle"
+	Project        string     `json:"project,omitempty"`
+	Tags           []string   `json:"tags,omitempty"`
+	Issue          string     `json:"issue,omitempty"`
+	Start          *time.Time `json:"start,omitempty"`
+	Elapsed        string     `json:"elapsed,omitempty"`
+	ElapsedSeconds int64      `json:"elapsed_seconds"`
+}
+
+func currentStatus(active *activeSession, cfg config, now time.Time) statusInfo {
+	if active == nil {
+		return statusInfo{State: "idle"}
+	}
+
+	elapsed := active.elapsed(now)
+	info := statusInfo{
+		State:          "tracking",
+		Project:        active.project,
+		Tags:           active.tags,
+		Issue:          active.issue,
+		Start:          &active.start,
+		Elapsed:        cfg.duration(elapsed),
+		ElapsedSeconds: int64(elapsed.Seconds()),
+	}
+	if active.paused() {
+		info.State = "paused"
+	}
+	return info
+}
+
+// runStatus prints whether a session is running and for how long. The
+// format flag picks between a sentence, a compact line for status bars, JSON,
+// or a Go template over statusInfo, e.g. '{{.Project}} {{.Elapsed}}'.
+func runStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	format := fs.String("format", "text", "output format: text, short, json, or a Go template")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	active, err := loadActive()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	info := currentStatus(active, cfg, time.Now())
+
+	switch {
+	case *format == "json":
+		return json.NewEncoder(os.Stdout).Encode(info)
+	case *format == "short":
+		// Empty when idle so prompts and status bars can hide the segment.
+		switch info.State {
+		case "tracking":
+			fmt.Printf("● %s %s\n", track.ProjectLabel(info.Project), info.Elapsed)
+		case "paused":
+			fmt.Printf("⏸ %s %s\n", track.ProjectLabel(info.Project), info.Elapsed)
+		default:
+			fmt.Println()
+		}
+		return nil
+	case strings.Contains(*format, "{{"):
+		tmpl, err := template.New("status").Parse(*format)
+		if err != nil {
+			return err
+		}
+		if err := tmpl.Execute(os.Stdout, info); err != nil {
+			return err
+		}
+		fmt.Println()
+		return nil
+	case *format == "text":
+		if active == nil {
+			fmt.Println("Not tracking")
+			return nil
+		}
+		state := "Tracking"
+		if active.paused() {
+			state = "Paused"
+		}
+		fmt.Printf("%s %s for %s (since %s)\n",
+			state,
+			track.ProjectLabel(active.project),
+			info.Elapsed,
+			active.start.Format("15:04"),
+		)
+		if active.issue != "" {
+			fmt.Printf("Linked to %s\n", active.issue)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+// runReport prints the history report to stdout.
+func runReport(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	rangeSpec := fs.String("range", "", "only report today, week, month, last-month or FROM..TO")
+	by := fs.String("by", "", "print totals by project, tag, client or issue instead of every session")
+	tmplPath := fs.String("template", "", "render the report with the Go text/template in `file`")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *by != "" && *by != "project" && *by != "tag" && *by != "client" && *by != "issue" {
+		return fmt.Errorf("unknown grouping %q (want project, tag, client or issue)", *by)
+	}
+	if *by != "" && *tmplPath != "" {
+		return errors.New("-by and -template cannot be combined; templates have every grouping")
+	}
+
+	dates, err := parseRange(*rangeSpec, time.Now())
+	if err != nil {
+		return err
+	}
+
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	all := history
+	history = dates.Filter(history)
+	if *tmplPath != "" {
+		tmpl, err := loadTemplate(*tmplPath, cfg)
+		if err != nil {
+			return err
+		}
+		return tmpl.Execute(os.Stdout, newReportData(history, dates, cfg))
+	}
+	switch *by {
+	case "project":
+		fmt.Print(renderTotals("Project", summarizeByProject(history, cfg), history, cfg))
+	case "tag":
+		fmt.Print(renderTotals("Tag", summarizeByTag(history, cfg), history, cfg))
+	case "client":
+		fmt.Print(renderTotals("Client", summarizeByClient(history, cfg), history, cfg))
+	case "issue":
+		rows := summarizeByIssue(history, cfg)
+		fmt.Print(renderTotals("Issue", rows, history, cfg))
+		fmt.Print(renderIssueLinks(rows))
+	default:
+		fmt.Print(renderReport(history, cfg))
+	}
+	if u := cfg.utilizationIn(history, dates, time.Now()).describe(cfg); u != "" {
+		fmt.Println("\n" + u)
+	}
+	// Budgets count everything tracked, whatever the range.
+	fmt.Print(renderBudgets(all, cfg))
+	return nil
+}
+
+// runInvoice prints an invoice for the billable sessions in a date range,
+// defaulting to the current month.
+func runInvoice(storageKind string, args []string) error {
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+
+	fs := flag.NewFlagSet("invoice", flag.ExitOnError)
+	from := fs.String("from", monthStart.Format(report.DateLayout), "first day to bill, YYYY-MM-DD")
+	to := fs.String("to", now.Format(report.DateLayout), "last day to bill, YYYY-MM-DD")
+	rangeSpec := fs.String("range", "", "bill week, month, last-month or FROM..TO instead of -from/-to")
+	project := fs.String("project", "", "only bill this project")
+	client := fs.String("client", "", "bill this client from config.json, or just name it on the \"Bill to\" line")
+	number := fs.String("number", "", "invoice number")
+	by := fs.String("by", "day", "line items per day or per session")
+	roundTo := fs.Duration("round", 0, "round each line item up to a multiple of this, e.g. 15m, instead of as config.json says")
+	format := fs.String("format", "text", "output format: text or html")
+	tmplPath := fs.String("template", "", "render the invoice with the Go text/template in `file` instead of -format")
+	out := fs.String("o", "", "write to `file` instead of stdout")
+	closeBilled := fs.Bool("close", false, "close the period billed so its sessions can no longer be edited")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts := invoiceOptions{
+		project: *project,
+		client:  *client,
+		number:  *number,
+	}
+	spec := *from + ".." + *to
+	if *rangeSpec != "" {
+		spec = *rangeSpec
+	}
+	dates, err := parseRange(spec, now)
+	if err != nil {
+		return err
+	}
+	if dates.From.IsZero() || dates.To.IsZero() {
+		return fmt.Errorf("invoice period %q needs both a start and an end", spec)
+	}
+	opts.from, opts.to = dates.From, dates.To
+	switch *by {
+	case "day":
+		opts.perDay = true
+	case "session":
+	default:
+		return fmt.Errorf("unknown -by %q (want day or session)", *by)
+	}
+	if *format != "text" && *format != "html" {
+		return fmt.Errorf("unknown format %q (want text or html)", *format)
+	}
+	var tmpl *template.Template
+
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if key, ok := cfg.findClient(*client); ok {
+		opts.clientKey = key
+		opts.client = cfg.clientName(key)
+		opts.address = cfg.Clients[key].Address
+	}
+	opts.rounding = cfg.Rounding
+	if *roundTo > 0 {
+		opts.rounding = rounding{To: roundTo.String(), Mode: roundUpward, Per: roundPerSession}
+		if opts.perDay {
+			opts.rounding.Per = roundPerDay
+		}
+	}
+	if opts.rounding.perDay() && !opts.perDay {
+		return errors.New("rounding per day needs line items per day (-by day)")
+	}
+	if *tmplPath != "" {
+		if tmpl, err = loadTemplate(*tmplPath, cfg); err != nil {
+			return err
+		}
+	}
+	inv := buildInvoice(history, cfg, opts)
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	switch {
+	case tmpl != nil:
+		err = tmpl.Execute(w, inv)
+	case *format == "html":
+		err = writeInvoiceHTML(w, inv)
+	default:
+		_, err = fmt.Fprint(w, renderInvoiceText(inv))
+	}
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		fmt.Printf("Wrote invoice for %s hours (%s) to %s\n", formatHours(inv.Hours), formatCurrency(inv.Total, inv.Currency), *out)
+	}
+	if *closeBilled {
+		cfg.closePeriod(dates)
+		return saveConfig(cfg)
+	}
+	return nil
+}
+
+// runRate shows or sets the hourly rates in configFile. With no argument it
+// lists the rates; with one it sets the global rate, or the rate for -project.
+func runRate(args []string) error {
+	fs := flag.NewFlagSet("rate", flag.ExitOnError)
+	project := fs.String("project", "", "set the rate for this project instead of the global rate")
+	unset := fs.Bool("unset", false, "remove the rate for -project so it uses the global rate")
+	fs.Parse(args)
+	if fs.NArg() > 1 || (*unset && fs.NArg() > 0) {
+		return errors.New("usage: rate [-project P] [RATE] or rate -project P -unset")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if *unset {
+		if *project == "" {
+			return errors.New("-unset needs -project")
+		}
+		delete(cfg.ProjectRates, *project)
+		return saveConfig(cfg)
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Printf("Default: %s/h\n", cfg.money(cfg.Rate))
+		projects := make([]string, 0, len(cfg.ProjectRates))
+		for name := range cfg.ProjectRates {
+			projects = append(projects, name)
+		}
+		sort.Strings(projects)
+		for _, name := range projects {
+			fmt.Printf("%s: %s/h\n", name, cfg.projectMoney(cfg.ProjectRates[name], name))
+		}
+		return nil
+	}
+
+	rate, err := strconv.ParseFloat(fs.Arg(0), 64)
+	if err != nil || rate < 0 {
+		return fmt.Errorf("invalid rate %q", fs.Arg(0))
+	}
+	if *project == "" {
+		cfg.Rate = rate
+	} else {
+		if cfg.ProjectRates == nil {
+			cfg.ProjectRates = make(map[string]float64)
+		}
+		cfg.ProjectRates[*project] = rate
+	}
+	return saveConfig(cfg)
+}
+
+// runExport writes the history as versioned JSON for backups or moving to
+// another machine, as iCalendar events to review in a calendar app, as CSV
+// or in a format added by a plugin.
+func runExport(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "json", "json, ics, csv or a format added by a plugin")
+	out := fs.String("o", "", "write to `file` instead of stdout")
+	rangeSpec := fs.String("range", "", "only export today, week, month, last-month or FROM..TO")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	dates, err := parseRange(*rangeSpec, time.Now())
+	if err != nil {
+		return err
+	}
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+	sessions := dates.Filter(history)
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	exp, err := cfg.exporter(*format)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return exp.Export(os.Stdout, sessions, cfg)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := exp.Export(f, sessions, cfg); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %s to %s\n", exportSummary(sessions, cfg), *out)
+	return nil
+}
+
+// runImport merges sessions from a JSON export, Timewarrior, Watson or a
+// plugin into the history, skipping ones it already has or, with -merge, merging them
+// into the stored ones. With -replace the history is replaced instead, and
+// with -dry-run nothing is written.
+func runImport(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	from := fs.String("from", importJSON, "format of PATH: json, timewarrior, watson or one added by a plugin")
+	replace := fs.Bool("replace", false, "replace the history instead of merging into it")
+	tolerance := fs.Duration("tolerance", importTolerance, "how far apart starts and ends of one project's sessions may be to count as duplicates")
+	combine := fs.Bool("merge", false, "merge the tags, notes and time of duplicates into the stored sessions instead of skipping them")
+	dryRun := fs.Bool("dry-run", false, "only list what would be imported")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: import [-from json|timewarrior|watson] [-replace] [-tolerance D] [-merge] [-dry-run] PATH (- for JSON on stdin)")
+	}
+	if *tolerance < 0 {
+		return fmt.Errorf("invalid tolerance %s", *tolerance)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	imp, err := cfg.importer(*from)
+	if err != nil {
+		return err
+	}
+	name := fs.Arg(0)
+	imported, err := imp.Import(name)
+	if err != nil {
+		return err
+	}
+	if name == "-" {
+		name = "stdin"
+	}
+	// Sessions no format could have recorded are reported and left out
+	// rather than stored to trip up totals later.
+	total := len(imported)
+	valid := imported[:0]
+	for _, sess := range imported {
+		if err := sess.Check(); err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", cfg.describeRecord(track.ToRecord(sess)), err)
+			continue
+		}
+		valid = append(valid, sess)
+	}
+	imported = valid
+
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+	// A replaced history is restored as it was.
+	if !*replace {
+		for i := range imported {
+			cfg.categorize(&imported[i], "", "")
+		}
+	}
+	if *replace {
+		if *dryRun {
+			fmt.Printf("Would replace %d sessions with %d from %s%s\n", len(history), len(imported), name, importResult{invalid: total - len(imported)}.summary())
+			return nil
+		}
+		history = imported
+	}
+
+	result := importResult{added: imported}
+	if !*replace {
+		history, result = mergeSessions(history, imported, *tolerance, *combine)
+	}
+	result.invalid = total - len(imported)
+	if *dryRun {
+		result.preview(cfg)
+		fmt.Printf("Would import %d of %d sessions from %s%s\n", len(result.added), total, name, result.summary())
+		return nil
+	}
+	if err := storage.Save(history); err != nil {
+		return err
+	}
+	if err := appendAudit(auditFile, result.audit()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if err := writeReport(historyFile, history, cfg); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d of %d sessions from %s%s\n", len(result.added), total, name, result.summary())
+	return nil
+}
+
+// runMigrate copies every session from one storage backend to another, e.g.
+// to move an existing history.txt or sessions.json into SQLite.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := fs.String("from", storageJSON, "storage backend to read from")
+	to := fs.String("to", storageSQLite, "storage backend to write to")
+	force := fs.Bool("force", false, "overwrite sessions already in the destination")
+	fromDir := fs.String("from-dir", "", "move the files an earlier version kept in `DIR` into the data directory")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *fromDir != "" {
+		return migrateDataDir(*fromDir, filepath.Dir(dataFile))
+	}
+	if *from == *to {
+		return fmt.Errorf("source and destination are both %s", *from)
+	}
+
+	src, err := openStorage(*from)
+	if err != nil {
+		return err
+	}
+	dst, err := openStorage(*to)
+	if err != nil {
+		return err
+	}
+
+	history, err := src.Load()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", *from, err)
+	}
+
+	existing, err := dst.Load()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", *to, err)
+	}
+	if len(existing) > 0 && !*force {
+		return fmt.Errorf("%s already has %d sessions; use -force to overwrite", *to, len(existing))
+	}
+
+	if err := dst.Save(history); err != nil {
+		return fmt.Errorf("writing %s: %w", *to, err)
+	}
+
+	fmt.Printf("Migrated %d sessions from %s to %s\n", len(history), *from, *to)
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// client is someone work is billed to, with the projects done for them.
+type client struct {
+	// Name is shown on invoices and reports; it defaults to the client's key
+	// in config.json.
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address,omitempty"`
+	// Rate is the hourly rate for the client's projects that have none of
+	// their own.
+	Rate float64 `json:"rate,omitempty"`
+	// Currency is the ISO code of the currency the client is billed in,
+	// e.g. "EUR", if it is not the home currency.
+	Currency string   `json:"currency,omitempty"`
+	Projects []string `json:"projects,omitempty"`
+	Budget   budget   `json:"budget,omitzero"`
+}
+
+// checkClients makes sure no project belongs to two clients and budgets are
+// not negative.
+func checkClients(clients map[string]client) error {
+	owner := make(map[string]string)
+	keys := make([]string, 0, len(clients))
+	for key := range clients {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := checkBudget("client "+key, clients[key].Budget); err != nil {
+			return err
+		}
+		for _, project := range clients[key].Projects {
+			if other, ok := owner[project]; ok {
+				return fmt.Errorf("project %q belongs to both clients %q and %q", project, other, key)
+			}
+			owner[project] = key
+		}
+	}
+	return nil
+}
+
+// clientOf returns the key of the client project is done for, or "" if it
+// has none.
+func (c config) clientOf(project string) string {
+	for key, cl := range c.Clients {
+		for _, p := range cl.Projects {
+			if p == project {
+				return key
+			}
+		}
+	}
+	return ""
+}
+
+// clientName is how the client with key is shown.
+func (c config) clientName(key string) string {
+	if key == "" {
+		return noClientLabel
+	}
+	if name := c.Clients[key].Name; name != "" {
+		return name
+	}
+	return key
+}
+
+// findClient returns the key of the client called name, matching either its
+// key or its name.
+func (c config) findClient(name string) (string, bool) {
+	if _, ok := c.Clients[name]; ok {
+		return name, true
+	}
+	for key, cl := range c.Clients {
+		if cl.Name != "" && cl.Name == name {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// summarizeByClient totals history per client, largest first.
+func summarizeByClient(history []session, cfg config) []summaryRow {
+	rows := summarize(history, cfg, func(sess session) (string, string) {
+		key := cfg.clientOf(sess.Project)
+		return key, cfg.clientName(key)
+	})
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].total > rows[j].total })
+	return rows
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+)
+
+const clockifyAPI = "https://api.clockify.me/api/v1"
+
+// clockifyPageSize is the number of entries asked for per page.
+const clockifyPageSize = 1000
+
+// clockifyConfig is the "clockify" section of config.json.
+type clockifyConfig struct {
+	// APIKey is from the Clockify profile settings; CLOCKIFY_API_KEY
+	// overrides it.
+	APIKey      string `json:"api_key,omitempty"`
+	WorkspaceID string `json:"workspace_id,omitempty"`
+	// Projects maps local project names to Clockify project names where
+	// they differ. Unmapped names are used as is.
+	Projects map[string]string `json:"projects,omitempty"`
+}
+
+// clockifyEntry is a Clockify time entry as returned by the API.
+type clockifyEntry struct {
+	ID           string `json:"id"`
+	TimeInterval struct {
+		Start time.Time  `json:"start"`
+		End   *time.Time `json:"end"`
+	} `json:"timeInterval"`
+}
+
+// clockifyNewEntry is a time entry as accepted by the API.
+type clockifyNewEntry struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Description string    `json:"description,omitempty"`
+	ProjectID   string    `json:"projectId,omitempty"`
+	TagIDs      []string  `json:"tagIds,omitempty"`
+	Billable    bool      `json:"billable"`
+}
+
+// clockifyNamed is a project or tag.
+type clockifyNamed struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// clockifyClient talks to the Clockify API for one workspace.
+type clockifyClient struct {
+	jsonAPI
+	workspace string
+	cfg       clockifyConfig
+	// Filled by load: the user's entries, and project and tag IDs by name.
+	entries  []clockifyEntry
+	projects map[string]string
+	tags     map[string]string
+}
+
+func newClockifyClient(cfg clockifyConfig) (*clockifyClient, error) {
+	key := cfg.APIKey
+	if env := os.Getenv("CLOCKIFY_API_KEY"); env != "" {
+		key = env
+	}
+	if key == "" {
+		return nil, errors.New("no Clockify API key: set clockify.api_key in config.json or CLOCKIFY_API_KEY")
+	}
+	api := jsonAPI{name: "clockify", base: clockifyAPI, auth: func(req *http.Request) error {
+		req.Header.Set("X-Api-Key", key)
+		return nil
+	}}
+	return &clockifyClient{jsonAPI: api, workspace: cfg.WorkspaceID, cfg: cfg}, nil
+}
+
+// names fetches the projects or tags of the workspace, by name.
+func (c *clockifyClient) names(kind string) (map[string]string, error) {
+	var list []clockifyNamed
+	if err := c.do(http.MethodGet, fmt.Sprintf("/workspaces/%s/%s?page-size=5000", c.workspace, kind), nil, &list); err != nil {
+		return nil, err
+	}
+	ids := make(map[string]string)
+	for _, n := range list {
+		ids[n.Name] = n.ID
+	}
+	return ids, nil
+}
+
+// load fetches the user's finished entries that start within r and the
+// workspace's projects and tags, first looking up the active workspace if
+// none is configured.
+func (c *clockifyClient) load(r dateRange) error {
+	var user struct {
+		ID              string `json:"id"`
+		ActiveWorkspace string `json:"activeWorkspace"`
+	}
+	if err := c.do(http.MethodGet, "/user", nil, &user); err != nil {
+		return err
+	}
+	if c.workspace == "" {
+		c.workspace = user.ActiveWorkspace
+	}
+	var err error
+	if c.projects, err = c.names("projects"); err != nil {
+		return err
+	}
+	if c.tags, err = c.names("tags"); err != nil {
+		return err
+	}
+
+	q := url.Values{"page-size": {fmt.Sprint(clockifyPageSize)}}
+	if !r.From.IsZero() {
+		q.Set("start", r.From.UTC().Format(time.RFC3339))
+	}
+	if !r.To.IsZero() {
+		q.Set("end", r.To.UTC().Format(time.RFC3339))
+	}
+	c.entries = nil
+	for page := 1; ; page++ {
+		q.Set("page", fmt.Sprint(page))
+		var entries []clockifyEntry
+		if err := c.do(http.MethodGet, fmt.Sprintf("/workspaces/%s/user/%s/time-entries?%s", c.workspace, user.ID, q.Encode()), nil, &entries); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.TimeInterval.End != nil {
+				c.entries = append(c.entries, e)
+			}
+		}
+		if len(entries) < clockifyPageSize {
+			return nil
+		}
+	}
+}
+
+// has reports whether an entry has the start and duration of sess. Clockify
+// keeps whole seconds only, so times are compared at that precision.
+func (c *clockifyClient) has(sess session) bool {
+	return slices.ContainsFunc(c.entries, func(e clockifyEntry) bool {
+		return sess.Start.Truncate(time.Second).Equal(e.TimeInterval.Start) &&
+			sess.Duration.Truncate(time.Second) == e.TimeInterval.End.Sub(e.TimeInterval.Start)
+	})
+}
+
+func (c *clockifyClient) check(sess session) error {
+	_, err := c.toEntry(sess)
+	return err
+}
+
+func (c *clockifyClient) push(sess session) error {
+	e, err := c.toEntry(sess)
+	if err != nil {
+		return err
+	}
+	return c.do(http.MethodPost, fmt.Sprintf("/workspaces/%s/time-entries", c.workspace), e, nil)
+}
+
+// toEntry converts sess to a new Clockify entry. Pauses are not represented
+// in Clockify, so the entry ends once the tracked time has elapsed, and tags
+// Clockify does not know are left out.
+func (c *clockifyClient) toEntry(sess session) (clockifyNewEntry, error) {
+	start := sess.Start.Truncate(time.Second).UTC()
+	e := clockifyNewEntry{
+		Start:       start,
+		End:         start.Add(sess.Duration.Truncate(time.Second)),
+		Description: sess.Note,
+		Billable:    sess.Billable,
+	}
+	for _, tag := range sess.Tags {
+		if id, ok := c.tags[tag]; ok {
+			e.TagIDs = append(e.TagIDs, id)
+		}
+	}
+	if sess.Project != "" {
+		name := remoteProject(c.cfg.Projects, sess.Project)
+		id, ok := c.projects[name]
+		if !ok {
+			return e, fmt.Errorf("no Clockify project %q for %s; add it in Clockify or map it under clockify.projects", name, sess.Project)
+		}
+		e.ProjectID = id
+	}
+	return e, nil
+}
+
+// runClockify pushes local sessions to Clockify.
+func runClockify(storageKind string, args []string) error {
+	return runTimesheetPush(storageKind, "clockify", "Clockify", args, func(cfg config) (timesheet, error) {
+		return newClockifyClient(cfg.Clockify)
+	})
+}
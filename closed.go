@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/report"
+)
+
+// checkClosed makes sure every closed period in config.json is a range of
+// whole days with both ends given.
+func checkClosed(periods []string) error {
+	for _, spec := range periods {
+		if _, err := parseClosed(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseClosed(spec string) (dateRange, error) {
+	r, err := parseRange(spec, time.Now())
+	if err != nil {
+		return r, fmt.Errorf("closed period: %w", err)
+	}
+	if r.From.IsZero() || r.To.IsZero() {
+		return r, fmt.Errorf("closed period %q needs both a start and an end", spec)
+	}
+	return r, nil
+}
+
+// closedSpec writes r as the FROM..TO spec it is stored under, so that
+// relative ranges such as last-month keep meaning the days they meant when
+// they were closed.
+func closedSpec(r dateRange) string {
+	return r.From.Format(report.DateLayout) + ".." + r.To.AddDate(0, 0, -1).Format(report.DateLayout)
+}
+
+// closedPeriod returns the closed period t falls in, if any.
+func (c config) closedPeriod(t time.Time) (string, dateRange, bool) {
+	for _, spec := range c.Closed {
+		// loadConfig has checked the closed periods.
+		r, _ := parseClosed(spec)
+		if r.Contains(t) {
+			return spec, r, true
+		}
+	}
+	return "", dateRange{}, false
+}
+
+// lockedIn returns the closed period of the first of the sessions at indices
+// into history that lies in one.
+func (m model) lockedIn(indices []int) (string, dateRange, bool) {
+	for _, i := range indices {
+		if spec, r, ok := m.config.closedPeriod(m.history[i].Start); ok {
+			return spec, r, true
+		}
+	}
+	return "", dateRange{}, false
+}
+
+// editable reports whether the sessions at indices may be changed. If one
+// lies in a closed period it instead asks whether to unlock that period,
+// returning the dialog to show; in read-only mode it says why not.
+func (m model) editable(indices ...int) (tea.Model, bool) {
+	if m.blockedReadOnly() {
+		return m, false
+	}
+	spec, r, ok := m.lockedIn(indices)
+	if !ok {
+		return m, true
+	}
+	dialog, _ := m.unlockPrompt(spec, r)
+	return dialog, false
+}
+
+// unlockPrompt asks whether to reopen the closed period spec so its sessions
+// can be edited again.
+func (m model) unlockPrompt(spec string, r dateRange) (tea.Model, tea.Cmd) {
+	return m.confirm("🔒 Period closed",
+		fmt.Sprintf("%s is closed for billing, so its sessions cannot be changed.\nUnlock it to edit them anyway?", r.Label),
+		confirmOption{"n", "Cancel", cancelDialog},
+		confirmOption{"u", "Unlock", func(m model) (tea.Model, tea.Cmd) {
+			m.config.Closed = slices.DeleteFunc(slices.Clone(m.config.Closed), func(s string) bool { return s == spec })
+			if err := saveConfig(m.config); err != nil {
+				m.status = fmt.Sprintf("Unlock failed: %v", err)
+				return m, nil
+			}
+			m.status = fmt.Sprintf("Unlocked %s", r.Label)
+			return m, nil
+		}},
+	)
+}
+
+// runClose closes a period for billing, or reopens one with -unlock. With no
+// argument it lists the closed periods.
+func runClose(args []string) error {
+	fs := flag.NewFlagSet("close", flag.ExitOnError)
+	reopen := fs.Bool("unlock", false, "reopen the closed period the given range or day falls in")
+	fs.Parse(args)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	switch fs.NArg() {
+	case 0:
+		if *reopen {
+			return errors.New("-unlock needs a period or a day in it")
+		}
+		if len(cfg.Closed) == 0 {
+			fmt.Println("No closed periods; close one with e.g. time-tracker close last-month")
+		}
+		for _, spec := range cfg.Closed {
+			r, _ := parseClosed(spec)
+			fmt.Println(r.Label)
+		}
+		return nil
+	case 1:
+	default:
+		return errors.New("usage: close [RANGE] | close -unlock RANGE")
+	}
+
+	r, err := parseClosed(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *reopen {
+		n := len(cfg.Closed)
+		cfg.Closed = slices.DeleteFunc(cfg.Closed, func(spec string) bool {
+			closed, _ := parseClosed(spec)
+			return closed.From.Before(r.To) && r.From.Before(closed.To)
+		})
+		if len(cfg.Closed) == n {
+			return fmt.Errorf("nothing is closed in %s", r.Label)
+		}
+		return saveConfig(cfg)
+	}
+	cfg.closePeriod(r)
+	return saveConfig(cfg)
+}
+
+// closePeriod adds r to the closed periods unless it is closed already.
+func (c *config) closePeriod(r dateRange) {
+	spec := closedSpec(r)
+	if !slices.Contains(c.Closed, spec) {
+		c.Closed = append(c.Closed, spec)
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// Keys of the toggles in the settings view.
+const (
+	settingShowSeconds   = "Show seconds"
+	settingAutoSave      = "Auto-save"
+	settingNotifications = "Notifications"
+	settingSound         = "Sound alerts"
+	settingDarkMode      = "Dark mode"
+	settingDecimalHours  = "Decimal hours"
+)
+
+// defaultSettings are the settings toggles used when config.json does not set
+// them. Dark mode is left out so the TUI can match the terminal background
+// until it is chosen explicitly.
+var defaultSettings = map[string]bool{
+	settingShowSeconds:   true,
+	settingAutoSave:      true,
+	settingNotifications: false,
+	settingSound:         false,
+	settingDecimalHours:  false,
+}
+
+// config is the user configuration kept in configFile.
+type config struct {
+	Settings map[string]bool `json:"settings,omitempty"`
+
+	// Rate is the hourly rate for sessions whose project has no entry in
+	// ProjectRates.
+	Rate         float64            `json:"rate,omitempty"`
+	ProjectRates map[string]float64 `json:"project_rates,omitempty"`
+	// Currency is the ISO code of the home currency, e.g. "USD", that totals
+	// are given in. ExchangeRates converts other currencies clients are
+	// billed in at a fixed rate: how much of the home currency one unit is
+	// worth.
+	Currency      string             `json:"currency,omitempty"`
+	ExchangeRates map[string]float64 `json:"exchange_rates,omitempty"`
+	// NonBillable lists projects whose sessions start out not billable.
+	NonBillable []string `json:"non_billable,omitempty"`
+	// Clients group projects by who they are done for, keyed by a short
+	// name used on the command line.
+	Clients map[string]client `json:"clients,omitempty"`
+	// Budgets cap the hours or money spent on projects, by name.
+	Budgets map[string]budget `json:"budgets,omitempty"`
+	// Estimates are the time planned for projects, or for tasks tracked
+	// under a tag when the key is "#tag", e.g. "20h".
+	Estimates map[string]string `json:"estimates,omitempty"`
+	// Rounding rounds billed time in reports, the summary and invoices.
+	Rounding rounding `json:"rounding,omitzero"`
+	// Closed are billing periods, as FROM..TO days, whose sessions can no
+	// longer be edited in the UI without unlocking them first.
+	Closed []string `json:"closed,omitempty"`
+
+	// Theme names a color theme from Themes or the built-in ones ("dark",
+	// "light", "high-contrast"). Empty follows the Dark mode setting.
+	Theme  string             `json:"theme,omitempty"`
+	Themes map[string]palette `json:"themes,omitempty"`
+
+	// DailyGoal is the time to track each day, e.g. "6h". Empty means none.
+	DailyGoal string `json:"daily_goal,omitempty"`
+	// WeeklyTarget, e.g. "40h", enables a flex-time balance in the summary
+	// counted from BalanceSince (YYYY-MM-DD) or the first session.
+	WeeklyTarget string `json:"weekly_target,omitempty"`
+	BalanceSince string `json:"balance_since,omitempty"`
+	// LongSession, e.g. "4h", is how long a session can run before it is
+	// flagged as a possibly forgotten timer. Empty means never.
+	LongSession string `json:"long_session,omitempty"`
+	// StreakMinimum, e.g. "4h", is the time a day needs tracked to count
+	// towards a streak. Empty counts any tracked day.
+	StreakMinimum string `json:"streak_minimum,omitempty"`
+	// BreakEvery, e.g. "50m", is how long a session can run without a
+	// pause before a break is suggested. Empty means never. BreakSnooze is
+	// how long snoozing the suggestion puts it off, 10 minutes by default.
+	BreakEvery  string `json:"break_every,omitempty"`
+	BreakSnooze string `json:"break_snooze,omitempty"`
+	// AlertSound is a sound file played along with the terminal bell when
+	// Sound alerts is on.
+	AlertSound string `json:"alert_sound,omitempty"`
+	// StartReminder, e.g. "15m", is how long nothing can be tracked within
+	// working hours before a reminder to start. Empty means never.
+	StartReminder string `json:"start_reminder,omitempty"`
+	// AtMidnight is "split" to cut running sessions in two at midnight or
+	// "stop" to end them there. Empty leaves them running.
+	AtMidnight string `json:"at_midnight,omitempty"`
+	// Timezone, e.g. "America/New_York", is the time zone times are shown
+	// and days counted in. Empty uses the computer's.
+	Timezone string `json:"timezone,omitempty"`
+	// WorkHours, e.g. "09:00-17:00", and WorkDays, e.g. ["mon", "tue"],
+	// are the working schedule: only time within it is reported as gaps or
+	// reminded about, and reports measure utilization against it. Monday to
+	// Friday unless set.
+	WorkHours string   `json:"work_hours,omitempty"`
+	WorkDays  []string `json:"work_days,omitempty"`
+
+	// Favorites are projects pinned to the top of the start prompt.
+	Favorites []string `json:"favorites,omitempty"`
+
+	// Keys remaps key bindings by name, e.g. "delete": ["x"].
+	Keys map[string][]string `json:"keys,omitempty"`
+
+	// Rules fill in the project, tags and billing of sessions as they are
+	// started or imported.
+	Rules []rule `json:"rules,omitempty"`
+
+	// Webhooks are notified when tracking starts, stops or reaches a
+	// threshold; Hooks run shell commands when it starts, stops, pauses or
+	// resumes.
+	Webhooks []webhook `json:"webhooks,omitempty"`
+	Hooks    hooks     `json:"hooks,omitzero"`
+
+	Toggl    togglConfig    `json:"toggl,omitzero"`
+	Clockify clockifyConfig `json:"clockify,omitzero"`
+	Harvest  harvestConfig  `json:"harvest,omitzero"`
+	Slack    slackConfig    `json:"slack,omitzero"`
+
+	// Server is the time-tracker serve that sync keeps the history in
+	// step with.
+	Server serverConfig `json:"server,omitzero"`
+
+	GoogleCalendar gcalConfig          `json:"google_calendar,omitzero"`
+	CalDAV         caldavConfig        `json:"caldav,omitzero"`
+	ActivityWatch  activityWatchConfig `json:"activitywatch,omitzero"`
+
+	// Backups are remote copies of the data file, uploaded after every
+	// save.
+	Backups []backupTarget `json:"backups,omitempty"`
+
+	// Plugins are programs adding export formats, import sources, sync
+	// targets or reactions to tracking events.
+	Plugins []pluginConfig `json:"plugins,omitempty"`
+}
+
+// loadConfig reads configFile, filling in defaults for anything it does not
+// set. A missing file yields the defaults.
+func loadConfig() (config, error) {
+	var cfg config
+	data, err := os.ReadFile(configFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return cfg, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("parsing %s: %w", configFile, err)
+		}
+	}
+
+	if _, ok := cfg.Themes[cfg.Theme]; cfg.Theme != "" && !ok {
+		if _, ok := builtinThemes[cfg.Theme]; !ok {
+			return cfg, fmt.Errorf("%s: unknown theme %q", configFile, cfg.Theme)
+		}
+	}
+
+	if _, err := newKeyMap(cfg.Keys); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+
+	if err := checkGoal(cfg.DailyGoal); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkWeeklyTarget(cfg.WeeklyTarget, cfg.BalanceSince); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkLongSession(cfg.LongSession); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkStreakMinimum(cfg.StreakMinimum); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkBreaks(cfg.BreakEvery, cfg.BreakSnooze); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkStartReminder(cfg.StartReminder); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkAtMidnight(cfg.AtMidnight); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkClients(cfg.Clients); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	for project, b := range cfg.Budgets {
+		if err := checkBudget(track.ProjectLabel(project), b); err != nil {
+			return cfg, fmt.Errorf("%s: %w", configFile, err)
+		}
+	}
+	if err := checkEstimates(cfg.Estimates); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkCurrencies(cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkRounding(cfg.Rounding); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkClosed(cfg.Closed); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkBackups(cfg.Backups); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkPlugins(cfg.Plugins); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkSlack(cfg.Slack); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := checkActivityWatch(&cfg.ActivityWatch); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+	if cfg.Timezone != "" {
+		if err := checkTimezone(cfg.Timezone); err != nil {
+			return cfg, fmt.Errorf("%s: %w", configFile, err)
+		}
+	}
+	if cfg.WorkHours != "" {
+		if _, _, err := parseWorkHours(cfg.WorkHours); err != nil {
+			return cfg, fmt.Errorf("%s: %w", configFile, err)
+		}
+	}
+	if _, err := parseWorkDays(cfg.WorkDays); err != nil {
+		return cfg, fmt.Errorf("%s: %w", configFile, err)
+	}
+
+	for i := range cfg.Webhooks {
+		if err := cfg.Webhooks[i].parse(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", configFile, err)
+		}
+	}
+	for i := range cfg.Rules {
+		if err := cfg.Rules[i].parse(); err != nil {
+			return cfg, fmt.Errorf("%s: %w", configFile, err)
+		}
+	}
+
+	if cfg.Settings == nil {
+		cfg.Settings = make(map[string]bool)
+	}
+	for key, value := range defaultSettings {
+		if _, ok := cfg.Settings[key]; !ok {
+			cfg.Settings[key] = value
+		}
+	}
+	return cfg, nil
+}
+
+// duration formats d for display: as decimal hours if the Decimal hours
+// setting is on, otherwise as a clock with seconds only if the Show seconds
+// setting is on.
+func (c config) duration(d time.Duration) string {
+	if c.Settings[settingDecimalHours] {
+		return formatHours(d) + "h"
+	}
+	return c.timer(d)
+}
+
+// timer formats the time on a running clock, which always counts in hh:mm
+// or hh:mm:ss.
+func (c config) timer(d time.Duration) string {
+	if c.Settings[settingShowSeconds] {
+		return track.FormatDuration(d)
+	}
+	return track.FormatMinutes(d)
+}
+
+// durationLong is duration in the "1h 5m 3s" style.
+func (c config) durationLong(d time.Duration) string {
+	if c.Settings[settingDecimalHours] {
+		return formatHours(d) + "h"
+	}
+	if c.Settings[settingShowSeconds] {
+		return track.FormatDurationLong(d)
+	}
+	return track.FormatMinutesLong(d)
+}
+
+func saveConfig(cfg config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(configFile, data, 0644)
+}
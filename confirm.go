@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var dialogStyle = lipgloss.NewStyle().
+	Border(lipgloss.RoundedBorder()).
+	Padding(1, 2)
+
+// confirmOption is one choice in a confirm dialog, picked with its hotkey or
+// by moving to it and pressing enter.
+type confirmOption struct {
+	hotkey string
+	label  string
+	run    func(m model) (tea.Model, tea.Cmd)
+}
+
+// confirmDialog asks the user to choose between options before something
+// destructive happens. esc always cancels.
+type confirmDialog struct {
+	title   string
+	message string
+	options []confirmOption
+	cursor  int
+}
+
+// cancelDialog is the run function for options that just close the dialog.
+func cancelDialog(m model) (tea.Model, tea.Cmd) {
+	return m, nil
+}
+
+// confirm opens a dialog; the first option is highlighted.
+func (m model) confirm(title, message string, options ...confirmOption) (tea.Model, tea.Cmd) {
+	m.dialog = &confirmDialog{title: title, message: message, options: options}
+	return m, nil
+}
+
+func (m model) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	d := *m.dialog
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc":
+		m.dialog = nil
+		return m, nil
+	case "left", "h", "shift+tab", "up":
+		d.cursor = (d.cursor + len(d.options) - 1) % len(d.options)
+		m.dialog = &d
+		return m, nil
+	case "right", "l", "tab", "down":
+		d.cursor = (d.cursor + 1) % len(d.options)
+		m.dialog = &d
+		return m, nil
+	case "enter":
+		m.dialog = nil
+		return d.options[d.cursor].run(m)
+	}
+	for _, opt := range d.options {
+		if msg.String() == opt.hotkey {
+			m.dialog = nil
+			return opt.run(m)
+		}
+	}
+	return m, nil
+}
+
+func (m model) viewConfirm() string {
+	d := m.dialog
+
+	var options []string
+	for i, opt := range d.options {
+		label := opt.label + " (" + opt.hotkey + ")"
+		if i == d.cursor {
+			options = append(options, selectedStyle.Render("[ "+label+" ]"))
+		} else {
+			options = append(options, normalStyle.Render("  "+label+"  "))
+		}
+	}
+
+	s := titleStyle.Render(d.title) + "\n"
+	if d.message != "" {
+		s += normalStyle.Render(d.message) + "\n\n"
+	}
+	s += strings.Join(options, " ") + "\n\n"
+	s += helpStyle.Render("←/→: choose • enter: confirm • esc: cancel")
+
+	box := dialogStyle.BorderForeground(titleStyle.GetForeground()).Render(s)
+	if m.width > 0 && m.height > 0 {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box)
+	}
+	return box
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// countdownWarning is how long before the target the countdown turns to the
+// warning color, at most; short targets warn for their last fifth instead.
+const countdownWarning = 5 * time.Minute
+
+// remaining is how much of the session's target is left after elapsed,
+// negative once it has overrun.
+func (a activeSession) remaining(elapsed time.Duration) time.Duration {
+	return a.target - elapsed
+}
+
+// nearTarget reports whether the countdown is close enough to zero to
+// warn about it.
+func (a activeSession) nearTarget(elapsed time.Duration) bool {
+	return a.target > 0 && a.remaining(elapsed) <= min(countdownWarning, a.target/5)
+}
+
+// viewCountdown renders the time left until the running session's target,
+// or how far it has overrun, or nothing if it has no target.
+func (m model) viewCountdown() string {
+	if m.active.target <= 0 {
+		return ""
+	}
+	left := m.active.remaining(m.elapsed)
+	if left <= 0 {
+		return warningStyle.Render(fmt.Sprintf("Overrun: +%s past the %s target", m.config.timer(-left), track.FormatMinutesLong(m.active.target))) + "\n\n"
+	}
+	line := fmt.Sprintf("Remaining: %s of %s", m.config.timer(left), track.FormatMinutesLong(m.active.target))
+	if m.active.nearTarget(m.elapsed) {
+		return warningStyle.Render(line) + "\n\n"
+	}
+	return normalStyle.Render(line) + "\n\n"
+}
+
+// countdownAlert rings the terminal bell and sends a notification when the
+// running session reaches its target, i.e. when it was short of it with
+// before tracked and is not with m.elapsed. Recording carries on.
+func (m model) countdownAlert(before time.Duration) tea.Cmd {
+	if m.active == nil || m.active.target <= 0 || before >= m.active.target || m.elapsed < m.active.target {
+		return nil
+	}
+	return tea.Batch(bellCmd(), m.soundCmd(), m.notifyCmd("Time's up",
+		fmt.Sprintf("%s has reached its %s target; still recording.", track.ProjectLabel(m.active.project), track.FormatMinutesLong(m.active.target))))
+}
+
+// bellCmd rings the terminal bell.
+func bellCmd() tea.Cmd {
+	return func() tea.Msg {
+		os.Stdout.WriteString("\a")
+		return nil
+	}
+}
+
+// startCountdownEdit opens the inline editor for the running session's
+// target.
+func (m model) startCountdownEdit() (tea.Model, tea.Cmd) {
+	m.editing = editCountdown
+	m.editTarget = -1
+	m.editInput.Placeholder = "45m"
+	m.editInput.SetValue("")
+	if m.active.target > 0 {
+		m.editInput.SetValue(strings.ReplaceAll(track.FormatMinutesLong(m.active.target), " ", ""))
+	}
+	m.editInput.CursorEnd()
+	return m, m.editInput.Focus()
+}
+
+// applyCountdown sets the running session's target from s, such as "45m" or
+// "1h30m"; empty or 0 clears it. An invalid value is reported in the status
+// line.
+func (m *model) applyCountdown(s string) {
+	if m.active == nil {
+		return
+	}
+	target, err := parseTarget(s)
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.active.target = target
+	saveActive(*m.active)
+}
+
+// parseTarget reads a countdown target; empty means none.
+func parseTarget(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid target %q (want e.g. 45m or 1h30m)", s)
+	}
+	return d, nil
+}
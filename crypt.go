@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// Encrypted files start with sealMagic, followed by the salt the key was
+// derived with, the nonce and the AES-256-GCM ciphertext.
+const (
+	sealMagic     = "time-tracker sealed v1\n"
+	saltSize      = 16
+	keyIterations = 600_000
+)
+
+// passphraseEnv holds the passphrase for encrypted data, for scripts and
+// status lines that cannot be asked for it.
+const passphraseEnv = "TIME_TRACKER_PASSPHRASE"
+
+var errBadPassphrase = errors.New("wrong passphrase")
+
+// dataKey encrypts the history, the journal, the running session, the trash
+// and the audit log. It is nil while they are stored in the clear.
+var dataKey *sealKey
+
+type sealKey struct {
+	salt []byte
+	aead cipher.AEAD
+}
+
+// deriveKey stretches passphrase into an AES-256 key with PBKDF2, so that
+// guessing it from a stolen file is slow.
+func deriveKey(passphrase string, salt []byte) (*sealKey, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, keyIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &sealKey{salt: salt, aead: aead}, nil
+}
+
+// sealed reports whether data was encrypted by seal.
+func sealed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(sealMagic))
+}
+
+func (k *sealKey) seal(data []byte) []byte {
+	nonce := make([]byte, k.aead.NonceSize())
+	rand.Read(nonce)
+	out := append([]byte(sealMagic), k.salt...)
+	out = append(out, nonce...)
+	return k.aead.Seal(out, nonce, data, nil)
+}
+
+// open decrypts data sealed with k. Data sealed with another passphrase, or
+// tampered with, fails with errBadPassphrase.
+func (k *sealKey) open(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(data, []byte(sealMagic))
+	if len(data) < saltSize+k.aead.NonceSize() || !bytes.Equal(data[:saltSize], k.salt) {
+		return nil, errBadPassphrase
+	}
+	nonce, ciphertext := data[saltSize:saltSize+k.aead.NonceSize()], data[saltSize+k.aead.NonceSize():]
+	plain, err := k.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errBadPassphrase
+	}
+	return plain, nil
+}
+
+// readData reads the file at path, decrypting it if it is encrypted.
+func readData(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || !sealed(data) {
+		return data, err
+	}
+	if dataKey == nil {
+		return nil, fmt.Errorf("%s is encrypted, but %s is not", path, dataFile)
+	}
+	data, err = dataKey.open(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
+
+// writeData writes data to path atomically, encrypted if the history is.
+func writeData(path string, data []byte) error {
+	if dataKey != nil {
+		data = dataKey.seal(data)
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// sealLine encrypts one line of an append-only file such as the audit log,
+// or leaves it as it is while the history is stored in the clear.
+func sealLine(line []byte) []byte {
+	if dataKey == nil {
+		return line
+	}
+	return []byte(base64.StdEncoding.EncodeToString(dataKey.seal(line)))
+}
+
+// openLine reverses sealLine. Lines written in the clear start with "{".
+func openLine(line []byte) ([]byte, error) {
+	if bytes.HasPrefix(line, []byte("{")) {
+		return line, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(string(line))
+	if err != nil {
+		return nil, err
+	}
+	if dataKey == nil {
+		return nil, fmt.Errorf("encrypted, but %s is not", dataFile)
+	}
+	return dataKey.open(data)
+}
+
+// unlockData asks for the passphrase if the history is encrypted, before
+// anything is read or written, and keeps the key for the rest of the run.
+func unlockData() error {
+	data, err := os.ReadFile(dataFile)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !sealed(data)) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	salt := bytes.TrimPrefix(data, []byte(sealMagic))
+	if len(salt) < saltSize {
+		return fmt.Errorf("%s: truncated", dataFile)
+	}
+	passphrase, err := askPassphrase(fmt.Sprintf("Passphrase for %s: ", dataFile))
+	if err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, salt[:saltSize])
+	if err != nil {
+		return err
+	}
+	if _, err := key.open(data); err != nil {
+		return err
+	}
+	dataKey = key
+	return nil
+}
+
+// dataEncrypted reports whether the history is encrypted.
+func dataEncrypted() bool {
+	f, err := os.Open(dataFile)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, len(sealMagic))
+	n, _ := f.Read(head)
+	return sealed(head[:n])
+}
+
+// askPassphrase reads a passphrase from passphraseEnv or, failing that,
+// from the terminal without echoing it.
+func askPassphrase(prompt string) (string, error) {
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return "", fmt.Errorf("the history is encrypted; set %s to its passphrase", passphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	return string(passphrase), err
+}
+
+// newPassphrase asks for a new passphrase twice, so a typo cannot lock the
+// history away.
+func newPassphrase() (string, error) {
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	passphrase, err := askPassphrase("New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("the passphrase must not be empty")
+	}
+	again, err := askPassphrase("Repeat it: ")
+	if err != nil {
+		return "", err
+	}
+	if again != passphrase {
+		return "", errors.New("the passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+// runEncrypt encrypts the history and the files holding sessions with a
+// new passphrase, or with -off stores them in the clear again. The
+// history.txt report would give the history away, so it is removed while
+// encryption is on and written again when it is turned off.
+func runEncrypt(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	off := fs.Bool("off", false, "decrypt the data files and store them in the clear again")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if storageKind != storageJSON {
+		return fmt.Errorf("only the %s storage can be encrypted", storageJSON)
+	}
+	if lockedElsewhere() {
+		return errors.New("another time-tracker has the history open; quit it first")
+	}
+	if *off && dataKey == nil {
+		return errors.New("the history is not encrypted")
+	}
+
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+	files, err := readDataFiles()
+	if err != nil {
+		return err
+	}
+	entries, err := loadAudit(auditFile)
+	if err != nil {
+		return err
+	}
+
+	var key *sealKey
+	if !*off {
+		passphrase, err := newPassphrase()
+		if err != nil {
+			return err
+		}
+		salt := make([]byte, saltSize)
+		rand.Read(salt)
+		if key, err = deriveKey(passphrase, salt); err != nil {
+			return err
+		}
+	}
+	dataKey = key
+
+	if err := storage.Save(history); err != nil {
+		return err
+	}
+	for path, data := range files {
+		if err := writeData(path, data); err != nil {
+			return err
+		}
+	}
+	if len(entries) > 0 {
+		data, err := encodeAudit(entries)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(auditFile, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	if *off {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if err := writeReport(historyFile, history, cfg); err != nil {
+			return err
+		}
+		fmt.Printf("%s is no longer encrypted\n", dataFile)
+		return nil
+	}
+	if err := removeFile(historyFile); err != nil {
+		return err
+	}
+	fmt.Printf("Encrypted %s; keep the passphrase safe, as the history cannot be recovered without it\n", dataFile)
+	return nil
+}
+
+// readDataFiles reads the files besides the history that hold sessions or
+// secrets, decrypted, keyed by path. Missing ones are left out.
+func readDataFiles() (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for _, path := range []string{journalFile, activeFile, trashFile, gcalTokenFile, syncFile} {
+		data, err := readData(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files[path] = data
+	}
+	return files, nil
+}
+
+// removeFile removes path if it exists.
+func removeFile(path string) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	err := os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// currencyFormat is how amounts in a currency are written.
+type currencyFormat struct {
+	symbol   string
+	decimals int
+}
+
+// currencyFormats covers common currencies; others are written with their
+// code after the amount and two decimals.
+var currencyFormats = map[string]currencyFormat{
+	"USD": {"$", 2},
+	"EUR": {"€", 2},
+	"GBP": {"£", 2},
+	"JPY": {"¥", 0},
+	"CNY": {"¥", 2},
+	"INR": {"₹", 2},
+	"KRW": {"₩", 0},
+	"CAD": {"CA$", 2},
+	"AUD": {"A$", 2},
+	"CHF": {"CHF ", 2},
+	"BRL": {"R$", 2},
+	"MXN": {"MX$", 2},
+}
+
+// formatCurrency writes amount in the currency with ISO code, e.g.
+// "€1,234.50" or "1,234.50 SEK". Without a code it is a plain number, as
+// before currencies were configured.
+func formatCurrency(amount float64, code string) string {
+	if code == "" {
+		return formatMoney(amount)
+	}
+	f, known := currencyFormats[code]
+	if !known {
+		f.decimals = 2
+	}
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	s := fmt.Sprintf("%.*f", f.decimals, amount)
+	whole, frac, _ := strings.Cut(s, ".")
+	var grouped strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(r)
+	}
+	s = grouped.String()
+	if frac != "" {
+		s += "." + frac
+	}
+	if !known {
+		return sign + s + " " + code
+	}
+	return sign + f.symbol + s
+}
+
+// homeCurrency is the currency totals are given in: the one set as currency
+// in config.json or, failing that, the one every client bills in. It is
+// empty when no currencies are configured.
+func (c config) homeCurrency() string {
+	if c.Currency != "" {
+		return c.Currency
+	}
+	home := ""
+	for _, cl := range c.Clients {
+		if cl.Currency != "" && home != "" && cl.Currency != home {
+			return ""
+		}
+		if cl.Currency != "" {
+			home = cl.Currency
+		}
+	}
+	return home
+}
+
+// currencyFor is the currency project is billed in: its client's, or the
+// home currency.
+func (c config) currencyFor(project string) string {
+	if cur := c.Clients[c.clientOf(project)].Currency; cur != "" {
+		return cur
+	}
+	return c.homeCurrency()
+}
+
+// toHome converts amount in currency to the home currency at the fixed rate
+// in exchange_rates.
+func (c config) toHome(amount float64, currency string) float64 {
+	if currency == "" || currency == c.homeCurrency() {
+		return amount
+	}
+	// loadConfig has checked that every client currency has a rate.
+	return amount * c.ExchangeRates[currency]
+}
+
+// money formats a total in the home currency.
+func (c config) money(amount float64) string {
+	return formatCurrency(amount, c.homeCurrency())
+}
+
+// projectMoney formats an amount in the currency project is billed in.
+func (c config) projectMoney(amount float64, project string) string {
+	return formatCurrency(amount, c.currencyFor(project))
+}
+
+// checkCurrencies makes sure every client billing in a currency other than
+// the home one has a rate to convert totals with.
+func checkCurrencies(cfg config) error {
+	keys := make([]string, 0, len(cfg.Clients))
+	for key := range cfg.Clients {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	home := cfg.homeCurrency()
+	for _, key := range keys {
+		cur := cfg.Clients[key].Currency
+		if cur == "" || cur == home {
+			continue
+		}
+		if home == "" {
+			return fmt.Errorf("clients bill in different currencies; set currency to the one totals should be given in")
+		}
+		if rate := cfg.ExchangeRates[cur]; rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return fmt.Errorf("client %q bills in %s; set exchange_rates.%s to the %s one %s is worth", key, cur, cur, home, cur)
+		}
+	}
+	return nil
+}
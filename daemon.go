@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// daemonTimeout bounds a single request to the daemon, so a wedged daemon
+// cannot hang the TUI or a CLI command.
+const daemonTimeout = 5 * time.Second
+
+// daemonRequest is one request sent to the daemon over socketFile. Op is
+// "get", "save" (with Active) or "clear".
+type daemonRequest struct {
+	Op     string        `json:"op"`
+	Active *activeRecord `json:"active,omitempty"`
+}
+
+// daemonResponse carries the running session after the request, nil when
+// nothing is being tracked.
+type daemonResponse struct {
+	Active *activeRecord `json:"active,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// callDaemon sends req to the daemon and waits for its answer. ok is false if
+// no daemon is listening, in which case callers work on activeFile directly.
+func callDaemon(req daemonRequest) (resp daemonResponse, ok bool, err error) {
+	conn, err := net.DialTimeout("unix", socketFile, daemonTimeout)
+	if err != nil {
+		return resp, false, nil
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(daemonTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return resp, true, fmt.Errorf("talking to daemon: %w", err)
+	}
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return resp, true, fmt.Errorf("talking to daemon: %w", err)
+	}
+	if resp.Error != "" {
+		return resp, true, errors.New(resp.Error)
+	}
+	return resp, true, nil
+}
+
+// daemon owns the running session while `time-tracker daemon` is up. The TUI
+// and CLI commands read and change it over socketFile instead of touching
+// activeFile, which the daemon keeps up to date so a restart loses nothing.
+type daemon struct {
+	mu            sync.Mutex
+	active        *activeSession
+	remindersSent int           // trackingReminderEvery milestones already notified
+	checked       time.Duration // tracked time when webhook thresholds were last checked
+
+	// For reminders to start tracking, made by remind alone.
+	storageKind   string
+	storage       Storage // opened when first needed
+	startReminded time.Time
+}
+
+// runDaemon serves the running session on socketFile until interrupted.
+func runDaemon(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if _, ok, _ := callDaemon(daemonRequest{Op: "get"}); ok {
+		return fmt.Errorf("a daemon is already listening on %s", socketFile)
+	}
+	// Nobody answered, so any socket left behind is from a daemon that did
+	// not shut down cleanly.
+	os.Remove(socketFile)
+
+	active, err := loadActiveFile()
+	if err != nil {
+		return err
+	}
+	d := &daemon{active: active, storageKind: storageKind}
+	if active != nil {
+		d.checked = active.elapsed(time.Now())
+		d.remindersSent = int(d.checked / trackingReminderEvery)
+	}
+
+	ln, err := net.Listen("unix", socketFile)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	// Keep running when the terminal that started the daemon goes away.
+	signal.Ignore(syscall.SIGHUP)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		ln.Close()
+	}()
+	go d.remind()
+
+	fmt.Printf("Daemon listening on %s\n", socketFile)
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			fmt.Println("Daemon stopped")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go d.serve(conn)
+	}
+}
+
+// serve answers the single request sent on conn.
+func (d *daemon) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(daemonTimeout))
+
+	var req daemonRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(daemonResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	json.NewEncoder(conn).Encode(d.handle(req))
+}
+
+func (d *daemon) handle(req daemonRequest) daemonResponse {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch req.Op {
+	case "get":
+	case "save":
+		a := req.Active.session()
+		if a == nil {
+			return daemonResponse{Error: "save needs a session"}
+		}
+		if err := saveActiveFile(*a); err != nil {
+			return daemonResponse{Error: err.Error()}
+		}
+		if d.active == nil || !d.active.start.Equal(a.start) {
+			d.remindersSent = 0
+			d.checked = a.elapsed(time.Now())
+		}
+		d.active = a
+	case "clear":
+		if err := clearActiveFile(); err != nil {
+			return daemonResponse{Error: err.Error()}
+		}
+		d.active = nil
+	default:
+		return daemonResponse{Error: fmt.Sprintf("unknown request %q", req.Op)}
+	}
+
+	if d.active == nil {
+		return daemonResponse{}
+	}
+	rec := d.active.record()
+	return daemonResponse{Active: &rec}
+}
+
+// remind sends the "still tracking" notifications, threshold webhooks and
+// reminders to start tracking for as long as the daemon runs, so they arrive
+// even with no TUI open.
+func (d *daemon) remind() {
+	for range time.Tick(time.Minute) {
+		// Reload each time to pick up changes made in Settings.
+		cfg, err := loadConfig()
+		if err != nil {
+			continue
+		}
+
+		d.mu.Lock()
+		var title, body string
+		var events []webhookEvent
+		if d.active != nil {
+			elapsed := d.active.elapsed(time.Now())
+			for _, threshold := range cfg.crossedThresholds(d.checked, elapsed) {
+				events = append(events, thresholdEvent(*d.active, elapsed, threshold))
+			}
+			long := cfg.crossedLongSession(d.checked, elapsed)
+			d.checked = elapsed
+			if reached := int(elapsed / trackingReminderEvery); reached > d.remindersSent {
+				d.remindersSent = reached
+				title = "Still tracking"
+				body = fmt.Sprintf("You've been tracking %s for %s.", track.ProjectLabel(d.active.project), cfg.durationLong(elapsed))
+			}
+			// The long session warning says more than the reminder.
+			if long {
+				title = "Long session"
+				body = longSessionMessage(d.active.project, elapsed, cfg)
+			}
+		}
+		idle := d.active == nil
+		d.mu.Unlock()
+
+		if idle {
+			title, body = d.startReminder(cfg)
+		}
+
+		if title != "" && cfg.Settings[settingNotifications] {
+			sendNotification(title, body)
+		}
+		for _, ev := range events {
+			if err := cfg.sendWebhooks(ev); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+}
+
+// startReminder returns the reminder to start tracking if one is due, with
+// nothing running, looking up when the last session ended.
+func (d *daemon) startReminder(cfg config) (title, body string) {
+	now := time.Now()
+	if _, ok := cfg.untrackedSince(time.Time{}, now); !ok || cfg.startReminder() <= 0 {
+		return "", ""
+	}
+	if d.storage == nil {
+		storage, err := openStorage(d.storageKind)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return "", ""
+		}
+		d.storage = storage
+	}
+	history, err := d.storage.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return "", ""
+	}
+	end := lastEnd(history)
+	if !cfg.startReminderDue(end, d.startReminded, now) {
+		return "", ""
+	}
+	d.startReminded = now
+	since, _ := cfg.untrackedSince(end, now)
+	return "Not tracking", startReminderMessage(since)
+}
+
+// daemonRunning reports whether a daemon is listening on socketFile.
+func daemonRunning() bool {
+	_, ok, _ := callDaemon(daemonRequest{Op: "get"})
+	return ok
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"time-tracking/pkg/track"
+)
+
+// dataDirEnv names the environment variable that moves the data directory,
+// as -data-dir does.
+const dataDirEnv = "TIME_TRACKER_DATA_DIR"
+
+// defaultDataDir is $XDG_DATA_HOME/time-tracker, or
+// ~/.local/share/time-tracker when XDG_DATA_HOME is not set.
+func defaultDataDir() (string, error) {
+	if xdg := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, "time-tracker"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("finding the data directory: %w (set %s or pass -data-dir)", err, dataDirEnv)
+	}
+	return filepath.Join(home, ".local", "share", "time-tracker"), nil
+}
+
+// useDataDir keeps all files in dir, or if it is empty in $TIME_TRACKER_DATA_DIR
+// or else defaultDataDir, creating it as needed, under the given profile.
+// In read-only mode dir is used as it is.
+func useDataDir(dir, profile string) error {
+	if dir == "" {
+		dir = os.Getenv(dataDirEnv)
+	}
+	if dir == "" {
+		var err error
+		if dir, err = defaultDataDir(); err != nil {
+			return err
+		}
+	}
+	dataRoot = dir
+	if readOnlyReason == "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if !writableDir(dir) {
+			readOnlyReason = readOnlyUnwritable
+		}
+	}
+	if profile == "" {
+		profile = os.Getenv(profileEnv)
+	}
+	return useProfile(profile)
+}
+
+// migrateDataDir moves the files an earlier version kept in the directory
+// from into dir, as asked for with migrate -from-dir. It refuses when dir
+// already holds a history, and only moves files that read as this app's
+// data, so unrelated files that happen to share a name, such as another
+// program's config.json, are left alone. A file dir already has, such as a
+// config.json written since, is kept and the old one left where it is.
+func migrateDataDir(from, dir string) error {
+	hasHistory := func(in string) bool {
+		for _, name := range []string{dataFile, sqliteFile, historyFile} {
+			if _, err := os.Stat(filepath.Join(in, filepath.Base(name))); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+	if same, err := sameDir(from, dir); err != nil {
+		return err
+	} else if same {
+		return fmt.Errorf("%s is already the data directory", from)
+	}
+	if hasHistory(dir) {
+		return fmt.Errorf("%s already has a history", dir)
+	}
+
+	var moved int
+	for _, file := range []string{dataFile, sqliteFile, activeFile, configFile, historyFile, auditFile, trashFile, journalFile} {
+		name := filepath.Base(file)
+		path := filepath.Join(from, name)
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err := checkDataFile(name, path); err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", path, err)
+			continue
+		}
+		err := moveFile(path, filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrExist) {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %s already has one\n", path, dir)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("moving %s to %s: %w", path, dir, err)
+		}
+		moved++
+	}
+	if moved == 0 {
+		return fmt.Errorf("no time-tracker files to move in %s", from)
+	}
+	fmt.Printf("Moved %d files from %s to %s\n", moved, from, dir)
+	return nil
+}
+
+// checkDataFile reports why the file at path, named as one of the data
+// files, does not hold this app's data. Sealed files are taken as ours
+// unread, since the key to open them lives in the data directory.
+func checkDataFile(name, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if sealed(data) {
+		return nil
+	}
+	strict := func(v any) error {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(v); err != nil {
+			return fmt.Errorf("not a time-tracker %s: %w", name, err)
+		}
+		return nil
+	}
+	switch name {
+	case filepath.Base(dataFile):
+		var file struct {
+			Version  int            `json:"version"`
+			Sessions []track.Record `json:"sessions"`
+		}
+		if err := strict(&file); err != nil {
+			return err
+		}
+		if file.Version == 0 {
+			return fmt.Errorf("not a time-tracker %s: no version", name)
+		}
+		_, err = track.Decode(path, data)
+		return err
+	case filepath.Base(sqliteFile):
+		db, err := openSQLiteReadOnly(path)
+		if err != nil {
+			return err
+		}
+		defer db.db.Close()
+		_, err = db.Load()
+		return err
+	case filepath.Base(historyFile):
+		history, err := loadLegacyHistory(path)
+		if err == nil && len(history) == 0 {
+			err = fmt.Errorf("no sessions in %s", name)
+		}
+		return err
+	case filepath.Base(configFile):
+		return strict(&config{})
+	case filepath.Base(activeFile):
+		return strict(&activeRecord{})
+	case filepath.Base(trashFile):
+		return strict(&[]trashRecord{})
+	case filepath.Base(journalFile):
+		return strict(&[]track.Record{})
+	case filepath.Base(auditFile):
+		_, err := loadAudit(path)
+		return err
+	}
+	return fmt.Errorf("unknown data file %s", name)
+}
+
+func sameDir(a, b string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(infoA, infoB), nil
+}
+
+// moveFile renames from to to, copying it instead when they are on
+// different file systems. Either way it fails with fs.ErrExist rather than
+// replace a file already at to.
+func moveFile(from, to string) error {
+	if _, err := os.Lstat(to); err == nil {
+		return &fs.PathError{Op: "move", Path: to, Err: fs.ErrExist}
+	}
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(to)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(to)
+		return err
+	}
+	return os.Remove(from)
+}
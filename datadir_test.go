@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateDataDirKeepsExistingFiles(t *testing.T) {
+	from, dir := t.TempDir(), t.TempDir()
+	write := func(path, data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	const sessions = `{"version": 3, "sessions": []}`
+	const oldConfig, newConfig = `{"rate": 10}`, `{"rate": 20}`
+	write(filepath.Join(from, "sessions.json"), sessions)
+	write(filepath.Join(from, "config.json"), oldConfig)
+	write(filepath.Join(dir, "config.json"), newConfig)
+
+	if err := migrateDataDir(from, dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(filepath.Join(dir, "sessions.json")); got != sessions {
+		t.Errorf("moved sessions.json = %s, want %s", got, sessions)
+	}
+	if _, err := os.Stat(filepath.Join(from, "sessions.json")); !os.IsNotExist(err) {
+		t.Errorf("sessions.json left behind: %v", err)
+	}
+	if got := read(filepath.Join(dir, "config.json")); got != newConfig {
+		t.Errorf("config.json in the data directory = %s, want it kept as %s", got, newConfig)
+	}
+	if got := read(filepath.Join(from, "config.json")); got != oldConfig {
+		t.Errorf("old config.json = %s, want it left as %s", got, oldConfig)
+	}
+}
+
+func TestMoveFileRefusesToReplace(t *testing.T) {
+	dir := t.TempDir()
+	from, to := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	os.WriteFile(from, []byte("a"), 0644)
+	os.WriteFile(to, []byte("b"), 0644)
+	if err := moveFile(from, to); !os.IsExist(err) {
+		t.Errorf("moveFile onto an existing file = %v, want it to exist already", err)
+	}
+	if data, _ := os.ReadFile(to); string(data) != "b" {
+		t.Errorf("destination = %q, want it unchanged", data)
+	}
+}
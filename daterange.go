@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/report"
+	"time-tracking/pkg/track"
+)
+
+// rangePresets are the named date ranges, in the order the history view
+// cycles through them. The empty name means no range.
+var rangePresets = []string{"", "today", "week", "month", "last-month"}
+
+// dateRange limits sessions to a span of days; see report.Range.
+type dateRange = report.Range
+
+// parseRange reads a range spec relative to now; see report.ParseRange.
+func parseRange(spec string, now time.Time) (dateRange, error) {
+	return report.ParseRange(spec, now)
+}
+
+// nextRangePreset returns the preset after spec in rangePresets, starting
+// over from no range after the last one or after a custom range.
+func nextRangePreset(spec string) string {
+	for i, preset := range rangePresets {
+		if preset == spec && i+1 < len(rangePresets) {
+			return rangePresets[i+1]
+		}
+	}
+	return rangePresets[0]
+}
+
+// startRangeEdit opens the inline editor for a custom history date range.
+func (m model) startRangeEdit() (tea.Model, tea.Cmd) {
+	m.editing = editRange
+	m.editInput.Placeholder = "2026-01-01..2026-01-31"
+	m.editInput.SetValue(m.rangeFilter)
+	m.editInput.CursorEnd()
+	return m, m.editInput.Focus()
+}
+
+// applyRange sets the history date range from spec, reporting an invalid
+// spec in the status line.
+func (m *model) applyRange(spec string) {
+	spec = strings.TrimSpace(spec)
+	if _, err := parseRange(spec, time.Now()); err != nil {
+		m.status = err.Error()
+		return
+	}
+	if spec == "all" {
+		spec = ""
+	}
+	m.rangeFilter = spec
+	m.cursor = 0
+}
+
+// historyRange is the date range currently applied to the history and
+// summary views. The spec was validated when it was set, so errors cannot
+// occur here.
+func (m model) historyRange() dateRange {
+	r, _ := parseRange(m.rangeFilter, time.Now())
+	return r
+}
+
+// filterLabel describes the active history filters, or is empty if there are
+// none.
+func (m model) filterLabel() string {
+	var filters []string
+	if m.rangeFilter != "" {
+		filters = append(filters, m.historyRange().Label)
+	}
+	if m.tagFilter != "" {
+		filters = append(filters, "#"+m.tagFilter)
+	}
+	return strings.Join(filters, " • ")
+}
+
+// visible reports whether sess passes the history view's tag and date
+// filters.
+func (m model) visible(sess session, r dateRange) bool {
+	if m.tagFilter != "" && !track.HasTag(sess.Tags, m.tagFilter) {
+		return false
+	}
+	return r.Contains(sess.Start)
+}
+
+// filteredHistory returns the sessions shown in the history view under the
+// current tag and date filters, in stored order.
+func (m model) filteredHistory() []session {
+	r := m.historyRange()
+	var sessions []session
+	for _, sess := range m.history {
+		if m.visible(sess, r) {
+			sessions = append(sessions, sess)
+		}
+	}
+	return sessions
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// importTolerance is how far apart the starts and the ends of two sessions
+// of one project may lie for import to take them as the same work, e.g. the
+// same session rounded to the minute by another tracker.
+const importTolerance = time.Minute
+
+// duplicate is an imported session that history already has, and the stored
+// session it matched.
+type duplicate struct {
+	imported, stored session
+}
+
+// importResult is what merging imported sessions into the history did, or
+// would do on a dry run.
+type importResult struct {
+	added      []session
+	skipped    []duplicate
+	combined   []duplicate
+	duplicates int
+	invalid    int // sessions left out by Check
+}
+
+// isDuplicate reports whether a and b are the same session, perhaps edited
+// since one was exported, or the same project's work starting and ending
+// within tolerance of each other.
+func isDuplicate(a, b session, tolerance time.Duration) bool {
+	return a.ID == b.ID ||
+		a.Project == b.Project && a.Start.Sub(b.Start).Abs() <= tolerance && a.End.Sub(b.End).Abs() <= tolerance
+}
+
+// mergeSessions adds the sessions in imported that history does not already
+// have, keeping the result in start order. A duplicate is skipped, or with
+// combine merged into the stored session so that tags, notes and time only
+// the imported one has are kept.
+func mergeSessions(history, imported []session, tolerance time.Duration, combine bool) ([]session, importResult) {
+	merged := slices.Clone(history)
+	var result importResult
+	for _, sess := range imported {
+		i := slices.IndexFunc(merged, func(s session) bool { return isDuplicate(s, sess, tolerance) })
+		if i < 0 {
+			merged = append(merged, sess)
+			result.added = append(result.added, sess)
+			continue
+		}
+		result.duplicates++
+		if !combine {
+			result.skipped = append(result.skipped, duplicate{sess, merged[i]})
+			continue
+		}
+		if both := combineSessions(merged[i], sess); !identical(both, merged[i]) {
+			result.combined = append(result.combined, duplicate{sess, merged[i]})
+			merged[i] = both
+		} else {
+			result.skipped = append(result.skipped, duplicate{sess, merged[i]})
+		}
+	}
+	slices.SortStableFunc(merged, func(a, b session) int { return a.Start.Compare(b.Start) })
+	return merged, result
+}
+
+// audit returns audit log entries for the stored sessions that duplicates
+// were merged into.
+func (r importResult) audit() []auditEntry {
+	var entries []auditEntry
+	for _, d := range r.combined {
+		entries = append(entries, auditEntry{Time: time.Now().Round(0), Action: auditMerge,
+			Before: []track.Record{track.ToRecord(d.stored)}, After: []track.Record{track.ToRecord(combineSessions(d.stored, d.imported))}})
+	}
+	return entries
+}
+
+// preview lists what the import added, skipped and combined, one session a
+// line, for a dry run.
+func (r importResult) preview(cfg config) {
+	for _, sess := range r.added {
+		fmt.Printf("+ %s\n", cfg.describeRecord(track.ToRecord(sess)))
+	}
+	for _, d := range r.combined {
+		fmt.Printf("~ %s, merged into %s\n", cfg.describeRecord(track.ToRecord(d.imported)), cfg.describeRecord(track.ToRecord(d.stored)))
+	}
+	for _, d := range r.skipped {
+		fmt.Printf("= %s, already stored as %s\n", cfg.describeRecord(track.ToRecord(d.imported)), cfg.describeRecord(track.ToRecord(d.stored)))
+	}
+}
+
+// summary describes the duplicates and invalid sessions found, e.g.
+// " (3 duplicates skipped)", or is empty if there were none.
+func (r importResult) summary() string {
+	var parts []string
+	switch {
+	case r.duplicates == 0:
+	case len(r.combined) == 0:
+		parts = append(parts, fmt.Sprintf("%d duplicates skipped", r.duplicates))
+	default:
+		parts = append(parts, fmt.Sprintf("%d duplicates, %d merged", r.duplicates, len(r.combined)))
+	}
+	if r.invalid > 0 {
+		parts = append(parts, fmt.Sprintf("%d invalid skipped", r.invalid))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
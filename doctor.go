@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// diagnosis is what doctor found wrong with the stored history, and the
+// history as -fix would leave it.
+type diagnosis struct {
+	problems []string
+	fixable  int
+	repaired []session
+	changes  []auditEntry
+}
+
+func (d *diagnosis) report(problem string, fixable bool) {
+	if fixable {
+		d.fixable++
+	}
+	d.problems = append(d.problems, problem)
+}
+
+func (d *diagnosis) change(before, after []session) {
+	entry := auditEntry{Time: time.Now().Round(0), Action: auditRepair}
+	for _, sess := range before {
+		entry.Before = append(entry.Before, track.ToRecord(sess))
+	}
+	for _, sess := range after {
+		entry.After = append(entry.After, track.ToRecord(sess))
+	}
+	d.changes = append(d.changes, entry)
+}
+
+// diagnose checks history for sessions that end before they start or have
+// pauses outside them, duplicates, shared IDs and overlaps, and works out the
+// repairs: swapping the ends, clipping the pauses, dropping the later
+// duplicate, giving the later of two sessions sharing an ID a new one and
+// trimming the later of two overlapping sessions. A session inside another
+// is only reported, as trimming it would lose it.
+func diagnose(history []session, cfg config) diagnosis {
+	var d diagnosis
+	repaired := slices.Clone(history)
+
+	for i, sess := range repaired {
+		fixed := sess
+		if sess.End.Before(sess.Start) {
+			d.report(cfg.describeRecord(track.ToRecord(sess))+": ends before it starts; its start and end will be swapped", true)
+			fixed.Start, fixed.End = sess.End, sess.Start
+		}
+		if badPauses(fixed) {
+			d.report(cfg.describeRecord(track.ToRecord(sess))+": has pauses outside it; they will be clipped to it", true)
+			fixed.Pauses = slices.DeleteFunc(slices.Clone(fixed.Pauses), func(p pause) bool {
+				return !p.End.IsZero() && p.End.Before(p.Start)
+			})
+		}
+		if !identical(fixed, sess) || badPauses(fixed) {
+			fixed.SetBounds(fixed.Start, fixed.End)
+			repaired[i] = fixed
+			d.change([]session{sess}, []session{fixed})
+		}
+	}
+
+	for i := 0; i < len(repaired); i++ {
+		for j := len(repaired) - 1; j > i; j-- {
+			if sameSession(repaired[i], repaired[j]) {
+				d.report(cfg.describeRecord(track.ToRecord(repaired[j]))+": stored twice; the second copy will be removed", true)
+				d.change([]session{repaired[j]}, nil)
+				repaired = slices.Delete(repaired, j, j+1)
+			}
+		}
+	}
+
+	ids := make(map[string]session)
+	for i, sess := range repaired {
+		other, ok := ids[sess.ID]
+		if !ok {
+			ids[sess.ID] = sess
+			continue
+		}
+		d.report(fmt.Sprintf("%s has the same ID as %s; it will get a new one", cfg.describeRecord(track.ToRecord(sess)), cfg.describeRecord(track.ToRecord(other))), true)
+		repaired[i].ID = track.NewID()
+		d.change([]session{sess}, []session{repaired[i]})
+	}
+
+	order := make([]int, len(repaired))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return repaired[a].Start.Compare(repaired[b].Start) })
+	for n, i := range order {
+		for _, j := range order[n+1:] {
+			if !overlaps(repaired[i], repaired[j]) {
+				continue
+			}
+			desc := fmt.Sprintf("%s overlaps %s", cfg.describeRecord(track.ToRecord(repaired[j])), cfg.describeRecord(track.ToRecord(repaired[i])))
+			if !repaired[j].End.After(repaired[i].End) {
+				d.report(desc+" and lies inside it; resolve it with o in the history view", false)
+				continue
+			}
+			d.report(desc+"; it will start when the other ends", true)
+			before := repaired[j]
+			repaired[j].SetBounds(repaired[i].End, repaired[j].End)
+			d.change([]session{before}, []session{repaired[j]})
+		}
+	}
+
+	d.repaired = repaired
+	return d
+}
+
+// badPauses reports whether sess has pauses that end before they start or
+// lie partly outside it.
+func badPauses(sess session) bool {
+	for _, p := range sess.Pauses {
+		if p.Start.Before(sess.Start) || p.End.IsZero() || p.End.Before(p.Start) || p.End.After(sess.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// runDoctor checks the stored history, config.json and the running session
+// for problems and, with -fix, repairs those it can.
+func runDoctor(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	fix := fs.Bool("fix", false, "repair the problems found where possible")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var broken int
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		broken++
+	}
+	if _, err := loadActiveFile(); err != nil {
+		fmt.Printf("✗ %v\n", err)
+		broken++
+	}
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return errors.New("the history cannot be read, so it was not checked; restore it from a backup or fix it by hand")
+	}
+
+	d := diagnose(history, cfg)
+	fmt.Printf("Checked %d sessions\n", len(history))
+	for _, problem := range d.problems {
+		fmt.Printf("✗ %s\n", problem)
+	}
+	switch {
+	case len(d.problems) == 0 && broken == 0:
+		fmt.Println("No problems found")
+		return nil
+	case d.fixable == 0:
+		return nil
+	case !*fix:
+		fmt.Printf("\n%d of %d problems can be repaired with time-tracker doctor -fix\n", d.fixable, len(d.problems)+broken)
+		return nil
+	}
+
+	if err := storage.Save(d.repaired); err != nil {
+		return err
+	}
+	if err := appendAudit(auditFile, d.changes); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if err := writeReport(historyFile, d.repaired, cfg); err != nil {
+		return err
+	}
+	fmt.Printf("\nRepaired %d problems\n", d.fixable)
+	return nil
+}
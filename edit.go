@@ -0,0 +1,216 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// editField identifies which session field the inline editor is changing.
+type editField int
+
+const (
+	editNone editField = iota
+	editNote
+	editTags
+	editRange
+	editGoal
+	editDay
+	editSplit
+	editSplitProject
+	editMarkedTags
+	editProject
+	editCountdown
+	editIssue
+	editInterruption
+	editAddNote
+)
+
+func (f editField) label() string {
+	switch f {
+	case editNote:
+		return "Note:"
+	case editTags:
+		return "Tags (space separated):"
+	case editRange:
+		return "Date range (YYYY-MM-DD..YYYY-MM-DD, today, week, month, last-month):"
+	case editGoal:
+		return "Daily goal (e.g. 6h or 7h30m, empty for none):"
+	case editDay:
+		return "Day (YYYY-MM-DD):"
+	case editSplit:
+		return "Split at (HH:MM):"
+	case editSplitProject:
+		return "Project and #tags of the second half:"
+	case editMarkedTags:
+		return "Tags of all marked sessions (space separated):"
+	case editProject:
+		return "Project:"
+	case editCountdown:
+		return "Count down from (e.g. 45m or 1h30m, empty for none):"
+	case editIssue:
+		return "Issue or pull request (URL, #42 or owner/repo#42, empty for none):"
+	case editInterruption:
+		return "Interrupted by (optional):"
+	case editAddNote:
+		return "Add to note:"
+	default:
+		return ""
+	}
+}
+
+// startEdit opens the inline editor for field on the session at target, which
+// is an index into history or -1 for the running session.
+func (m model) startEdit(field editField, target int) (tea.Model, tea.Cmd) {
+	var note, issue string
+	var tags []string
+	if target < 0 {
+		note, tags, issue = m.active.note, m.active.tags, m.active.issue
+	} else {
+		note, tags, issue = m.history[target].Note, m.history[target].Tags, m.history[target].Issue
+	}
+
+	m.editing = field
+	m.editTarget = target
+	switch field {
+	case editNote:
+		m.editInput.Placeholder = "What are you working on?"
+		m.editInput.SetValue(note)
+	case editTags:
+		m.editInput.Placeholder = "#billable #meeting"
+		m.editInput.SetValue(track.FormatTags(tags))
+	case editIssue:
+		m.editInput.Placeholder = "#42"
+		if issue == "" {
+			// Offer the issue the current branch is named after.
+			issue = branchIssue()
+		}
+		m.editInput.SetValue(issue)
+	case editInterruption:
+		m.interruptedAt = time.Now()
+		m.editInput.Placeholder = "phone call"
+		m.editInput.SetValue("")
+	case editAddNote:
+		m.editInput.Placeholder = "What did you just do?"
+		m.editInput.SetValue("")
+	}
+	m.editInput.CursorEnd()
+	return m, m.editInput.Focus()
+}
+
+func (m model) updateEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m.quit()
+	case "esc":
+		m.editing = editNone
+		m.editInput.Blur()
+		return m, nil
+	case "enter":
+		if m.editing == editSplit {
+			return m.applySplitTime(m.editInput.Value())
+		}
+		m.applyEdit(m.editInput.Value())
+		m.editing = editNone
+		m.editInput.Blur()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.editInput, cmd = m.editInput.Update(msg)
+	return m, cmd
+}
+
+// applyEdit stores value in the field being edited and persists the change.
+func (m *model) applyEdit(value string) {
+	switch m.editing {
+	case editRange:
+		m.applyRange(value)
+		return
+	case editGoal:
+		m.applyGoal(value)
+		return
+	case editDay:
+		m.applyDay(value)
+		return
+	case editSplitProject:
+		m.applySplit(value)
+		return
+	case editMarkedTags:
+		m.applyMarkedTags(value)
+		return
+	case editProject:
+		m.applyProject(value)
+		return
+	case editCountdown:
+		m.applyCountdown(value)
+		return
+	case editInterruption:
+		m.logInterruption(value)
+		return
+	}
+	var issue string
+	if m.editing == editIssue {
+		var err error
+		if issue, err = parseIssue(value); err != nil {
+			m.status = err.Error()
+			return
+		}
+	}
+	if m.editTarget < 0 {
+		if m.active == nil {
+			return
+		}
+		switch m.editing {
+		case editNote:
+			m.active.note = strings.TrimSpace(value)
+		case editAddNote:
+			m.active.note = appendNote(m.active.note, value, time.Now())
+		case editTags:
+			m.active.tags = track.ParseTags(value)
+		case editIssue:
+			m.active.issue = issue
+		}
+		saveActive(*m.active)
+		return
+	}
+
+	if m.editTarget >= len(m.history) {
+		return
+	}
+	before := m.history[m.editTarget]
+	switch m.editing {
+	case editNote:
+		m.history[m.editTarget].Note = strings.TrimSpace(value)
+	case editTags:
+		m.history[m.editTarget].Tags = track.ParseTags(value)
+	case editIssue:
+		m.history[m.editTarget].Issue = issue
+	}
+	m.auditEdited(m.editTarget, before)
+	m.changed()
+}
+
+// appendNote adds entry to note, stamped with the time it was written at, as
+// another part of it.
+func appendNote(note, entry string, at time.Time) string {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return note
+	}
+	entry = at.Format("15:04") + " " + entry
+	if note == "" {
+		return entry
+	}
+	return note + "; " + entry
+}
+
+func (m model) viewEdit() string {
+	s := normalStyle.Render(m.editing.label()) + "\n"
+	s += m.editInput.View() + "\n\n"
+	s += helpStyle.Render("enter: save • esc: cancel")
+	return s
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// checkEstimates makes sure every estimate in config.json parses.
+func checkEstimates(estimates map[string]string) error {
+	for name, spec := range estimates {
+		if _, err := parseEstimate(spec); err != nil {
+			return fmt.Errorf("estimate for %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func parseEstimate(spec string) (time.Duration, error) {
+	d, err := time.ParseDuration(spec)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid estimate %q (want e.g. 20h or 90m)", spec)
+	}
+	return d, nil
+}
+
+// estimateRow compares the time estimated for a project, or for a task
+// tracked under a tag, with the time actually tracked.
+type estimateRow struct {
+	label    string
+	estimate time.Duration
+	actual   time.Duration
+}
+
+func (r estimateRow) over() bool {
+	return r.actual > r.estimate
+}
+
+// estimateRows returns a row for every estimate in cfg, in order of name.
+// Estimates are keyed by project, or by "#tag" for a task.
+func estimateRows(history []session, cfg config) []estimateRow {
+	names := make([]string, 0, len(cfg.Estimates))
+	for name := range cfg.Estimates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var rows []estimateRow
+	for _, name := range names {
+		// loadConfig has checked the estimates.
+		estimate, _ := parseEstimate(cfg.Estimates[name])
+		row := estimateRow{label: name, estimate: estimate}
+		tag, isTag := strings.CutPrefix(name, "#")
+		for _, sess := range history {
+			if isTag && track.HasTag(sess.Tags, tag) || !isTag && sess.Project == name {
+				row.actual += sess.Duration
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// renderEstimates formats rows as a table of estimated against tracked time,
+// marking overruns with "!".
+func renderEstimates(rows []estimateRow, cfg config) string {
+	if len(rows) == 0 {
+		return "No estimates yet; add one with e.g. time-tracker estimate website 20h\n"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("  %-30s %12s %12s %7s\n", "Project or #task", "Estimate", "Tracked", "Used"))
+	overruns := 0
+	for _, row := range rows {
+		mark, note := " ", ""
+		if row.over() {
+			mark = "!"
+			note = "  over by " + cfg.durationLong(row.actual-row.estimate)
+			overruns++
+		}
+		sb.WriteString(fmt.Sprintf("%s %-30s %12s %12s %6.0f%%%s\n", mark, truncate(row.label, 30),
+			cfg.durationLong(row.estimate), cfg.durationLong(row.actual),
+			100*float64(row.actual)/float64(row.estimate), note))
+	}
+	if overruns > 0 {
+		sb.WriteString(fmt.Sprintf("\n%d of %d estimates overrun\n", overruns, len(rows)))
+	}
+	return sb.String()
+}
+
+// runEstimate sets or removes the estimate for a project or "#tag", or with
+// no arguments compares every estimate with the time tracked.
+func runEstimate(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("estimate", flag.ExitOnError)
+	unset := fs.Bool("unset", false, "remove the estimate for the project or #tag given")
+	rangeSpec := fs.String("range", "", "only count time tracked today, week, month, last-month or FROM..TO")
+	fs.Parse(args)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *unset:
+		if fs.NArg() != 1 {
+			return errors.New("-unset needs a project or #tag")
+		}
+		delete(cfg.Estimates, fs.Arg(0))
+		return saveConfig(cfg)
+	case fs.NArg() == 2:
+		if _, err := parseEstimate(fs.Arg(1)); err != nil {
+			return err
+		}
+		if cfg.Estimates == nil {
+			cfg.Estimates = make(map[string]string)
+		}
+		cfg.Estimates[fs.Arg(0)] = fs.Arg(1)
+		return saveConfig(cfg)
+	case fs.NArg() != 0:
+		return errors.New("usage: estimate [-range RANGE] | estimate NAME DURATION | estimate -unset NAME")
+	}
+
+	dates, err := parseRange(*rangeSpec, time.Now())
+	if err != nil {
+		return err
+	}
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+	fmt.Print(renderEstimates(estimateRows(dates.Filter(history), cfg), cfg))
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"time-tracking/pkg/track"
+)
+
+const (
+	csvExportFile = "sessions.csv"
+	csvTimeLayout = "2006-01-02 15:04:05"
+)
+
+var csvHeader = []string{"start", "end", "duration", "project", "tags", "notes", "billable", "rate", "amount", "currency", "issue"}
+
+// exportSummary describes exported sessions for the message after an
+// export, e.g. "12 sessions (10h 5m billable, 2h 0m not billable)".
+func exportSummary(sessions []session, cfg config) string {
+	billable, nonBillable := cfg.billableSplit(sessions)
+	return fmt.Sprintf("%d sessions (%s billable, %s not billable)", len(sessions),
+		cfg.durationLong(billable), cfg.durationLong(nonBillable))
+}
+
+// writeCSV writes history to w as CSV, one row per session, with earnings at
+// the rates in cfg.
+func writeCSV(w io.Writer, history []session, cfg config) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, sess := range history {
+		row := []string{
+			sess.Start.Format(csvTimeLayout),
+			sess.End.Format(csvTimeLayout),
+			track.FormatClock(sess.Duration),
+			sess.Project,
+			strings.Join(sess.Tags, " "),
+			sess.Note,
+			strconv.FormatBool(sess.Billable),
+			formatMoney(cfg.rateFor(sess.Project)),
+			formatMoney(cfg.earnings(sess)),
+			cfg.currencyFor(sess.Project),
+			sess.Issue,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportCSV writes history as CSV to path.
+func exportCSV(path string, history []session, cfg config) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeCSV(f, history, cfg); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// sameSession reports whether a and b record the same stretch of work, even
+// if they were stored under different IDs.
+func sameSession(a, b session) bool {
+	return a.Start.Equal(b.Start) && a.End.Equal(b.End) && a.Project == b.Project
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"slices"
+	"sort"
+)
+
+// quickPicks is how many projects on the start prompt get a number key.
+const quickPicks = 9
+
+// pickProjects returns the projects offered on the start prompt: pinned
+// favorites in the order they were pinned, then the other projects in
+// history, most recently used first.
+func (m model) pickProjects() []string {
+	projects := slices.Clone(m.config.Favorites)
+	lastUsed := make(map[string]int)
+	for i, sess := range m.history {
+		if sess.Project == "" || slices.Contains(m.config.Favorites, sess.Project) {
+			continue
+		}
+		if j, ok := lastUsed[sess.Project]; !ok || sess.Start.After(m.history[j].Start) {
+			lastUsed[sess.Project] = i
+		}
+	}
+	recent := make([]string, 0, len(lastUsed))
+	for project := range lastUsed {
+		recent = append(recent, project)
+	}
+	sort.Slice(recent, func(a, b int) bool {
+		return m.history[lastUsed[recent[a]]].Start.After(m.history[lastUsed[recent[b]]].Start)
+	})
+	return append(projects, recent...)
+}
+
+// toggleFavorite pins project to the top of the start prompt, or unpins it,
+// and saves the choice to config.json.
+func (m *model) toggleFavorite(project string) {
+	if i := slices.Index(m.config.Favorites, project); i >= 0 {
+		m.config.Favorites = slices.Delete(m.config.Favorites, i, i+1)
+	} else {
+		m.config.Favorites = append(m.config.Favorites, project)
+	}
+	saveConfig(m.config)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"time-tracking/pkg/report"
+	"time-tracking/pkg/track"
+)
+
+// weeklyTarget is the configured weekly target, or 0 if there is none.
+func (c config) weeklyTarget() time.Duration {
+	// loadConfig has checked that WeeklyTarget parses.
+	d, _ := time.ParseDuration(c.WeeklyTarget)
+	return d
+}
+
+// flexBalance is the over/under time against the weekly target.
+type flexBalance struct {
+	// weeks holds each week's difference from the target and the running
+	// balance after it, keyed like report.ByWeek.
+	weeks map[string]flexWeek
+	// total is the balance carried into the current week.
+	total time.Duration
+	// thisWeek is the time tracked so far in the current week.
+	thisWeek time.Duration
+}
+
+type flexWeek struct {
+	diff, balance time.Duration
+}
+
+// computeFlexBalance walks every ISO week from the first one with a session,
+// or from since if set, up to now, carrying the difference between tracked
+// time and target from week to week. The current week is not counted until it
+// is over.
+func computeFlexBalance(history []session, target time.Duration, since, now time.Time) flexBalance {
+	b := flexBalance{weeks: make(map[string]flexWeek)}
+	tracked := make(map[string]time.Duration)
+	first := since
+	for _, sess := range history {
+		if !since.IsZero() && sess.Start.Before(since) {
+			continue
+		}
+		key, _ := report.ByWeek(sess)
+		tracked[key] += sess.Duration
+		if first.IsZero() || sess.Start.Before(first) {
+			first = sess.Start
+		}
+	}
+	if first.IsZero() {
+		return b
+	}
+
+	current := report.StartOfISOWeek(now)
+	for week := report.StartOfISOWeek(first); week.Before(current); week = week.AddDate(0, 0, 7) {
+		key, _ := report.ByWeek(session{Start: week})
+		diff := tracked[key] - target
+		b.total += diff
+		b.weeks[key] = flexWeek{diff: diff, balance: b.total}
+	}
+	key, _ := report.ByWeek(session{Start: current})
+	b.thisWeek = tracked[key]
+	return b
+}
+
+// flexBalance computes the balance for the full history, ignoring the history
+// view's filters, with the running session counted in this week.
+func (m model) flexBalance() flexBalance {
+	var since time.Time
+	if m.config.BalanceSince != "" {
+		// loadConfig has checked the date.
+		since, _ = time.ParseInLocation(report.DateLayout, m.config.BalanceSince, time.Local)
+	}
+	now := time.Now()
+	b := computeFlexBalance(m.history, m.config.weeklyTarget(), since, now)
+	if m.active != nil && !m.active.start.Before(report.StartOfISOWeek(now)) {
+		b.thisWeek += m.elapsed
+	}
+	return b
+}
+
+// formatBalance renders d with an explicit sign, e.g. "+03:20" or "-01:15".
+func formatBalance(d time.Duration) string {
+	if d < 0 {
+		return "-" + track.FormatMinutes(-d)
+	}
+	return "+" + track.FormatMinutes(d)
+}
+
+// viewFlexBalance renders the balance line for the summary view.
+func (m model) viewFlexBalance(b flexBalance) string {
+	target := m.config.weeklyTarget()
+	line := fmt.Sprintf("Flex balance: %s (target %s/week)", formatBalance(b.total), track.FormatMinutes(target))
+	if left := target - b.thisWeek; left > 0 {
+		line += fmt.Sprintf(" • %s to go this week", track.FormatMinutes(left))
+	} else {
+		line += fmt.Sprintf(" • %s over this week", track.FormatMinutes(-left))
+	}
+	return projectHeaderStyle.Render(line) + "\n\n"
+}
+
+func checkWeeklyTarget(target, since string) error {
+	if target != "" {
+		if d, err := time.ParseDuration(target); err != nil || d <= 0 || d > 7*24*time.Hour {
+			return fmt.Errorf("invalid weekly target %q (want e.g. 40h)", target)
+		}
+	}
+	if since != "" {
+		if _, err := time.Parse(report.DateLayout, since); err != nil {
+			return fmt.Errorf("invalid balance_since %q (want YYYY-MM-DD)", since)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"time-tracking/pkg/track"
+)
+
+// projectChoice is a row of the project picker: a known project, or the
+// typed name as a new one.
+type projectChoice struct {
+	project string
+	create  bool
+}
+
+// fuzzyScore reports whether the letters of query appear in s in order,
+// ignoring case, and how well they match. Runs of consecutive letters and
+// letters starting s or a word in it score higher, and s equal to query
+// highest of all.
+func fuzzyScore(query, s string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0, true
+	}
+	if strings.EqualFold(query, s) {
+		return 1000, true
+	}
+	score, qi := 0, 0
+	prevMatched := false
+	prev := ' '
+	for _, r := range strings.ToLower(s) {
+		if qi < len(q) && r == q[qi] {
+			score++
+			if prevMatched {
+				score += 3
+			}
+			if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+				score += 5
+			}
+			qi++
+			prevMatched = true
+		} else {
+			prevMatched = false
+		}
+		prev = r
+	}
+	return score, qi == len(q)
+}
+
+// projectChoices returns the rows of the project picker for what has been
+// typed so far: the favorite and recent projects matching the name part of
+// it, best match first, followed by an entry creating the typed project when
+// no known one has exactly that name.
+func (m model) projectChoices() []projectChoice {
+	query, _ := track.SplitProjectTags(m.projectInput.Value())
+	projects := m.pickProjects()
+	scores := make(map[string]int)
+	var matches []string
+	for _, project := range projects {
+		if score, ok := fuzzyScore(query, project); ok {
+			scores[project] = score
+			matches = append(matches, project)
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool { return scores[matches[a]] > scores[matches[b]] })
+
+	choices := make([]projectChoice, 0, len(matches)+1)
+	exact := false
+	for _, project := range matches {
+		choices = append(choices, projectChoice{project: project})
+		exact = exact || project == query
+	}
+	if query != "" && !exact {
+		choices = append(choices, projectChoice{project: query, create: true})
+	}
+	return choices
+}
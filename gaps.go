@@ -0,0 +1,215 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// minGap is the shortest untracked stretch reported as a gap.
+const minGap = 15 * time.Minute
+
+// gapRanges are the periods the gaps view cycles through.
+var gapRanges = []string{"week", "today", "month", "last-month"}
+
+// gap is untracked time within working hours.
+type gap struct {
+	start, end time.Time
+}
+
+// findGaps returns the stretches of at least minGap within the working
+// hours of sched in r, up to now, that no session covers.
+func findGaps(history []session, r dateRange, sched workSchedule, now time.Time) []gap {
+	sessions := slices.Clone(history)
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Start.Before(sessions[j].Start) })
+
+	first := r.From
+	if first.IsZero() && len(sessions) > 0 {
+		first = sessions[0].Start
+	}
+	last := now
+	if !r.To.IsZero() && r.To.Before(now) {
+		last = r.To
+	}
+
+	var gaps []gap
+	y, mo, d := first.Date()
+	for day := time.Date(y, mo, d, 0, 0, 0, 0, now.Location()); day.Before(last); day = day.AddDate(0, 0, 1) {
+		covered, end, ok := sched.hours(day)
+		if !ok {
+			continue
+		}
+		end = earlier(end, last)
+		for _, sess := range sessions {
+			if !sess.Start.Before(end) {
+				break
+			}
+			if sess.Start.Sub(covered) >= minGap {
+				gaps = append(gaps, gap{covered, sess.Start})
+			}
+			covered = later(covered, sess.End)
+		}
+		if end.Sub(covered) >= minGap {
+			gaps = append(gaps, gap{covered, end})
+		}
+	}
+	return gaps
+}
+
+// gaps lists the gaps in the period chosen in the gaps view, treating the
+// running session as tracked up to now.
+func (m model) gaps() []gap {
+	now := time.Now()
+	r, _ := parseRange(gapRanges[m.gapRange], now)
+	history := m.history
+	if m.active != nil {
+		history = append(slices.Clone(history), session{Start: m.active.start, End: now})
+	}
+	return findGaps(history, r, m.config.schedule(), now)
+}
+
+func (m model) updateGaps(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	gaps := m.gaps()
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Back):
+		m.currentView = menuView
+		m.cursor = 0
+	case key.Matches(msg, m.keys.Up):
+		if m.gapCursor > 0 {
+			m.gapCursor--
+		}
+	case key.Matches(msg, m.keys.Down):
+		if m.gapCursor < len(gaps)-1 {
+			m.gapCursor++
+		}
+	case key.Matches(msg, m.keys.DateRange):
+		m.gapRange = (m.gapRange + 1) % len(gapRanges)
+		m.gapCursor = 0
+	case key.Matches(msg, m.keys.Select):
+		if m.gapCursor < len(gaps) && !m.blockedReadOnly() {
+			g := gaps[m.gapCursor]
+			m.filling = &g
+			m.currentView = projectView
+			m.projectCursor = -1
+			m.projectInput.SetValue("")
+			return m, m.projectInput.Focus()
+		}
+	}
+	return m, nil
+}
+
+// fillGap asks to confirm adding a session for input ("project #tags") over
+// the gap being filled.
+func (m model) fillGap(input string) (tea.Model, tea.Cmd) {
+	g := *m.filling
+	m.filling = nil
+	m.currentView = gapsView
+	if spec, r, ok := m.config.closedPeriod(g.start); ok {
+		return m.unlockPrompt(spec, r)
+	}
+	project, tags := track.SplitProjectTags(input)
+	sess := session{
+		ID:       track.NewID(),
+		Project:  project,
+		Tags:     tags,
+		Start:    g.start,
+		End:      g.end,
+		Duration: g.end.Sub(g.start),
+		Billable: m.config.billableByDefault(project),
+	}
+	return m.confirm("➕ Fill gap?",
+		fmt.Sprintf("Add %s, %s – %s (%s).", track.ProjectLabel(project), g.start.Format("Mon Jan 02 15:04"),
+			g.end.Format("15:04"), m.config.duration(sess.Duration)),
+		confirmOption{"y", "Add", func(m model) (tea.Model, tea.Cmd) {
+			m.flushDelete()
+			at, _ := slices.BinarySearchFunc(m.history, sess.Start, func(s session, t time.Time) int { return s.Start.Compare(t) })
+			m.history = slices.Insert(m.history, at, sess)
+			m.changed()
+			m.gapCursor = max(0, min(m.gapCursor, len(m.gaps())-1))
+			return m, nil
+		}},
+		confirmOption{"n", "Cancel", cancelDialog},
+	)
+}
+
+func (m model) viewGaps() string {
+	s := titleStyle.Render("🕳  Gaps") + "\n\n"
+
+	r, _ := parseRange(gapRanges[m.gapRange], time.Now())
+	s += normalStyle.Render(fmt.Sprintf("%s • working hours %s", r.Label, m.config.schedule())) + "\n\n"
+
+	gaps := m.gaps()
+	if len(gaps) == 0 {
+		s += normalStyle.Render("No gaps — every working hour is accounted for.") + "\n"
+	}
+	var total time.Duration
+	for i, g := range gaps {
+		total += g.end.Sub(g.start)
+		line := fmt.Sprintf("%s - %s (%s)", g.start.Format("Mon Jan 02 15:04"), g.end.Format("15:04"),
+			m.config.duration(g.end.Sub(g.start)))
+		if i == m.gapCursor {
+			s += selectedStyle.Render("> "+line) + "\n"
+		} else {
+			s += historyItemStyle.Render("  "+line) + "\n"
+		}
+	}
+	if len(gaps) > 0 {
+		s += "\n" + projectHeaderStyle.Render(fmt.Sprintf("%d gaps, %s untracked", len(gaps), m.config.durationLong(total))) + "\n"
+	}
+
+	s += "\n" + helpLine(pairHelp(m.keys.Up, m.keys.Down, "navigate"), m.keys.Select, m.keys.DateRange, m.keys.Back, m.keys.Help, m.keys.Quit)
+
+	return s
+}
+
+// runGaps lists untracked time within working hours.
+func runGaps(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("gaps", flag.ExitOnError)
+	rangeSpec := fs.String("range", "week", "list gaps for today, week, month, last-month or FROM..TO")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	dates, err := parseRange(*rangeSpec, now)
+	if err != nil {
+		return err
+	}
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	active, err := loadActive()
+	if err != nil {
+		return err
+	}
+	if active != nil {
+		history = append(history, session{Start: active.start, End: now})
+	}
+
+	gaps := findGaps(history, dates, cfg.schedule(), now)
+	var total time.Duration
+	for _, g := range gaps {
+		total += g.end.Sub(g.start)
+		fmt.Printf("%s - %s  %s\n", g.start.Format("Mon Jan 02 15:04"), g.end.Format("15:04"), cfg.durationLong(g.end.Sub(g.start)))
+	}
+	fmt.Printf("%d gaps, %s untracked\n", len(gaps), cfg.durationLong(total))
+	return nil
+}
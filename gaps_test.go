@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+func TestFindGapsAcrossDST(t *testing.T) {
+	loc := berlin(t)
+	// Midnight to 06:00 every day, so the schedule spans the change.
+	sched := workSchedule{days: [7]bool{true, true, true, true, true, true, true}, to: 6 * time.Hour}
+	tests := []struct {
+		name       string
+		day        time.Time
+		start, end string // UTC
+		scheduled  time.Duration
+		gaps       []time.Duration
+	}{
+		{
+			name: "spring forward", day: time.Date(2026, 3, 29, 0, 0, 0, 0, loc),
+			start: "2026-03-29T00:00:00Z", end: "2026-03-29T02:00:00Z", // 01:00 CET–04:00 CEST
+			scheduled: 5 * time.Hour,
+			gaps:      []time.Duration{time.Hour, 2 * time.Hour},
+		},
+		{
+			name: "fall back", day: time.Date(2026, 10, 25, 0, 0, 0, 0, loc),
+			start: "2026-10-25T00:30:00Z", end: "2026-10-25T01:30:00Z", // 02:30 CEST–02:30 CET
+			scheduled: 7 * time.Hour,
+			gaps:      []time.Duration{2*time.Hour + 30*time.Minute, 3*time.Hour + 30*time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := track.ClockOn(tt.day, 24*time.Hour)
+			if got := sched.total(tt.day, next); got != tt.scheduled {
+				t.Errorf("scheduled = %s, want %s", got, tt.scheduled)
+			}
+			history := []session{{Start: localAt(tt.start), End: localAt(tt.end)}}
+			gaps := findGaps(history, dateRange{From: tt.day, To: next}, sched, next.Add(time.Hour))
+			if len(gaps) != len(tt.gaps) {
+				t.Fatalf("got %d gaps, want %d: %v", len(gaps), len(tt.gaps), gaps)
+			}
+			for i, g := range gaps {
+				if got := g.end.Sub(g.start); got != tt.gaps[i] {
+					t.Errorf("gap %d (%s–%s) = %s, want %s", i, g.start.Format("15:04 MST"), g.end.Format("15:04 MST"), got, tt.gaps[i])
+				}
+			}
+		})
+	}
+}
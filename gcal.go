@@ -0,0 +1,399 @@
+package main
+
+import (
+	"cmp"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+const (
+	googleAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	gcalAPI        = "https://www.googleapis.com/calendar/v3"
+	gcalScope      = "https://www.googleapis.com/auth/calendar.events"
+)
+
+// gcalTokenFile caches the Google OAuth token, so that logging in once is
+// enough. Like the other data files, it is encrypted with the history.
+var gcalTokenFile = "gcal-token.json"
+
+var googleClient = &http.Client{Timeout: 30 * time.Second}
+
+// gcalSessionKey is the private extended property holding the ID of the
+// session an event was pushed for.
+const gcalSessionKey = "timeTrackerSession"
+
+// gcalConfig is the "google_calendar" section of config.json.
+type gcalConfig struct {
+	// ClientID and ClientSecret identify a "Desktop app" OAuth client made
+	// in the Google Cloud console with the Calendar API enabled.
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	// Calendar is the ID of the calendar sessions are pushed to, best one
+	// made for them, e.g. "…@group.calendar.google.com".
+	Calendar string `json:"calendar,omitempty"`
+	// Meetings is the calendar meetings are pulled from; empty means the
+	// primary one.
+	Meetings string `json:"meetings,omitempty"`
+	// MeetingProject is the project pulled meetings are tracked under.
+	MeetingProject string `json:"meeting_project,omitempty"`
+}
+
+// gcalToken is an OAuth token as cached in gcalTokenFile.
+type gcalToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// gcalEvent is a Google Calendar event as returned and accepted by the API.
+type gcalEvent struct {
+	ID                 string          `json:"id,omitempty"`
+	Status             string          `json:"status,omitempty"`
+	Summary            string          `json:"summary,omitempty"`
+	Description        string          `json:"description,omitempty"`
+	Start              gcalTime        `json:"start"`
+	End                gcalTime        `json:"end"`
+	Attendees          []gcalAttendee  `json:"attendees,omitempty"`
+	ExtendedProperties *gcalProperties `json:"extendedProperties,omitempty"`
+}
+
+// gcalTime is when an event starts or ends; all-day events have a Date
+// instead of a DateTime.
+type gcalTime struct {
+	DateTime time.Time `json:"dateTime,omitzero"`
+	Date     string    `json:"date,omitempty"`
+}
+
+type gcalAttendee struct {
+	Self           bool   `json:"self,omitempty"`
+	ResponseStatus string `json:"responseStatus,omitempty"`
+}
+
+type gcalProperties struct {
+	Private map[string]string `json:"private,omitempty"`
+}
+
+// sessionID returns the ID of the session e was pushed for, or "".
+func (e gcalEvent) sessionID() string {
+	if e.ExtendedProperties == nil {
+		return ""
+	}
+	return e.ExtendedProperties.Private[gcalSessionKey]
+}
+
+// declined reports whether the user said no to the meeting.
+func (e gcalEvent) declined() bool {
+	return slices.ContainsFunc(e.Attendees, func(a gcalAttendee) bool {
+		return a.Self && a.ResponseStatus == "declined"
+	})
+}
+
+// gcalClient talks to the Google Calendar API, refreshing its token as
+// needed.
+type gcalClient struct {
+	jsonAPI
+	cfg   gcalConfig
+	token gcalToken
+}
+
+func newGcalClient(cfg gcalConfig) (*gcalClient, error) {
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	data, err := readData(gcalTokenFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errors.New("not logged in to Google Calendar; run time-tracker gcal login")
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &gcalClient{cfg: cfg}
+	if err := json.Unmarshal(data, &c.token); err != nil {
+		return nil, fmt.Errorf("%s: %w", gcalTokenFile, err)
+	}
+	c.jsonAPI = jsonAPI{name: "google calendar", base: gcalAPI, auth: func(req *http.Request) error {
+		token, err := c.accessToken()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}}
+	return c, nil
+}
+
+func (cfg gcalConfig) check() error {
+	if cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return errors.New("no Google OAuth client: set google_calendar.client_id and client_secret in config.json")
+	}
+	return nil
+}
+
+// saveGcalToken caches token, readable by the user only.
+func saveGcalToken(token gcalToken) error {
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dataKey != nil {
+		data = dataKey.seal(data)
+	}
+	return writeFileAtomic(gcalTokenFile, data, 0600)
+}
+
+// requestToken posts form to the token endpoint, as when exchanging an
+// authorization code or refreshing.
+func requestToken(form url.Values) (gcalToken, error) {
+	resp, err := googleClient.PostForm(googleTokenURL, form)
+	if err != nil {
+		return gcalToken{}, fmt.Errorf("google: %w", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		Error        string `json:"error"`
+		Description  string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return gcalToken{}, fmt.Errorf("google: %s: %w", resp.Status, err)
+	}
+	if result.Error != "" {
+		return gcalToken{}, fmt.Errorf("google: %s: %s", result.Error, result.Description)
+	}
+	return gcalToken{
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(result.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// accessToken returns a token good for at least another minute, refreshing
+// and caching it again once it has run out.
+func (c *gcalClient) accessToken() (string, error) {
+	if time.Until(c.token.Expiry) > time.Minute {
+		return c.token.AccessToken, nil
+	}
+	if c.token.RefreshToken == "" {
+		return "", errors.New("the Google Calendar login has expired; run time-tracker gcal login")
+	}
+	token, err := requestToken(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {c.token.RefreshToken},
+		"client_id":     {c.cfg.ClientID},
+		"client_secret": {c.cfg.ClientSecret},
+	})
+	if err != nil {
+		return "", fmt.Errorf("refreshing the Google Calendar login (run time-tracker gcal login if this persists): %w", err)
+	}
+	token.RefreshToken = c.token.RefreshToken
+	c.token = token
+	if err := saveGcalToken(token); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return token.AccessToken, nil
+}
+
+// events returns the events of calendar within r, recurring ones expanded.
+func (c *gcalClient) events(calendar string, r dateRange) ([]gcalEvent, error) {
+	q := url.Values{"singleEvents": {"true"}, "orderBy": {"startTime"}, "maxResults": {"2500"}}
+	if !r.From.IsZero() {
+		q.Set("timeMin", r.From.Format(time.RFC3339))
+	}
+	if !r.To.IsZero() {
+		q.Set("timeMax", r.To.Format(time.RFC3339))
+	}
+	var events []gcalEvent
+	for {
+		var page struct {
+			Items         []gcalEvent `json:"items"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		if err := c.do(http.MethodGet, "/calendars/"+url.PathEscape(calendar)+"/events?"+q.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		events = append(events, page.Items...)
+		if page.NextPageToken == "" {
+			return events, nil
+		}
+		q.Set("pageToken", page.NextPageToken)
+	}
+}
+
+func (c *gcalClient) create(calendar string, e gcalEvent) error {
+	return c.do(http.MethodPost, "/calendars/"+url.PathEscape(calendar)+"/events", e, nil)
+}
+
+// toEvent converts sess to an event remembering the session's ID, so that
+// pushing it again is skipped.
+func toEvent(sess session, cfg config) gcalEvent {
+	desc := "Tracked " + cfg.durationLong(sess.Duration)
+	if sess.Note != "" {
+		desc += "\n" + sess.Note
+	}
+	if len(sess.Tags) > 0 {
+		desc += "\n" + track.FormatTags(sess.Tags)
+	}
+	return gcalEvent{
+		Summary:            track.ProjectLabel(sess.Project),
+		Description:        desc,
+		Start:              gcalTime{DateTime: sess.Start.Truncate(time.Second)},
+		End:                gcalTime{DateTime: sess.End.Truncate(time.Second)},
+		ExtendedProperties: &gcalProperties{Private: map[string]string{gcalSessionKey: sess.ID}},
+	}
+}
+
+// pushed returns the IDs of the sessions pushed to the calendar kept for
+// them within r.
+func (c *gcalClient) pushed(r dateRange) ([]string, error) {
+	if c.cfg.Calendar == "" {
+		return nil, errors.New("no calendar to push to: set google_calendar.calendar in config.json to the ID of a calendar made for your sessions")
+	}
+	events, err := c.events(c.cfg.Calendar, r)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range events {
+		if id := e.sessionID(); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (c *gcalClient) push(sess session, cfg config) error {
+	return c.create(c.cfg.Calendar, toEvent(sess, cfg))
+}
+
+// meetings returns sessions for the finished meetings within r, leaving out
+// cancelled events, those the user declined and sessions pushed there.
+func (c *gcalClient) meetings(r dateRange, cfg config) ([]session, error) {
+	events, err := c.events(cmp.Or(c.cfg.Meetings, "primary"), r)
+	if err != nil {
+		return nil, err
+	}
+	var sessions []session
+	for _, e := range events {
+		if e.Status == "cancelled" || e.declined() || e.sessionID() != "" || !finishedMeeting(e.Start.DateTime, e.End.DateTime) {
+			continue
+		}
+		sessions = append(sessions, meetingSession(e.Summary, e.Start.DateTime, e.End.DateTime, c.cfg.MeetingProject, cfg))
+	}
+	return sessions, nil
+}
+
+// gcalLogin lets the user grant access in the browser and caches the token.
+// Google redirects back to a server on a loopback port for the code, which
+// PKCE ties to this run.
+func gcalLogin(cfg gcalConfig) error {
+	if err := cfg.check(); err != nil {
+		return err
+	}
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	redirect := "http://" + ln.Addr().String()
+
+	verifier := randomToken()
+	challenge := sha256.Sum256([]byte(verifier))
+	state := randomToken()
+	authURL := googleAuthURL + "?" + url.Values{
+		"client_id":             {cfg.ClientID},
+		"redirect_uri":          {redirect},
+		"response_type":         {"code"},
+		"scope":                 {gcalScope},
+		"access_type":           {"offline"},
+		"prompt":                {"consent"},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}.Encode()
+	fmt.Printf("Open this page to let time-tracker use Google Calendar:\n\n  %s\n\nWaiting for you to allow access…\n", authURL)
+
+	codes := make(chan url.Values, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("state") != state {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, "time-tracker got the answer; you can close this tab.")
+		select {
+		case codes <- r.URL.Query():
+		default:
+		}
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	var q url.Values
+	select {
+	case q = <-codes:
+	case <-time.After(5 * time.Minute):
+		return errors.New("gave up waiting for Google after 5 minutes")
+	}
+	if e := q.Get("error"); e != "" {
+		return fmt.Errorf("google: %s", e)
+	}
+	token, err := requestToken(url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {q.Get("code")},
+		"client_id":     {cfg.ClientID},
+		"client_secret": {cfg.ClientSecret},
+		"redirect_uri":  {redirect},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return err
+	}
+	if err := saveGcalToken(token); err != nil {
+		return err
+	}
+	fmt.Println("Logged in to Google Calendar")
+	return nil
+}
+
+// randomToken returns 32 random bytes, URL-safe encoded.
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// runGcal logs in to Google Calendar, or pushes sessions to it and pulls
+// meetings from it.
+func runGcal(storageKind string, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: gcal login|pull|push [-range RANGE] [-dry-run] [-yes]")
+	}
+	if args[0] != "login" {
+		return runCalendarSync(storageKind, "gcal", "Google Calendar", args, func(cfg config) (calendarService, error) {
+			return newGcalClient(cfg.GoogleCalendar)
+		})
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	return gcalLogin(cfg.GoogleCalendar)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/report"
+	"time-tracking/pkg/track"
+)
+
+// goalBarWidth is the width of the daily goal progress bar in cells.
+const goalBarWidth = 24
+
+// dailyGoal is the configured daily target, or 0 if there is none.
+func (c config) dailyGoal() time.Duration {
+	// loadConfig has checked that DailyGoal parses.
+	d, _ := time.ParseDuration(c.DailyGoal)
+	return d
+}
+
+// trackedToday sums the sessions started today, counting the running session
+// as if it had tracked elapsed so far.
+func (m model) trackedToday(elapsed time.Duration) time.Duration {
+	today, _ := report.ByDay(session{Start: time.Now()})
+	var total time.Duration
+	for _, sess := range m.history {
+		if key, _ := report.ByDay(sess); key == today {
+			total += sess.Duration
+		}
+	}
+	if m.active != nil {
+		if key, _ := report.ByDay(session{Start: m.active.start}); key == today {
+			total += elapsed
+		}
+	}
+	return total
+}
+
+// viewGoal renders today's progress towards the daily goal, or nothing if no
+// goal is set.
+func (m model) viewGoal() string {
+	goal := m.config.dailyGoal()
+	if goal <= 0 {
+		return ""
+	}
+	done := m.trackedToday(m.elapsed)
+	filled := int(int64(goalBarWidth) * int64(min(done, goal)) / int64(goal))
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", goalBarWidth-filled)
+
+	line := fmt.Sprintf("Today %s %s / %s  %d%%", bar, m.config.duration(done), track.FormatMinutes(goal), int(100*done/goal))
+	if done >= goal {
+		return selectedStyle.Render(line+"  ✓ goal reached") + "\n\n"
+	}
+	return normalStyle.Render(line) + "\n\n"
+}
+
+// goalReminder returns a notification command when the running session takes
+// today's total past the daily goal, i.e. when it was below the goal with
+// before tracked and is not with m.elapsed.
+func (m model) goalReminder(before time.Duration) tea.Cmd {
+	goal := m.config.dailyGoal()
+	if goal <= 0 || m.trackedToday(before) >= goal || m.trackedToday(m.elapsed) < goal {
+		return nil
+	}
+	return tea.Batch(m.alertCmd(), m.notifyCmd("Daily goal reached",
+		fmt.Sprintf("You've tracked %s today.", m.config.durationLong(m.trackedToday(m.elapsed)))))
+}
+
+// startGoalEdit opens the inline editor for the daily goal.
+func (m model) startGoalEdit() (tea.Model, tea.Cmd) {
+	m.editing = editGoal
+	m.editInput.Placeholder = "6h"
+	m.editInput.SetValue(m.config.DailyGoal)
+	m.editInput.CursorEnd()
+	return m, m.editInput.Focus()
+}
+
+// applyGoal sets the daily goal from s, such as "6h" or "7h30m"; empty or 0
+// clears it. An invalid value is reported in the status line.
+func (m *model) applyGoal(s string) {
+	s = strings.TrimSpace(s)
+	if s == "0" {
+		s = ""
+	}
+	if err := checkGoal(s); err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.status = ""
+	m.config.DailyGoal = s
+	saveConfig(m.config)
+}
+
+func checkGoal(s string) error {
+	if s == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(s); err != nil || d <= 0 || d > 24*time.Hour {
+		return fmt.Errorf("invalid daily goal %q (want e.g. 6h or 7h30m)", s)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+)
+
+const harvestAPI = "https://api.harvestapp.com/v2"
+
+// harvestDateLayout is how Harvest writes the day of an entry.
+const harvestDateLayout = "2006-01-02"
+
+// harvestGroup groups the external references of pushed entries, which
+// hold the IDs of the sessions they were pushed from.
+const harvestGroup = "time-tracker"
+
+// harvestConfig is the "harvest" section of config.json.
+type harvestConfig struct {
+	AccountID int64 `json:"account_id,omitempty"`
+	// Token is a personal access token; HARVEST_TOKEN overrides it.
+	Token string `json:"token,omitempty"`
+	// Task is the task entries are logged under, which can be left out
+	// where a project has a single task.
+	Task string `json:"task,omitempty"`
+	// Projects maps local project names to Harvest project names where
+	// they differ. Unmapped names are used as is.
+	Projects map[string]string `json:"projects,omitempty"`
+}
+
+// harvestEntry is a Harvest time entry as returned and accepted by the API.
+type harvestEntry struct {
+	ID        int64             `json:"id,omitempty"`
+	ProjectID int64             `json:"project_id"`
+	TaskID    int64             `json:"task_id"`
+	SpentDate string            `json:"spent_date"`
+	Hours     float64           `json:"hours"`
+	Notes     string            `json:"notes,omitempty"`
+	Reference *harvestReference `json:"external_reference,omitempty"`
+}
+
+// harvestReference links an entry to what it was made from elsewhere.
+type harvestReference struct {
+	ID      string `json:"id"`
+	GroupID string `json:"group_id"`
+}
+
+// harvestAssignment is a project the user can log time to, with its tasks.
+type harvestAssignment struct {
+	Project struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"project"`
+	Tasks []struct {
+		Task struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		} `json:"task"`
+	} `json:"task_assignments"`
+}
+
+// harvestClient talks to the Harvest API for one account.
+type harvestClient struct {
+	jsonAPI
+	cfg harvestConfig
+	// Filled by load: the user's entries and projects by name.
+	entries  []harvestEntry
+	projects map[string]harvestAssignment
+}
+
+func newHarvestClient(cfg harvestConfig) (*harvestClient, error) {
+	token := cfg.Token
+	if env := os.Getenv("HARVEST_TOKEN"); env != "" {
+		token = env
+	}
+	if token == "" {
+		return nil, errors.New("no Harvest token: set harvest.token in config.json or HARVEST_TOKEN")
+	}
+	if cfg.AccountID == 0 {
+		return nil, errors.New("no Harvest account: set harvest.account_id in config.json")
+	}
+	api := jsonAPI{name: "harvest", base: harvestAPI, auth: func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Harvest-Account-Id", fmt.Sprint(cfg.AccountID))
+		req.Header.Set("User-Agent", "time-tracker")
+		return nil
+	}}
+	return &harvestClient{jsonAPI: api, cfg: cfg}, nil
+}
+
+// load fetches the user's entries on the days of r and the projects they
+// can log time to.
+func (c *harvestClient) load(r dateRange) error {
+	var user struct {
+		ID int64 `json:"id"`
+	}
+	if err := c.do(http.MethodGet, "/users/me", nil, &user); err != nil {
+		return err
+	}
+
+	c.projects = make(map[string]harvestAssignment)
+	for page := 1; page != 0; {
+		var list struct {
+			Assignments []harvestAssignment `json:"project_assignments"`
+			NextPage    int                 `json:"next_page"`
+		}
+		if err := c.do(http.MethodGet, fmt.Sprintf("/users/me/project_assignments?page=%d", page), nil, &list); err != nil {
+			return err
+		}
+		for _, a := range list.Assignments {
+			c.projects[a.Project.Name] = a
+		}
+		page = list.NextPage
+	}
+
+	q := url.Values{"user_id": {fmt.Sprint(user.ID)}}
+	if !r.From.IsZero() {
+		q.Set("from", r.From.Format(harvestDateLayout))
+	}
+	if !r.To.IsZero() {
+		q.Set("to", r.To.Add(-time.Nanosecond).Format(harvestDateLayout))
+	}
+	c.entries = nil
+	for page := 1; page != 0; {
+		q.Set("page", fmt.Sprint(page))
+		var list struct {
+			Entries  []harvestEntry `json:"time_entries"`
+			NextPage int            `json:"next_page"`
+		}
+		if err := c.do(http.MethodGet, "/time_entries?"+q.Encode(), nil, &list); err != nil {
+			return err
+		}
+		c.entries = append(c.entries, list.Entries...)
+		page = list.NextPage
+	}
+	return nil
+}
+
+// harvestHours is the time tracked in sess in hours, as Harvest keeps it.
+func harvestHours(sess session) float64 {
+	return math.Round(sess.Duration.Hours()*100) / 100
+}
+
+// has reports whether an entry was pushed from sess or, for entries made
+// otherwise, logs its time with its note on its day.
+func (c *harvestClient) has(sess session) bool {
+	return slices.ContainsFunc(c.entries, func(e harvestEntry) bool {
+		if e.Reference != nil && e.Reference.GroupID == harvestGroup {
+			return e.Reference.ID == sess.ID
+		}
+		return e.SpentDate == sess.Start.Format(harvestDateLayout) && e.Hours == harvestHours(sess) && e.Notes == sess.Note
+	})
+}
+
+func (c *harvestClient) check(sess session) error {
+	_, err := c.toEntry(sess)
+	return err
+}
+
+func (c *harvestClient) push(sess session) error {
+	e, err := c.toEntry(sess)
+	if err != nil {
+		return err
+	}
+	return c.do(http.MethodPost, "/time_entries", e, nil)
+}
+
+// toEntry converts sess to a new Harvest entry on the day it started.
+// Harvest logs time to a project's task, so sessions without a project
+// cannot be pushed, and decides what is billable by task.
+func (c *harvestClient) toEntry(sess session) (harvestEntry, error) {
+	e := harvestEntry{
+		SpentDate: sess.Start.Format(harvestDateLayout),
+		Hours:     harvestHours(sess),
+		Notes:     sess.Note,
+		Reference: &harvestReference{ID: sess.ID, GroupID: harvestGroup},
+	}
+	if sess.Project == "" {
+		return e, fmt.Errorf("session at %s has no project; Harvest needs one", sess.Start.Format(csvTimeLayout))
+	}
+	name := remoteProject(c.cfg.Projects, sess.Project)
+	a, ok := c.projects[name]
+	if !ok {
+		return e, fmt.Errorf("no Harvest project %q for %s; assign yourself to it in Harvest or map it under harvest.projects", name, sess.Project)
+	}
+	e.ProjectID = a.Project.ID
+	for _, t := range a.Tasks {
+		if t.Task.Name == c.cfg.Task || (c.cfg.Task == "" && len(a.Tasks) == 1) {
+			e.TaskID = t.Task.ID
+		}
+	}
+	if e.TaskID == 0 && c.cfg.Task == "" {
+		return e, fmt.Errorf("project %q has several tasks in Harvest; name the one to log to in harvest.task", name)
+	}
+	if e.TaskID == 0 {
+		return e, fmt.Errorf("project %q has no task %q in Harvest", name, c.cfg.Task)
+	}
+	return e, nil
+}
+
+// runHarvest pushes local sessions to Harvest.
+func runHarvest(storageKind string, args []string) error {
+	return runTimesheetPush(storageKind, "harvest", "Harvest", args, func(cfg config) (timesheet, error) {
+		return newHarvestClient(cfg.Harvest)
+	})
+}
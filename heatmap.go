@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/report"
+	"time-tracking/pkg/track"
+)
+
+// heatmapPeriods are the numbers of months the heatmap view cycles through.
+var heatmapPeriods = []int{3, 6, 12}
+
+// heatmapShades draw a day's total from nothing tracked to a full day.
+var heatmapShades = []string{"·", "░", "▒", "▓", "█"}
+
+// dailyTotals sums tracked time per day, keyed like report.ByDay, counting the
+// running session on the day it started.
+func (m model) dailyTotals() map[string]time.Duration {
+	totals := make(map[string]time.Duration)
+	for _, sess := range m.history {
+		key, _ := report.ByDay(sess)
+		totals[key] += sess.Duration
+	}
+	if m.active != nil {
+		key, _ := report.ByDay(session{Start: m.active.start})
+		totals[key] += m.elapsed
+	}
+	return totals
+}
+
+// heatmapShade picks the shade for total, scaled so that a full day (the
+// daily goal, or 8h without one) gets the darkest.
+func heatmapShade(total, fullDay time.Duration) string {
+	if total <= 0 {
+		return heatmapShades[0]
+	}
+	level := 1 + int(int64(len(heatmapShades)-2)*int64(total)/int64(fullDay))
+	return heatmapShades[min(level, len(heatmapShades)-1)]
+}
+
+// streaks returns the number of consecutive days with at least minimum
+// tracked ending today (or yesterday, if today has not got there yet) and the
+// longest run between from and today. A minimum of 0 counts any tracked day.
+func streaks(totals map[string]time.Duration, from, today time.Time, minimum time.Duration) (current, longest int) {
+	run := 0
+	for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+		key, _ := report.ByDay(session{Start: day})
+		if totals[key] > 0 && totals[key] >= minimum {
+			run++
+			longest = max(longest, run)
+		} else if !day.Equal(today) {
+			run = 0
+		}
+	}
+	return run, longest
+}
+
+func (m model) updateHeatmap(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Back):
+		m.currentView = menuView
+		m.cursor = 0
+	case key.Matches(msg, m.keys.NextMode):
+		m.heatmapPeriod = (m.heatmapPeriod + 1) % len(heatmapPeriods)
+	case key.Matches(msg, m.keys.PrevMode):
+		m.heatmapPeriod = (m.heatmapPeriod + len(heatmapPeriods) - 1) % len(heatmapPeriods)
+	}
+	return m, nil
+}
+
+// viewHeatmap renders a GitHub-style calendar of the last few months: one
+// column per ISO week and one row per weekday.
+func (m model) viewHeatmap() string {
+	s := titleStyle.Render("🗓  Heatmap") + "\n\n"
+
+	for i, months := range heatmapPeriods {
+		label := fmt.Sprintf(" %d months ", months)
+		if i == m.heatmapPeriod {
+			s += selectedStyle.Render("[" + label + "]")
+		} else {
+			s += normalStyle.Render(" " + label + " ")
+		}
+	}
+	s += "\n\n"
+
+	now := time.Now()
+	y, mo, d := now.Date()
+	today := time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+	first := report.StartOfISOWeek(today.AddDate(0, -heatmapPeriods[m.heatmapPeriod], 0))
+	weeks := int(report.StartOfISOWeek(today).Sub(first).Hours()/24/7+0.5) + 1
+
+	fullDay := m.config.dailyGoal()
+	if fullDay <= 0 {
+		fullDay = 8 * time.Hour
+	}
+	totals := m.dailyTotals()
+
+	// Month names above the week in which each month starts.
+	header := []rune(strings.Repeat(" ", 4+2*weeks))
+	lastMonth := time.Month(0)
+	for w := 0; w < weeks; w++ {
+		monday := first.AddDate(0, 0, 7*w)
+		if month := monday.AddDate(0, 0, 6).Month(); month != lastMonth {
+			lastMonth = month
+			if name := []rune(month.String()[:3]); 4+2*w+len(name) <= len(header) {
+				copy(header[4+2*w:], name)
+			}
+		}
+	}
+	s += historyItemStyle.Render(strings.TrimRight(string(header), " ")) + "\n"
+
+	var total time.Duration
+	tracked := 0
+	for weekday := 0; weekday < 7; weekday++ {
+		line := "    "
+		if weekday%2 == 0 {
+			line = first.AddDate(0, 0, weekday).Format("Mon") + " "
+		}
+		for w := 0; w < weeks; w++ {
+			day := first.AddDate(0, 0, 7*w+weekday)
+			if day.After(today) {
+				break
+			}
+			key, _ := report.ByDay(session{Start: day})
+			line += heatmapShade(totals[key], fullDay) + " "
+			total += totals[key]
+			if totals[key] > 0 {
+				tracked++
+			}
+		}
+		s += selectedStyle.Render(strings.TrimRight(line, " ")) + "\n"
+	}
+
+	current, longest := streaks(totals, first, today, m.config.streakMinimum())
+	s += "\n" + historyItemStyle.Render(fmt.Sprintf("Less %s More   (%s = %s or more)",
+		strings.Join(heatmapShades, " "), heatmapShades[len(heatmapShades)-1], track.FormatMinutes(fullDay))) + "\n\n"
+	s += normalStyle.Render(fmt.Sprintf("Tracked %s on %d days • current streak %d • longest streak %d",
+		m.config.duration(total), tracked, current, longest)) + "\n"
+
+	s += "\n" + helpLine(pairHelp(m.keys.NextMode, m.keys.PrevMode, "period"), m.keys.Back, m.keys.Help, m.keys.Quit)
+
+	return s
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// helpSection is a titled group of bindings in the help overlay.
+type helpSection struct {
+	title    string
+	bindings []key.Binding
+}
+
+// helpSections lists the bindings that apply in the current view, followed by
+// the ones available everywhere.
+func (m model) helpSections() []helpSection {
+	k := m.keys
+	var sections []helpSection
+	switch m.currentView {
+	case menuView:
+		sections = append(sections, helpSection{"Menu", []key.Binding{k.Up, k.Down, k.Select, k.Start, k.Resume, k.Snooze}})
+	case trackingView:
+		sections = append(sections, helpSection{"Tracking", []key.Binding{k.Stop, k.Pause, k.Countdown, k.EditNote, k.AddNote, k.EditTags, k.EditIssue, k.Billable, k.Interrupt, k.Snooze}})
+	case historyView:
+		sections = append(sections,
+			helpSection{"Moving around", []key.Binding{k.Up, k.Down, k.PageUp, k.PageDown, k.Top, k.Bottom, k.NextMode, k.PrevMode, k.Collapse, k.CollapseAll}},
+			helpSection{"Finding sessions", []key.Binding{k.Search, k.NextMatch, k.PrevMatch, k.FilterTag, k.DateRange, k.CustomRange}},
+			helpSection{"Editing", []key.Binding{k.EditNote, k.EditTags, k.EditIssue, k.Project, k.Billable, k.Split, k.Mark, k.Merge, k.Delete, k.Undo, k.Resolve, k.ClearAll, k.Export}},
+		)
+	case todayView:
+		sections = append(sections, helpSection{"Today", []key.Binding{k.Start, k.Resume, k.Stop, k.Pause, k.Snooze}})
+	case summaryView:
+		sections = append(sections, helpSection{"Summary", []key.Binding{k.NextMode, k.PrevMode, k.DateRange}})
+	case heatmapView:
+		sections = append(sections, helpSection{"Heatmap", []key.Binding{k.NextMode, k.PrevMode}})
+	case timelineView:
+		sections = append(sections, helpSection{"Timeline", []key.Binding{k.PrevMode, k.NextMode, k.CustomRange}})
+	case gapsView:
+		sections = append(sections, helpSection{"Gaps", []key.Binding{k.Up, k.Down, k.Select, k.DateRange}})
+	case trashView:
+		sections = append(sections, helpSection{"Trash", []key.Binding{k.Up, k.Down, withDesc(k.Select, "restore"), withDesc(k.Undo, "restore"), withDesc(k.Delete, "purge"), withDesc(k.ClearAll, "empty trash")}})
+	case profilesView:
+		sections = append(sections, helpSection{"Profiles", []key.Binding{k.Up, k.Down, withDesc(k.Select, "switch")}})
+	case auditView:
+		sections = append(sections, helpSection{"Audit log", []key.Binding{k.Up, k.Down, k.PageUp, k.PageDown, k.Top, k.Bottom}})
+	case settingsView:
+		sections = append(sections, helpSection{"Settings", []key.Binding{k.Up, k.Down, k.Toggle}})
+	}
+	return append(sections, helpSection{"Everywhere", []key.Binding{
+		k.Back,
+		k.Save,
+		k.Help,
+		k.Quit,
+		key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit (cannot be remapped)")),
+	}})
+}
+
+func (m model) updateHelp(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, m.keys.Quit) {
+		return m.quit()
+	}
+	// Any other key closes the overlay.
+	m.showHelp = false
+	return m, nil
+}
+
+// viewHelp renders the full keybinding cheat sheet for the current view.
+func (m model) viewHelp() string {
+	width := 0
+	for _, section := range m.helpSections() {
+		for _, b := range section.bindings {
+			width = max(width, lipgloss.Width(b.Help().Key))
+		}
+	}
+
+	s := titleStyle.Render("⌨  Keys") + "\n\n"
+	for _, section := range m.helpSections() {
+		s += projectHeaderStyle.Render(section.title) + "\n"
+		for _, b := range section.bindings {
+			if !b.Enabled() {
+				continue
+			}
+			pad := strings.Repeat(" ", width-lipgloss.Width(b.Help().Key))
+			s += fmt.Sprintf("  %s%s  %s\n", selectedStyle.Render(b.Help().Key), pad, normalStyle.Render(b.Help().Desc))
+		}
+		s += "\n"
+	}
+	s += helpStyle.Render("press any key to close")
+
+	if m.width > 0 && m.height > 0 {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, s)
+	}
+	return s
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/report"
+	"time-tracking/pkg/track"
+)
+
+// historySort selects the order of the history view and how it is grouped.
+type historySort int
+
+const (
+	sortNewest historySort = iota
+	sortOldest
+	sortLongest
+	sortByProject
+	historySortCount
+)
+
+func (s historySort) String() string {
+	switch s {
+	case sortOldest:
+		return "Oldest first"
+	case sortLongest:
+		return "Longest"
+	case sortByProject:
+		return "Project"
+	default:
+		return "Newest first"
+	}
+}
+
+// historyGroup is a run of sessions shown under one header in the history
+// view, identified by their indices into the history slice.
+type historyGroup struct {
+	key     string // identifies the group in the set of collapsed groups
+	label   string
+	indices []int
+	total   time.Duration
+}
+
+// sortHistory groups and orders the sessions at indices: by day for the date
+// orders, under a single header when longest first, and by project, in name
+// order with sessions oldest first, otherwise.
+func sortHistory(history []session, indices []int, order historySort) []historyGroup {
+	indices = slices.Clone(indices)
+	sort.SliceStable(indices, func(a, b int) bool { return history[indices[a]].Start.Before(history[indices[b]].Start) })
+
+	var groups []historyGroup
+	switch order {
+	case sortLongest:
+		sort.SliceStable(indices, func(a, b int) bool { return history[indices[a]].Duration > history[indices[b]].Duration })
+		if len(indices) > 0 {
+			groups = []historyGroup{{key: "all", label: "All sessions"}}
+			addToGroup(&groups[0], history, indices)
+		}
+	case sortByProject:
+		lookup := make(map[string]int)
+		for _, i := range indices {
+			project := history[i].Project
+			g, ok := lookup[project]
+			if !ok {
+				g = len(groups)
+				lookup[project] = g
+				groups = append(groups, historyGroup{key: "project:" + project, label: track.ProjectLabel(project)})
+			}
+			addToGroup(&groups[g], history, []int{i})
+		}
+		sort.SliceStable(groups, func(a, b int) bool {
+			return strings.ToLower(groups[a].label) < strings.ToLower(groups[b].label)
+		})
+	default:
+		for _, i := range indices {
+			key, label := report.ByDay(history[i])
+			if len(groups) == 0 || groups[len(groups)-1].key != key {
+				groups = append(groups, historyGroup{key: key, label: label})
+			}
+			addToGroup(&groups[len(groups)-1], history, []int{i})
+		}
+		if order == sortNewest {
+			slices.Reverse(groups)
+			for _, g := range groups {
+				slices.Reverse(g.indices)
+			}
+		}
+	}
+	return groups
+}
+
+func addToGroup(g *historyGroup, history []session, indices []int) {
+	for _, i := range indices {
+		g.indices = append(g.indices, i)
+		g.total += history[i].Duration
+	}
+}
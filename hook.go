@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Hook-only events; webhooks are not told about pauses.
+const (
+	eventPause  = "pause"
+	eventResume = "resume"
+)
+
+// hookTimeout bounds how long a hook command may run.
+const hookTimeout = 30 * time.Second
+
+// hooks are shell commands from config.json run when tracking changes, with
+// the session described in TIME_TRACKER_* environment variables.
+type hooks struct {
+	OnStart  string `json:"on_start,omitempty"`
+	OnStop   string `json:"on_stop,omitempty"`
+	OnPause  string `json:"on_pause,omitempty"`
+	OnResume string `json:"on_resume,omitempty"`
+}
+
+func (h hooks) command(event string) string {
+	switch event {
+	case eventStart:
+		return h.OnStart
+	case eventStop:
+		return h.OnStop
+	case eventPause:
+		return h.OnPause
+	case eventResume:
+		return h.OnResume
+	}
+	return ""
+}
+
+func pauseEvent(a activeSession, elapsed time.Duration) webhookEvent {
+	ev := startEvent(a)
+	ev.Event = eventPause
+	ev.ElapsedSeconds = int64(elapsed.Seconds())
+	return ev
+}
+
+func resumeEvent(a activeSession, elapsed time.Duration) webhookEvent {
+	ev := pauseEvent(a, elapsed)
+	ev.Event = eventResume
+	return ev
+}
+
+// hookEnv describes ev in TIME_TRACKER_* variables added to the environment.
+func hookEnv(ev webhookEvent) []string {
+	env := append(os.Environ(),
+		"TIME_TRACKER_EVENT="+ev.Event,
+		"TIME_TRACKER_PROJECT="+ev.Project,
+		"TIME_TRACKER_NOTE="+ev.Note,
+		"TIME_TRACKER_TAGS="+strings.Join(ev.Tags, ","),
+		"TIME_TRACKER_START="+ev.Start.Format(time.RFC3339),
+		"TIME_TRACKER_ELAPSED="+strconv.FormatInt(ev.ElapsedSeconds, 10),
+	)
+	if ev.End != nil {
+		env = append(env, "TIME_TRACKER_END="+ev.End.Format(time.RFC3339))
+	}
+	return env
+}
+
+// runHook runs the command configured for ev.Event, if any, through the
+// shell, tells the plugins taking events and updates the Slack status. Its
+// output is only shown when it fails.
+func (c config) runHook(ev webhookEvent) error {
+	command := c.Hooks.command(ev.Event)
+	if command == "" {
+		return errors.Join(c.notifyPlugins(ev), c.updateSlack(ev))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = hookEnv(ev)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			err = fmt.Errorf("on_%s hook: %w: %s", ev.Event, err, msg)
+		} else {
+			err = fmt.Errorf("on_%s hook: %w", ev.Event, err)
+		}
+	}
+	return errors.Join(err, c.notifyPlugins(ev), c.updateSlack(ev))
+}
+
+// hookErrMsg reports a failed hook command from the TUI.
+type hookErrMsg struct {
+	err error
+}
+
+// hookCmd runs the hook for ev, tells the plugins taking events and updates
+// the Slack status in the background, or is nil if there is nothing to do.
+func (m model) hookCmd(ev webhookEvent) tea.Cmd {
+	if m.config.Hooks.command(ev.Event) == "" && !m.config.hasEventPlugins() && !m.config.Slack.enabled() {
+		return nil
+	}
+	cfg := m.config
+	return func() tea.Msg {
+		if err := cfg.runHook(ev); err != nil {
+			return hookErrMsg{err}
+		}
+		return nil
+	}
+}
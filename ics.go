@@ -0,0 +1,211 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+const icsTimeLayout = "20060102T150405Z"
+
+// icsUIDSuffix follows the session ID in the UID of an exported event.
+const icsUIDSuffix = "@time-tracker"
+
+// icsEscaper escapes iCalendar TEXT values (RFC 5545, section 3.3.11).
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// writeICS writes history to w as an iCalendar file with one VEVENT per
+// session, so tracked time can be reviewed in a calendar app.
+func writeICS(w io.Writer, history []session, cfg config) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//time-tracker//EN",
+		"CALSCALE:GREGORIAN",
+	}
+	stamp := time.Now().UTC().Format(icsTimeLayout)
+	for _, sess := range history {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+sess.ID+icsUIDSuffix,
+			"DTSTAMP:"+stamp,
+			"DTSTART:"+sess.Start.UTC().Format(icsTimeLayout),
+			"DTEND:"+sess.End.UTC().Format(icsTimeLayout),
+			"SUMMARY:"+icsEscaper.Replace(track.ProjectLabel(sess.Project)),
+		)
+		desc := "Tracked " + cfg.durationLong(sess.Duration)
+		if sess.Note != "" {
+			desc += "\n" + sess.Note
+		}
+		lines = append(lines, "DESCRIPTION:"+icsEscaper.Replace(desc))
+		if sess.Issue != "" {
+			lines = append(lines, "URL:"+sess.Issue)
+		}
+		if len(sess.Tags) > 0 {
+			escaped := make([]string, len(sess.Tags))
+			for i, tag := range sess.Tags {
+				escaped[i] = icsEscaper.Replace(tag)
+			}
+			lines = append(lines, "CATEGORIES:"+strings.Join(escaped, ","))
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, foldICSLine(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// foldICSLine splits line into 75-octet pieces joined by CRLF and a space,
+// without breaking a UTF-8 sequence.
+func foldICSLine(line string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		n := len(string(r))
+		if width+n > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	return b.String()
+}
+
+// icsEvent is a VEVENT as read by readICSEvents. Start and End are zero for
+// all-day events.
+type icsEvent struct {
+	UID      string
+	Summary  string
+	Status   string
+	Start    time.Time
+	End      time.Time
+	Declined []string // addresses of attendees who declined, lowercased
+}
+
+// icsUnescaper reverses icsEscaper.
+var icsUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+// readICSEvents reads the events of an iCalendar file, skipping alarms and
+// other components nested in them.
+func readICSEvents(data string) []icsEvent {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.NewReplacer("\n ", "", "\n\t", "").Replace(data)
+
+	var events []icsEvent
+	var ev *icsEvent
+	var duration time.Duration
+	nested := 0
+	for _, line := range strings.Split(data, "\n") {
+		name, params, value := splitICSLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			ev, duration, nested = &icsEvent{}, 0, 0
+			continue
+		case ev == nil:
+			continue
+		case name == "BEGIN":
+			nested++
+			continue
+		case name == "END" && nested > 0:
+			nested--
+			continue
+		case nested > 0:
+			continue
+		}
+		switch name {
+		case "END":
+			if ev.End.IsZero() && !ev.Start.IsZero() {
+				ev.End = ev.Start.Add(duration)
+			}
+			events = append(events, *ev)
+			ev = nil
+		case "UID":
+			ev.UID = value
+		case "SUMMARY":
+			ev.Summary = icsUnescaper.Replace(value)
+		case "STATUS":
+			ev.Status = strings.ToUpper(value)
+		case "DTSTART":
+			ev.Start = parseICSTime(value, params)
+		case "DTEND":
+			ev.End = parseICSTime(value, params)
+		case "DURATION":
+			duration = parseICSDuration(value)
+		case "ATTENDEE":
+			if strings.EqualFold(params["PARTSTAT"], "DECLINED") {
+				ev.Declined = append(ev.Declined, strings.TrimPrefix(strings.ToLower(value), "mailto:"))
+			}
+		}
+	}
+	return events
+}
+
+// splitICSLine splits a content line into its upper-cased name, parameters
+// and value. Colons and semicolons in quoted parameter values do not count.
+func splitICSLine(line string) (name string, params map[string]string, value string) {
+	params = make(map[string]string)
+	quoted := false
+	start := 0
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if quoted || (r != ';' && r != ':') {
+			continue
+		}
+		if part := line[start:i]; start == 0 {
+			name = strings.ToUpper(part)
+		} else if k, v, ok := strings.Cut(part, "="); ok {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+		if r == ':' {
+			return name, params, strings.TrimSpace(line[i+1:])
+		}
+		start = i + 1
+	}
+	return strings.ToUpper(strings.TrimSpace(line)), params, ""
+}
+
+// parseICSTime parses a DATE-TIME in UTC, in the zone named by TZID or in
+// local time. Dates without a time, as all-day events have, yield zero.
+func parseICSTime(value string, params map[string]string) time.Time {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		return time.Time{}
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, _ := time.Parse(icsTimeLayout, value)
+		return t
+	}
+	loc := time.Local
+	if tz, err := time.LoadLocation(params["TZID"]); params["TZID"] != "" && err == nil {
+		loc = tz
+	}
+	t, _ := time.ParseInLocation("20060102T150405", value, loc)
+	return t
+}
+
+// parseICSDuration parses a DURATION such as "PT1H30M" or "P1D".
+func parseICSDuration(value string) time.Duration {
+	units := map[rune]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+	var d time.Duration
+	n := 0
+	for _, r := range strings.TrimLeft(value, "+P") {
+		switch {
+		case r >= '0' && r <= '9':
+			n = n*10 + int(r-'0')
+		case r == 'T':
+		default:
+			d += time.Duration(n) * units[r]
+			n = 0
+		}
+	}
+	return d
+}
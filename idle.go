@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// idlePollInterval is how often the system idle time is sampled while
+// tracking. Sampling shells out on most platforms, so it is kept well below
+// the tick rate.
+const idlePollInterval = 15 * time.Second
+
+var errIdleUnsupported = errors.New("idle detection is not supported on this system")
+
+// idleMsg carries a sample of how long the user has been idle.
+type idleMsg struct {
+	idle time.Duration
+	err  error
+}
+
+func idleCheckCmd() tea.Cmd {
+	return func() tea.Msg {
+		idle, err := idleTime()
+		return idleMsg{idle: idle, err: err}
+	}
+}
+
+// idleCheck returns a command sampling the idle time if detection is enabled,
+// a session is running and the last sample is old enough.
+func (m *model) idleCheck(now time.Time) tea.Cmd {
+	if m.idleAfter <= 0 || m.idleUnsupported || m.active == nil || m.idlePrompt {
+		return nil
+	}
+	if m.active.paused() && !m.idlePaused {
+		return nil
+	}
+	if now.Sub(m.lastIdleCheck) < idlePollInterval {
+		return nil
+	}
+	m.lastIdleCheck = now
+	return idleCheckCmd()
+}
+
+// handleIdle pauses the running session once the user has been idle for
+// m.idleAfter, backdating the pause to when input stopped, and raises the
+// keep/discard prompt once they come back.
+func (m model) handleIdle(msg idleMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.idleUnsupported = true
+		return m, nil
+	}
+	if m.active == nil {
+		return m, nil
+	}
+
+	now := time.Now()
+	switch {
+	case !m.idlePaused && !m.active.paused() && msg.idle >= m.idleAfter:
+		m.active.pauses = append(m.active.pauses, pause{Start: m.active.idleSince(now, msg.idle)})
+		m.elapsed = m.active.elapsed(now)
+		m.idlePaused = true
+		saveActive(*m.active)
+		return m, tea.Batch(m.notifyCmd("Tracking paused",
+			fmt.Sprintf("No input for %s; %s is paused.", m.config.durationLong(msg.idle), track.ProjectLabel(m.active.project))),
+			m.hookCmd(pauseEvent(*m.active, m.elapsed)))
+	case m.idlePaused && msg.idle < m.idleAfter:
+		m.idlePrompt = true
+	}
+	return m, nil
+}
+
+// idleSince returns when input stopped, idle before now, for backdating the
+// pause to. It never reaches back before the session started or before its
+// last pause ended, which would count the same time as paused twice.
+func (a activeSession) idleSince(now time.Time, idle time.Duration) time.Time {
+	since := later(now.Add(-idle), a.start)
+	for _, p := range a.pauses {
+		since = later(since, p.End)
+	}
+	return since
+}
+
+func (m model) updateIdlePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m.quit()
+	case "k":
+		// Keep: count the idle time as tracked by dropping the auto pause.
+		m.active.pauses = m.active.pauses[:len(m.active.pauses)-1]
+	case "d", "enter":
+		// Discard: the idle time stays excluded and tracking resumes now.
+		m.active.pauses[len(m.active.pauses)-1].End = time.Now()
+	case "esc":
+		// Stay paused: leave the pause open as if paused by hand.
+	default:
+		return m, nil
+	}
+
+	m.idlePaused = false
+	m.idlePrompt = false
+	m.elapsed = m.active.elapsed(time.Now())
+	saveActive(*m.active)
+	if m.active.paused() {
+		return m, nil
+	}
+	return m, m.hookCmd(resumeEvent(*m.active, m.elapsed))
+}
+
+func (m model) viewIdlePrompt() string {
+	s := titleStyle.Render("💤 Welcome back") + "\n\n"
+
+	idle := track.PausedTotal(m.active.pauses[len(m.active.pauses)-1:], time.Now())
+	s += normalStyle.Render(fmt.Sprintf("You were idle for %s while tracking %s.",
+		m.config.durationLong(idle),
+		track.ProjectLabel(m.active.project),
+	)) + "\n\n"
+
+	s += selectedStyle.Render("  k  keep the idle time") + "\n"
+	s += selectedStyle.Render("  d  discard it and resume") + "\n"
+	s += selectedStyle.Render("esc  stay paused") + "\n"
+
+	return s
+}
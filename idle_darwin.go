@@ -0,0 +1,30 @@
+//go:build darwin
+
+package main
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var hidIdleRe = regexp.MustCompile(`"HIDIdleTime" = (\d+)`)
+
+// idleTime reports how long the session has had no keyboard or mouse input,
+// read from the IOHIDSystem registry entry.
+func idleTime() (time.Duration, error) {
+	out, err := exec.Command("ioreg", "-c", "IOHIDSystem", "-d", "4").Output()
+	if err != nil {
+		return 0, err
+	}
+	match := hidIdleRe.FindSubmatch(out)
+	if match == nil {
+		return 0, errIdleUnsupported
+	}
+	ns, err := strconv.ParseInt(string(match[1]), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ns), nil
+}
@@ -0,0 +1,54 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var mutterIdleRe = regexp.MustCompile(`uint64 (\d+)`)
+
+// idleTime reports how long the session has had no keyboard or mouse input.
+// GNOME's Mutter idle monitor is used on Wayland and xprintidle on X11.
+func idleTime() (time.Duration, error) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if idle, err := mutterIdleTime(); err == nil {
+			return idle, nil
+		}
+	}
+	if os.Getenv("DISPLAY") != "" {
+		out, err := exec.Command("xprintidle").Output()
+		if err == nil {
+			ms, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+			if err == nil {
+				return time.Duration(ms) * time.Millisecond, nil
+			}
+		}
+	}
+	return 0, errIdleUnsupported
+}
+
+func mutterIdleTime() (time.Duration, error) {
+	out, err := exec.Command("gdbus", "call", "--session",
+		"--dest", "org.gnome.Mutter.IdleMonitor",
+		"--object-path", "/org/gnome/Mutter/IdleMonitor/Core",
+		"--method", "org.gnome.Mutter.IdleMonitor.GetIdletime",
+	).Output()
+	if err != nil {
+		return 0, err
+	}
+	match := mutterIdleRe.FindSubmatch(out)
+	if match == nil {
+		return 0, errIdleUnsupported
+	}
+	ms, err := strconv.ParseInt(string(match[1]), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
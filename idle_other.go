@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package main
+
+import "time"
+
+func idleTime() (time.Duration, error) {
+	return 0, errIdleUnsupported
+}
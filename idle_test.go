@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdleSince(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		active activeSession
+		idle   time.Duration
+		want   time.Time
+	}{
+		{
+			name:   "within the session",
+			active: activeSession{start: now.Add(-time.Hour)},
+			idle:   10 * time.Minute,
+			want:   now.Add(-10 * time.Minute),
+		},
+		{
+			name:   "back before the session started",
+			active: activeSession{start: now.Add(-5 * time.Minute)},
+			idle:   20 * time.Minute,
+			want:   now.Add(-5 * time.Minute),
+		},
+		{
+			name: "over an earlier pause",
+			active: activeSession{
+				start:  now.Add(-time.Hour),
+				pauses: []pause{{Start: now.Add(-30 * time.Minute), End: now.Add(-15 * time.Minute)}},
+			},
+			idle: 20 * time.Minute,
+			want: now.Add(-15 * time.Minute),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			since := tt.active.idleSince(now, tt.idle)
+			if !since.Equal(tt.want) {
+				t.Errorf("idleSince = %s, want %s", since.Format("15:04"), tt.want.Format("15:04"))
+			}
+			a := tt.active
+			a.pauses = append(a.pauses, pause{Start: since})
+			if got := a.elapsed(now); got < 0 {
+				t.Errorf("elapsed = %s, want it not negative", got)
+			}
+			sess := a.finish(now)
+			if err := sess.Check(); err != nil {
+				t.Errorf("stopped session: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// Sources understood by `import -from`.
+const (
+	importJSON        = "json"
+	importTimewarrior = "timewarrior"
+	importWatson      = "watson"
+)
+
+const timewarriorTimeLayout = "20060102T150405Z"
+
+// loadTimewarrior reads Timewarrior intervals from path: a .data file, the
+// data directory holding them, or the ~/.timewarrior directory above it.
+// Timewarrior has no projects, so each interval's first tag becomes the
+// project and the rest its tags. Open intervals are skipped.
+func loadTimewarrior(path string) ([]session, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if sub := filepath.Join(path, "data"); isDir(sub) {
+			path = sub
+		}
+		files, err = filepath.Glob(filepath.Join(path, "*.data"))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no Timewarrior .data files in %s", path)
+		}
+	}
+
+	var history []session
+	for _, name := range files {
+		sessions, err := loadTimewarriorFile(name)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, sessions...)
+	}
+	return history, nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// loadTimewarriorFile parses lines such as
+//
+//	inc 20261001T090000Z - 20261001T103000Z # website billable # "landing page"
+func loadTimewarriorFile(name string) ([]session, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var history []session
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "inc ") {
+			continue
+		}
+		times, rest, _ := strings.Cut(strings.TrimPrefix(line, "inc "), "#")
+		startStr, endStr, closed := strings.Cut(times, " - ")
+		if !closed {
+			continue
+		}
+		start, err := time.Parse(timewarriorTimeLayout, strings.TrimSpace(startStr))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, n, err)
+		}
+		end, err := time.Parse(timewarriorTimeLayout, strings.TrimSpace(endStr))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, n, err)
+		}
+
+		tagStr, annotation, _ := strings.Cut(rest, " # ")
+		sess := session{
+			ID:       track.NewID(),
+			Note:     strings.Trim(strings.TrimSpace(annotation), `"`),
+			Start:    start.Local(),
+			End:      end.Local(),
+			Duration: end.Sub(start),
+			Billable: true,
+		}
+		if tags := splitQuoted(tagStr); len(tags) > 0 {
+			sess.Project, sess.Tags = tags[0], tags[1:]
+		}
+		history = append(history, sess)
+	}
+	return history, scanner.Err()
+}
+
+// splitQuoted splits s on spaces, keeping "double quoted" words together.
+func splitQuoted(s string) []string {
+	var words []string
+	var word strings.Builder
+	quoted, escaped := false, false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		default:
+			word.WriteRune(r)
+		}
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// loadWatson reads Watson's frames file, or the frames file in the Watson
+// config directory at path. Each frame is a JSON array of start and stop Unix
+// times, project, ID, tags and update time.
+func loadWatson(path string) ([]session, error) {
+	if isDir(path) {
+		path = filepath.Join(path, "frames")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var frames [][]json.RawMessage
+	if err := json.Unmarshal(data, &frames); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	history := make([]session, 0, len(frames))
+	for i, frame := range frames {
+		if len(frame) < 3 {
+			return nil, fmt.Errorf("%s: frame %d has %d fields, want at least 3", path, i, len(frame))
+		}
+		var start, stop int64
+		var project string
+		var tags []string
+		err := errors.Join(
+			json.Unmarshal(frame[0], &start),
+			json.Unmarshal(frame[1], &stop),
+			json.Unmarshal(frame[2], &project),
+		)
+		if len(frame) > 4 {
+			err = errors.Join(err, json.Unmarshal(frame[4], &tags))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: frame %d: %w", path, i, err)
+		}
+		sess := session{
+			ID:       track.NewID(),
+			Project:  project,
+			Tags:     tags,
+			Start:    time.Unix(start, 0),
+			End:      time.Unix(stop, 0),
+			Billable: true,
+		}
+		sess.Duration = sess.End.Sub(sess.Start)
+		history = append(history, sess)
+	}
+	return history, nil
+}
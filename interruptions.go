@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/report"
+	"time-tracking/pkg/track"
+)
+
+// logInterruption notes in the running session that it was interrupted at
+// m.interruptedAt, with an optional note saying by what.
+func (m *model) logInterruption(note string) {
+	if m.active == nil {
+		return
+	}
+	m.active.interruptions = append(m.active.interruptions, track.Interruption{At: m.interruptedAt, Note: strings.TrimSpace(note)})
+	saveActive(*m.active)
+}
+
+// viewInterruptions describes the interruptions of the running session for
+// the tracking view.
+func (m model) viewInterruptions() string {
+	n := len(m.active.interruptions)
+	if n == 0 {
+		return ""
+	}
+	last := m.active.interruptions[n-1]
+	desc := fmt.Sprintf("Interrupted: %d×, last at %s", n, last.At.Format("15:04"))
+	if last.Note != "" {
+		desc += " (" + last.Note + ")"
+	}
+	return normalStyle.Render(desc) + "\n"
+}
+
+// interruptionDay is the interruptions logged on one day and the time
+// tracked on it.
+type interruptionDay struct {
+	label         string
+	tracked       time.Duration
+	interruptions []loggedInterruption
+}
+
+// loggedInterruption is an interruption with the project of the session it
+// interrupted.
+type loggedInterruption struct {
+	track.Interruption
+	project string
+}
+
+// interruptionsByDay groups the interruptions of history per day, newest
+// day first, leaving out days without any.
+func interruptionsByDay(history []session) []interruptionDay {
+	days := make(map[string]*interruptionDay)
+	for _, sess := range history {
+		key, label := report.ByDay(sess)
+		d := days[key]
+		if d == nil {
+			d = &interruptionDay{label: label}
+			days[key] = d
+		}
+		d.tracked += sess.Duration
+		for _, in := range sess.Interruptions {
+			d.interruptions = append(d.interruptions, loggedInterruption{in, sess.Project})
+		}
+	}
+	var keys []string
+	for key, d := range days {
+		if len(d.interruptions) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	var result []interruptionDay
+	for _, key := range keys {
+		d := days[key]
+		sort.SliceStable(d.interruptions, func(i, j int) bool { return d.interruptions[i].At.Before(d.interruptions[j].At) })
+		result = append(result, *d)
+	}
+	return result
+}
+
+// runInterruptions lists the interruptions logged per day with how often
+// they broke up the tracked time, to see how focused the days were.
+func runInterruptions(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("interruptions", flag.ExitOnError)
+	rangeSpec := fs.String("range", "week", "list interruptions for today, week, month, last-month or FROM..TO")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	dates, err := parseRange(*rangeSpec, now)
+	if err != nil {
+		return err
+	}
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	active, err := loadActive()
+	if err != nil {
+		return err
+	}
+	if active != nil {
+		history = append(history, active.finish(now))
+	}
+
+	total := 0
+	var tracked time.Duration
+	for _, day := range interruptionsByDay(dates.Filter(history)) {
+		n := len(day.interruptions)
+		total += n
+		tracked += day.tracked
+		fmt.Printf("%s  %d interruptions in %s tracked, one every %s\n", day.label, n, cfg.durationLong(day.tracked), cfg.durationLong(day.tracked/time.Duration(n)))
+		for _, in := range day.interruptions {
+			fmt.Printf("  %s  %-20s %s\n", in.At.Format("15:04"), truncate(track.ProjectLabel(in.project), 20), in.Note)
+		}
+	}
+	fmt.Printf("%d interruptions", total)
+	if total > 0 {
+		fmt.Printf(", one every %s tracked on the days they happened", cfg.durationLong(tracked/time.Duration(total)))
+	}
+	fmt.Println()
+	return nil
+}
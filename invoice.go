@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/report"
+	"time-tracking/pkg/track"
+)
+
+// invoiceOptions selects what goes on an invoice and how it is itemised.
+type invoiceOptions struct {
+	from, to  time.Time // sessions starting in [from, to)
+	project   string    // empty for all projects
+	clientKey string    // bill only this client's projects; empty for all
+	client    string    // shown as the "Bill to" line
+	address   string
+	number    string
+	perDay    bool     // one line per day and project rather than per session
+	rounding  rounding // per day rounding needs perDay
+}
+
+type invoiceLine struct {
+	Date        time.Time
+	Description string
+	Duration    time.Duration
+	Rate        float64
+	Amount      float64
+	Currency    string
+}
+
+type invoice struct {
+	Number  string
+	Client  string
+	Address string
+	From    time.Time
+	To      time.Time // inclusive last day, for display
+	Issued  time.Time
+	Lines   []invoiceLine
+	Total   float64
+	Hours   time.Duration
+	// Currency is the one Total is in. Converted means the lines are in
+	// several currencies and Total is in the home currency at fixed rates.
+	Currency  string
+	Converted bool
+	// NonBillable is the time tracked in the period for the same projects
+	// but not charged for.
+	NonBillable time.Duration
+}
+
+// buildInvoice collects the billable sessions matching opts into line items,
+// priced at the rates in cfg.
+func buildInvoice(history []session, cfg config, opts invoiceOptions) invoice {
+	inv := invoice{
+		Number:  opts.number,
+		Client:  opts.client,
+		Address: opts.address,
+		From:    opts.from,
+		To:      opts.to.AddDate(0, 0, -1),
+		Issued:  time.Now(),
+	}
+
+	lookup := make(map[string]int)
+	for _, sess := range history {
+		if sess.Start.Before(opts.from) || !sess.Start.Before(opts.to) {
+			continue
+		}
+		if opts.project != "" && sess.Project != opts.project {
+			continue
+		}
+		if opts.clientKey != "" && cfg.clientOf(sess.Project) != opts.clientKey {
+			continue
+		}
+		if !sess.Billable {
+			inv.NonBillable += sess.Duration
+			continue
+		}
+
+		if !opts.perDay {
+			desc := track.ProjectLabel(sess.Project)
+			if sess.Note != "" {
+				desc += ": " + sess.Note
+			}
+			inv.Lines = append(inv.Lines, invoiceLine{
+				Date:        sess.Start,
+				Description: desc,
+				Duration:    opts.rounding.round(sess.Duration),
+				Rate:        cfg.rateFor(sess.Project),
+				Currency:    cfg.currencyFor(sess.Project),
+			})
+			continue
+		}
+
+		key := sess.Start.Format(report.DateLayout) + "\x00" + sess.Project
+		i, ok := lookup[key]
+		if !ok {
+			i = len(inv.Lines)
+			lookup[key] = i
+			inv.Lines = append(inv.Lines, invoiceLine{
+				Date:        sess.Start,
+				Description: track.ProjectLabel(sess.Project),
+				Rate:        cfg.rateFor(sess.Project),
+				Currency:    cfg.currencyFor(sess.Project),
+			})
+		}
+		if opts.rounding.perSession() {
+			inv.Lines[i].Duration += opts.rounding.round(sess.Duration)
+		} else {
+			inv.Lines[i].Duration += sess.Duration
+		}
+		if sess.Note != "" {
+			if strings.Contains(inv.Lines[i].Description, ": ") {
+				inv.Lines[i].Description += "; " + sess.Note
+			} else {
+				inv.Lines[i].Description += ": " + sess.Note
+			}
+		}
+	}
+
+	inv.Currency = cfg.homeCurrency()
+	for i := range inv.Lines {
+		if i == 0 {
+			inv.Currency = inv.Lines[i].Currency
+		} else if inv.Lines[i].Currency != inv.Currency {
+			inv.Currency, inv.Converted = cfg.homeCurrency(), true
+			break
+		}
+	}
+	for i := range inv.Lines {
+		line := &inv.Lines[i]
+		if opts.rounding.perDay() {
+			line.Duration = opts.rounding.round(line.Duration)
+		}
+		line.Amount = amountFor(line.Duration, line.Rate)
+		if inv.Converted {
+			inv.Total += cfg.toHome(line.Amount, line.Currency)
+		} else {
+			inv.Total += line.Amount
+		}
+		inv.Hours += line.Duration
+	}
+	return inv
+}
+
+// roundUp rounds d up to a multiple of unit. A zero unit leaves d unchanged.
+func roundUp(d, unit time.Duration) time.Duration {
+	if unit <= 0 || d%unit == 0 {
+		return d
+	}
+	return d - d%unit + unit
+}
+
+// formatHours formats d as decimal hours, as invoices conventionally do.
+func formatHours(d time.Duration) string {
+	return fmt.Sprintf("%.2f", d.Hours())
+}
+
+// renderInvoiceText formats inv as plain text.
+func renderInvoiceText(inv invoice) string {
+	var sb strings.Builder
+
+	sb.WriteString("INVOICE")
+	if inv.Number != "" {
+		sb.WriteString(" #" + inv.Number)
+	}
+	sb.WriteString("\n\n")
+	if inv.Client != "" {
+		sb.WriteString(fmt.Sprintf("Bill to: %s\n", inv.Client))
+		for _, line := range addressLines(inv.Address) {
+			sb.WriteString(fmt.Sprintf("         %s\n", line))
+		}
+	}
+	sb.WriteString(fmt.Sprintf("Issued:  %s\n", inv.Issued.Format(report.DateLayout)))
+	sb.WriteString(fmt.Sprintf("Period:  %s – %s\n\n",
+		inv.From.Format(report.DateLayout), inv.To.Format(report.DateLayout)))
+
+	sb.WriteString(fmt.Sprintf("%-10s  %-38s %7s %9s %11s\n", "Date", "Description", "Hours", "Rate", "Amount"))
+	sb.WriteString(strings.Repeat("─", 79) + "\n")
+	if len(inv.Lines) == 0 {
+		sb.WriteString("No billable time in this period.\n")
+	}
+	for _, line := range inv.Lines {
+		desc := wrapWords(line.Description, 38)
+		if len(desc) == 0 {
+			desc = []string{""}
+		}
+		sb.WriteString(fmt.Sprintf("%-10s  %-38s %7s %9s %11s\n",
+			line.Date.Format(report.DateLayout),
+			desc[0],
+			formatHours(line.Duration),
+			formatCurrency(line.Rate, line.Currency),
+			formatCurrency(line.Amount, line.Currency),
+		))
+		for _, more := range desc[1:] {
+			sb.WriteString(fmt.Sprintf("%-10s  %s\n", "", more))
+		}
+	}
+	sb.WriteString(strings.Repeat("─", 79) + "\n")
+	sb.WriteString(fmt.Sprintf("%-50s %7s %9s %11s\n", "Total", formatHours(inv.Hours), "", formatCurrency(inv.Total, inv.Currency)))
+	if inv.Converted {
+		sb.WriteString(fmt.Sprintf("Total converted to %s at fixed exchange rates.\n", inv.Currency))
+	}
+	if inv.NonBillable > 0 {
+		sb.WriteString(fmt.Sprintf("\nNot charged: %s hours of non-billable time\n", formatHours(inv.NonBillable)))
+	}
+
+	return sb.String()
+}
+
+var invoiceHTML = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"date":  func(t time.Time) string { return t.Format(report.DateLayout) },
+	"hours": formatHours,
+	"money": formatCurrency,
+	"lines": addressLines,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice{{with .Number}} #{{.}}{{end}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 50em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-top: 1.5em; }
+th, td { padding: 0.4em 0.6em; border-bottom: 1px solid #ddd; text-align: left; }
+td.num, th.num { text-align: right; }
+tfoot td { font-weight: bold; border-top: 2px solid #222; border-bottom: none; }
+</style>
+</head>
+<body>
+<h1>Invoice{{with .Number}} #{{.}}{{end}}</h1>
+{{with .Client}}<p><strong>Bill to:</strong> {{.}}{{range lines $.Address}}<br>{{.}}{{end}}</p>{{end}}
+<p><strong>Issued:</strong> {{date .Issued}}<br>
+<strong>Period:</strong> {{date .From}} – {{date .To}}</p>
+<table>
+<thead><tr><th>Date</th><th>Description</th><th class="num">Hours</th><th class="num">Rate</th><th class="num">Amount</th></tr></thead>
+<tbody>
+{{range .Lines}}<tr><td>{{date .Date}}</td><td>{{.Description}}</td><td class="num">{{hours .Duration}}</td><td class="num">{{money .Rate .Currency}}</td><td class="num">{{money .Amount .Currency}}</td></tr>
+{{else}}<tr><td colspan="5">No billable time in this period.</td></tr>
+{{end}}</tbody>
+<tfoot><tr><td colspan="2">Total</td><td class="num">{{hours .Hours}}</td><td></td><td class="num">{{money .Total .Currency}}</td></tr></tfoot>
+</table>
+{{if .Converted}}<p>Total converted to {{.Currency}} at fixed exchange rates.</p>
+{{end}}{{if .NonBillable}}<p>Not charged: {{hours .NonBillable}} hours of non-billable time</p>
+{{end}}</body>
+</html>
+`))
+
+// addressLines splits a client address on newlines and commas.
+func addressLines(address string) []string {
+	var lines []string
+	for _, line := range strings.FieldsFunc(address, func(r rune) bool { return r == '\n' || r == ',' }) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// writeInvoiceHTML writes inv to w as a standalone HTML page.
+func writeInvoiceHTML(w io.Writer, inv invoice) error {
+	return invoiceHTML.Execute(w, inv)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// noIssueLabel labels the group of sessions not linked to an issue.
+const noIssueLabel = "(no issue)"
+
+var errNoGitRepo = errors.New("not in a git repository with an origin remote; give the issue's URL")
+
+var (
+	// issueRefPattern matches "42", "#42", "owner/repo#42" and
+	// "group/project!42".
+	issueRefPattern = regexp.MustCompile(`^(?:([\w.-]+(?:/[\w.-]+)+))?([#!])?(\d+)$`)
+	// branchNumber finds the issue number in a branch such as
+	// "42-fix-login" or "feature/gh-42".
+	branchNumber = regexp.MustCompile(`(?:^|[/_-])(\d+)(?:[/_-]|$)`)
+
+	githubIssuePath = regexp.MustCompile(`^/([^/]+/[^/]+)/(?:issues|pull)/(\d+)$`)
+	gitlabIssuePath = regexp.MustCompile(`^/(.+?)/-/issues/(\d+)$`)
+	gitlabMergePath = regexp.MustCompile(`^/(.+?)/-/merge_requests/(\d+)$`)
+)
+
+// gitRepo returns the web address of the origin remote of the git
+// repository in the working directory, e.g. "https://github.com/owner/repo",
+// and the branch checked out there.
+func gitRepo() (web, branch string, err error) {
+	remote, err := exec.Command("git", "remote", "get-url", "origin").Output()
+	if err != nil {
+		return "", "", errNoGitRepo
+	}
+	web = remoteWebURL(strings.TrimSpace(string(remote)))
+	if web == "" {
+		return "", "", errNoGitRepo
+	}
+	return web, gitBranch(), nil
+}
+
+// gitBranch returns the branch checked out in the git repository in the
+// working directory, or "" if there is none.
+func gitBranch() string {
+	head, err := exec.Command("git", "symbolic-ref", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(head))
+}
+
+// remoteWebURL turns a git remote such as "git@github.com:owner/repo.git" or
+// "https://gitlab.com/group/project.git" into the repository's web address,
+// or "" if it is not a remote on a web host.
+func remoteWebURL(remote string) string {
+	remote = strings.TrimSuffix(remote, ".git")
+	if host, path, ok := strings.Cut(remote, ":"); ok && !strings.Contains(host, "/") && !strings.HasPrefix(path, "//") {
+		// scp-like syntax: [user@]host:path
+		_, host, _ = strings.Cut(host, "@")
+		if host == "" {
+			return ""
+		}
+		return "https://" + host + "/" + strings.TrimPrefix(path, "/")
+	}
+	u, err := url.Parse(remote)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http" && u.Scheme != "ssh" && u.Scheme != "git") {
+		return ""
+	}
+	return "https://" + u.Hostname() + u.Path
+}
+
+// gitlabHost reports whether web is a repository on a GitLab server, which
+// names issues and merge requests differently from GitHub.
+func gitlabHost(web string) bool {
+	u, err := url.Parse(web)
+	return err == nil && strings.Contains(u.Hostname(), "gitlab")
+}
+
+// issueURL returns the address of issue or, with merge set, merge request n
+// of the repository at web. GitHub shows pull requests at their issue
+// address too.
+func issueURL(web string, n int, merge bool) string {
+	switch {
+	case gitlabHost(web) && merge:
+		return fmt.Sprintf("%s/-/merge_requests/%d", web, n)
+	case gitlabHost(web):
+		return fmt.Sprintf("%s/-/issues/%d", web, n)
+	}
+	return fmt.Sprintf("%s/issues/%d", web, n)
+}
+
+// parseIssue resolves ref, as given to -issue or in the TUI, to the URL of
+// an issue or pull request. ref is the URL itself, "42" or "#42" in the
+// repository of the working directory, or "owner/repo#42" or, for GitLab
+// merge requests, "group/project!42". An empty ref, or "none", means none.
+func parseIssue(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || ref == "none" {
+		return "", nil
+	}
+	if u, err := url.Parse(ref); err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" {
+		return ref, nil
+	}
+	m := issueRefPattern.FindStringSubmatch(ref)
+	if m == nil {
+		return "", fmt.Errorf("invalid issue %q (want a URL, #42 or owner/repo#42)", ref)
+	}
+	repo, sign := m[1], m[2]
+	n, _ := strconv.Atoi(m[3])
+
+	web, _, err := gitRepo()
+	if repo == "" {
+		if err != nil {
+			return "", err
+		}
+		return issueURL(web, n, sign == "!"), nil
+	}
+	// Another repository on the same host as this one's, or on the host
+	// its reference style belongs to.
+	host := "https://github.com"
+	if sign == "!" {
+		host = "https://gitlab.com"
+	}
+	if err == nil {
+		u, _ := url.Parse(web)
+		host = u.Scheme + "://" + u.Host
+	}
+	return issueURL(host+"/"+repo, n, sign == "!"), nil
+}
+
+// issueRef shortens an issue URL to "owner/repo#42" or "group/project!42"
+// where it can, for display.
+func issueRef(issue string) string {
+	u, err := url.Parse(issue)
+	if err != nil {
+		return issue
+	}
+	if m := githubIssuePath.FindStringSubmatch(u.Path); m != nil && !gitlabHost(issue) {
+		return m[1] + "#" + m[2]
+	}
+	if m := gitlabIssuePath.FindStringSubmatch(u.Path); m != nil {
+		return m[1] + "#" + m[2]
+	}
+	if m := gitlabMergePath.FindStringSubmatch(u.Path); m != nil {
+		return m[1] + "!" + m[2]
+	}
+	return issue
+}
+
+// branchIssue returns the URL of the issue the branch checked out in the
+// working directory is named after, or "" if there is none.
+func branchIssue() string {
+	web, branch, err := gitRepo()
+	if err != nil {
+		return ""
+	}
+	m := branchNumber.FindStringSubmatch(branch)
+	if m == nil {
+		return ""
+	}
+	n, _ := strconv.Atoi(m[1])
+	return issueURL(web, n, false)
+}
+
+// summarizeByIssue totals history per linked issue, largest first.
+func summarizeByIssue(history []session, cfg config) []summaryRow {
+	rows := summarize(history, cfg, func(sess session) (string, string) {
+		if sess.Issue == "" {
+			return "", noIssueLabel
+		}
+		return sess.Issue, issueRef(sess.Issue)
+	})
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].total > rows[j].total })
+	return rows
+}
+
+// renderIssueLinks lists the URLs of the issues in rows from
+// summarizeByIssue, to follow from the totals to the work items.
+func renderIssueLinks(rows []summaryRow) string {
+	var sb strings.Builder
+	for _, row := range rows {
+		if row.key != "" {
+			sb.WriteString(fmt.Sprintf("%-30s %s\n", truncate(row.label, 30), row.key))
+		}
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "\n" + sb.String()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+
+	"time-tracking/pkg/track"
+)
+
+// journalFile holds sessions that have been stopped but may not have reached
+// storage yet. They are written there before the running session is
+// cleared, so a crash or kill part way through a stop still records them the
+// next time storage is opened.
+var journalFile = "journal.json"
+
+// journal adds sessions to journalFile.
+func journal(sessions []session) error {
+	recs, err := loadJournal()
+	if err != nil {
+		return err
+	}
+	for _, sess := range sessions {
+		recs = append(recs, track.ToRecord(sess))
+	}
+	data, err := json.MarshalIndent(recs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeData(journalFile, data)
+}
+
+func loadJournal() ([]track.Record, error) {
+	data, err := readData(journalFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var recs []track.Record
+	if err := json.Unmarshal(data, &recs); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", journalFile, err)
+	}
+	return recs, nil
+}
+
+// clearJournal removes journalFile once its sessions are in storage.
+func clearJournal() error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	err := os.Remove(journalFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// replayJournal appends the sessions left in journalFile by an interrupted
+// stop to storage, skipping any that made it there before the interruption.
+func replayJournal(storage Storage) error {
+	recs, err := loadJournal()
+	if err != nil || len(recs) == 0 {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		sess := track.FromRecord(rec)
+		if slices.ContainsFunc(history, func(other session) bool { return other.ID == sess.ID }) {
+			continue
+		}
+		if err := storage.Append(sess); err != nil {
+			return err
+		}
+	}
+	return clearJournal()
+}
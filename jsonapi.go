@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var apiClient = &http.Client{Timeout: 30 * time.Second}
+
+// jsonAPI is a JSON-over-HTTP API of a service time-tracker syncs with.
+// Clients embed it for its do method.
+type jsonAPI struct {
+	// name starts error messages, e.g. "toggl".
+	name string
+	// base is prepended to the paths of requests.
+	base string
+	// auth adds the credentials to a request.
+	auth func(req *http.Request) error
+}
+
+// do sends a request to the API and decodes the JSON response into out, if
+// out is non-nil.
+func (a jsonAPI) do(method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, a.base+path, r)
+	if err != nil {
+		return err
+	}
+	if err := a.auth(req); err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", a.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s %s: %s: %s", a.name, method, path, resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// keyMap holds every remappable key binding. ctrl+c always quits and the
+// keys inside text prompts (enter, esc) are fixed.
+type keyMap struct {
+	Up     key.Binding
+	Down   key.Binding
+	Select key.Binding
+	Back   key.Binding
+	Quit   key.Binding
+	Save   key.Binding
+	Help   key.Binding
+
+	Start     key.Binding
+	Resume    key.Binding
+	Stop      key.Binding
+	Pause     key.Binding
+	Countdown key.Binding
+	EditNote  key.Binding
+	AddNote   key.Binding
+	EditTags  key.Binding
+	EditIssue key.Binding
+	Billable  key.Binding
+	Interrupt key.Binding
+	Snooze    key.Binding
+
+	PageUp      key.Binding
+	PageDown    key.Binding
+	Top         key.Binding
+	Bottom      key.Binding
+	Search      key.Binding
+	NextMatch   key.Binding
+	PrevMatch   key.Binding
+	FilterTag   key.Binding
+	DateRange   key.Binding
+	CustomRange key.Binding
+	Export      key.Binding
+	Delete      key.Binding
+	ClearAll    key.Binding
+	Undo        key.Binding
+	Resolve     key.Binding
+	Split       key.Binding
+	Mark        key.Binding
+	Merge       key.Binding
+	Project     key.Binding
+	Collapse    key.Binding
+	CollapseAll key.Binding
+
+	NextMode key.Binding
+	PrevMode key.Binding
+	Toggle   key.Binding
+}
+
+// binding builds a key.Binding whose help text lists its keys.
+func binding(desc string, keys ...string) key.Binding {
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(keyLabels(keys), desc))
+}
+
+func defaultKeyMap() keyMap {
+	return keyMap{
+		Up:     binding("up", "up", "k"),
+		Down:   binding("down", "down", "j"),
+		Select: binding("select", "enter"),
+		Back:   binding("back", "esc", "b"),
+		Quit:   binding("quit", "q"),
+		Save:   binding("save", "ctrl+s"),
+		Help:   binding("help", "?"),
+
+		Start:     binding("start tracking", "s"),
+		Resume:    binding("resume last", "r"),
+		Stop:      binding("stop", "enter", "s"),
+		Pause:     binding("pause/resume", "p"),
+		Countdown: binding("countdown", "c"),
+		EditNote:  binding("edit note", "e"),
+		AddNote:   binding("add to note", "n"),
+		EditTags:  binding("edit tags", "t"),
+		EditIssue: binding("link issue", "i"),
+		Billable:  binding("billable", "$"),
+		Interrupt: binding("log interruption", "!"),
+		Snooze:    binding("snooze break reminder", "z"),
+
+		PageUp:      binding("page up", "pgup", "ctrl+u"),
+		PageDown:    binding("page down", "pgdown", "ctrl+d"),
+		Top:         binding("first", "home", "g"),
+		Bottom:      binding("last", "end", "G"),
+		Search:      binding("search", "/"),
+		NextMatch:   binding("next match", "n"),
+		PrevMatch:   binding("previous match", "N"),
+		FilterTag:   binding("filter by tag", "f"),
+		DateRange:   binding("date range", "r"),
+		CustomRange: binding("custom date range", "R"),
+		Export:      binding("export CSV", "x"),
+		Delete:      binding("delete", "d", "backspace"),
+		ClearAll:    binding("clear all history", "C"),
+		Undo:        binding("undo", "u"),
+		Resolve:     binding("resolve overlap", "o"),
+		Split:       binding("split session", "S"),
+		Mark:        binding("mark", " "),
+		Merge:       binding("merge marked", "M"),
+		Project:     binding("change project", "P"),
+		Collapse:    binding("collapse/expand group", "enter"),
+		CollapseAll: binding("collapse/expand all", "z"),
+
+		NextMode: binding("next mode", "tab", "right", "l"),
+		PrevMode: binding("previous mode", "shift+tab", "left", "h"),
+		Toggle:   binding("toggle", "enter", " "),
+	}
+}
+
+// byName maps the names used under "keys" in config.json to the bindings.
+func (k *keyMap) byName() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up":           &k.Up,
+		"down":         &k.Down,
+		"select":       &k.Select,
+		"back":         &k.Back,
+		"quit":         &k.Quit,
+		"save":         &k.Save,
+		"help":         &k.Help,
+		"start":        &k.Start,
+		"resume":       &k.Resume,
+		"stop":         &k.Stop,
+		"pause":        &k.Pause,
+		"countdown":    &k.Countdown,
+		"edit_note":    &k.EditNote,
+		"add_note":     &k.AddNote,
+		"edit_tags":    &k.EditTags,
+		"edit_issue":   &k.EditIssue,
+		"billable":     &k.Billable,
+		"interrupt":    &k.Interrupt,
+		"snooze":       &k.Snooze,
+		"page_up":      &k.PageUp,
+		"page_down":    &k.PageDown,
+		"top":          &k.Top,
+		"bottom":       &k.Bottom,
+		"search":       &k.Search,
+		"next_match":   &k.NextMatch,
+		"prev_match":   &k.PrevMatch,
+		"filter_tag":   &k.FilterTag,
+		"date_range":   &k.DateRange,
+		"custom_range": &k.CustomRange,
+		"export":       &k.Export,
+		"delete":       &k.Delete,
+		"clear_all":    &k.ClearAll,
+		"undo":         &k.Undo,
+		"resolve":      &k.Resolve,
+		"split":        &k.Split,
+		"mark":         &k.Mark,
+		"merge":        &k.Merge,
+		"project":      &k.Project,
+		"collapse":     &k.Collapse,
+		"collapse_all": &k.CollapseAll,
+		"next_mode":    &k.NextMode,
+		"prev_mode":    &k.PrevMode,
+		"toggle":       &k.Toggle,
+	}
+}
+
+// newKeyMap returns the default bindings with overrides applied. Each
+// override replaces all keys of the named binding; an empty list disables it.
+func newKeyMap(overrides map[string][]string) (keyMap, error) {
+	k := defaultKeyMap()
+	bindings := k.byName()
+	for name, keys := range overrides {
+		b, ok := bindings[name]
+		if !ok {
+			names := make([]string, 0, len(bindings))
+			for n := range bindings {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return k, fmt.Errorf("unknown key binding %q (want one of %s)", name, strings.Join(names, ", "))
+		}
+		b.SetKeys(keys...)
+		b.SetHelp(keyLabels(keys), b.Help().Desc)
+		b.SetEnabled(len(keys) > 0)
+	}
+	return k, nil
+}
+
+// keyLabels renders keys for help text, using arrows for the arrow keys.
+func keyLabels(keys []string) string {
+	labels := make([]string, len(keys))
+	for i, k := range keys {
+		switch k {
+		case "up":
+			labels[i] = "↑"
+		case "down":
+			labels[i] = "↓"
+		case "left":
+			labels[i] = "←"
+		case "right":
+			labels[i] = "→"
+		case "pgdown":
+			labels[i] = "pgdn"
+		case " ":
+			labels[i] = "space"
+		default:
+			labels[i] = k
+		}
+	}
+	return strings.Join(labels, "/")
+}
+
+// pairHelp is a help-only binding describing two bindings at once, e.g.
+// "↑/↓: navigate", using the first key of each.
+func pairHelp(a, b key.Binding, desc string) key.Binding {
+	if !a.Enabled() || !b.Enabled() {
+		return key.NewBinding(key.WithDisabled())
+	}
+	return key.NewBinding(
+		key.WithKeys(a.Keys()[0], b.Keys()[0]),
+		key.WithHelp(keyLabels(a.Keys()[:1])+"/"+keyLabels(b.Keys()[:1]), desc),
+	)
+}
+
+// withDesc is b described as desc, for views where its usual description
+// would not say what it does there.
+func withDesc(b key.Binding, desc string) key.Binding {
+	if !b.Enabled() {
+		return b
+	}
+	return key.NewBinding(key.WithKeys(b.Keys()...), key.WithHelp(b.Help().Key, desc))
+}
+
+// helpLine renders bindings as the "key: description • ..." help line shown
+// at the bottom of each view, skipping disabled ones.
+func helpLine(bindings ...key.Binding) string {
+	var parts []string
+	for _, b := range bindings {
+		if !b.Enabled() {
+			continue
+		}
+		parts = append(parts, b.Help().Key+": "+b.Help().Desc)
+	}
+	return helpStyle.Render(strings.Join(parts, " • "))
+}
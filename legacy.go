@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// loadLegacyHistory parses sessions back out of a report written by
+// renderReport. Reports were the only storage format before sessions.json and
+// are read once to migrate existing data.
+func loadLegacyHistory(path string) ([]session, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var history []session
+	scanner := bufio.NewScanner(file)
+
+	var currentSession *session
+	var projectStr, dateStr, startStr, endStr string
+	var noteLines, pauseStrs []string
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.Contains(line, "SESSION #") {
+			currentSession = &session{ID: track.NewID(), Billable: true}
+			projectStr, dateStr, startStr, endStr = "", "", "", ""
+			noteLines, pauseStrs = nil, nil
+		}
+
+		if strings.Contains(line, "Note:") {
+			parts := strings.SplitN(line, "Note:", 2)
+			if len(parts) == 2 {
+				noteLines = append(noteLines, strings.TrimSpace(strings.Split(parts[1], "│")[0]))
+			}
+			continue
+		}
+
+		if strings.Contains(line, "Pause:") {
+			parts := strings.SplitN(line, "Pause:", 2)
+			if len(parts) == 2 {
+				pauseStrs = append(pauseStrs, strings.TrimSpace(strings.Split(parts[1], "│")[0]))
+			}
+			continue
+		}
+
+		if strings.Contains(line, "Billable:") {
+			if currentSession != nil && strings.Contains(line, "no") {
+				currentSession.Billable = false
+			}
+			continue
+		}
+
+		if strings.Contains(line, "Project:") {
+			parts := strings.SplitN(line, "Project:", 2)
+			if len(parts) == 2 {
+				projectStr = strings.TrimSpace(strings.Split(parts[1], "│")[0])
+			}
+		}
+
+		if strings.Contains(line, "Date:") {
+			parts := strings.SplitN(line, "Date:", 2)
+			if len(parts) == 2 {
+				dateStr = strings.TrimSpace(strings.Split(parts[1], "│")[0])
+			}
+		}
+
+		if strings.Contains(line, "Start:") && !strings.Contains(line, "──") {
+			parts := strings.SplitN(line, "Start:", 2)
+			if len(parts) == 2 {
+				startStr = strings.TrimSpace(strings.Split(parts[1], "│")[0])
+			}
+		}
+
+		if strings.Contains(line, "End:") {
+			parts := strings.SplitN(line, "End:", 2)
+			if len(parts) == 2 {
+				endStr = strings.TrimSpace(strings.Split(parts[1], "│")[0])
+			}
+		}
+
+		if strings.Contains(line, "└──") && currentSession != nil && dateStr != "" && startStr != "" && endStr != "" {
+			startTime, err1 := time.ParseInLocation("Monday, January 02, 2006 03:04:05 PM", dateStr+" "+startStr, time.Local)
+			endTime, err2 := time.ParseInLocation("Monday, January 02, 2006 03:04:05 PM", dateStr+" "+endStr, time.Local)
+
+			if err1 == nil && err2 == nil {
+				currentSession.Project = projectStr
+				currentSession.Note = strings.Join(noteLines, " ")
+				currentSession.Start = startTime
+				currentSession.End = endTime
+				for _, ps := range pauseStrs {
+					from, to, ok := strings.Cut(ps, " - ")
+					if !ok {
+						continue
+					}
+					pauseStart, err1 := time.ParseInLocation("Monday, January 02, 2006 03:04:05 PM", dateStr+" "+from, time.Local)
+					pauseEnd, err2 := time.ParseInLocation("Monday, January 02, 2006 03:04:05 PM", dateStr+" "+to, time.Local)
+					if err1 == nil && err2 == nil {
+						currentSession.Pauses = append(currentSession.Pauses, pause{Start: pauseStart, End: pauseEnd})
+					}
+				}
+				currentSession.Duration = endTime.Sub(startTime) - track.PausedTotal(currentSession.Pauses, endTime)
+				history = append(history, *currentSession)
+			}
+			currentSession = nil
+		}
+	}
+
+	return history, scanner.Err()
+}
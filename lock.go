@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// lockFile is held by the TUI while it has the history open, so a second
+// instance can tell and open it read-only rather than overwrite it.
+var lockFile = "time-tracker.lock"
+
+var errLocked = errors.New("history is locked")
+
+// lockedError is returned by lockHistory when another process holds
+// lockFile.
+type lockedError struct {
+	pid string // as written to lockFile by the holder, possibly empty
+}
+
+func (e lockedError) Error() string {
+	if e.pid == "" {
+		return "another time-tracker already has the history open"
+	}
+	return fmt.Sprintf("another time-tracker (pid %s) already has the history open", e.pid)
+}
+
+func (e lockedError) Is(target error) bool {
+	return target == errLocked
+}
+
+// heldLock is lockFile while this process holds it.
+var heldLock *os.File
+
+// lockHistory takes lockFile for this process until it exits, recording the
+// pid in it for the message other instances show.
+func lockHistory() error {
+	f, err := os.OpenFile(lockFile, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if err := tryLock(f); err != nil {
+		f.Close()
+		if errors.Is(err, errLocked) {
+			pid, _ := os.ReadFile(lockFile)
+			return lockedError{pid: strings.TrimSpace(string(pid))}
+		}
+		return err
+	}
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	heldLock = f
+	return nil
+}
+
+// unlockHistory gives up lockFile, e.g. before switching profiles.
+func unlockHistory() {
+	if heldLock != nil {
+		unlock(heldLock)
+		heldLock.Close()
+		heldLock = nil
+	}
+}
+
+// lockedElsewhere reports whether another process holds lockFile.
+func lockedElsewhere() bool {
+	if heldLock != nil {
+		return false
+	}
+	f, err := os.Open(lockFile)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	if err := tryLock(f); err != nil {
+		return errors.Is(err, errLocked)
+	}
+	unlock(f)
+	return false
+}
+
+// askReadOnly tells the user another instance has the history open and asks
+// whether to attach to it read-only.
+func askReadOnly(locked lockedError) bool {
+	who := "Another time-tracker"
+	if locked.pid != "" {
+		who += " (pid " + locked.pid + ")"
+	}
+	fmt.Printf("%s is already running with this history.\n", who)
+	fmt.Print("Attach read-only? You can watch the running session and browse the history, but not change them. [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// blockedReadOnly reports whether a change has to be refused because the
+// history is open read-only, saying so in the status line.
+func (m *model) blockedReadOnly() bool {
+	if m.readOnly {
+		m.status = "Read-only: " + readOnlyReason
+	}
+	return m.readOnly
+}
+
+// viewReadOnly is the indicator shown under every view in read-only mode.
+func (m model) viewReadOnly() string {
+	if !m.readOnly {
+		return ""
+	}
+	return "\n\n" + warningStyle.Render("🔒 Read-only")
+}
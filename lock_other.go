@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package main
+
+import "os"
+
+// tryLock always succeeds: there is no file locking on this platform, so
+// instances are not kept from overwriting each other.
+func tryLock(f *os.File) error {
+	return nil
+}
+
+func unlock(f *os.File) error {
+	return nil
+}
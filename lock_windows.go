@@ -0,0 +1,35 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockOffset is where the locked byte lies, well past the pid at the start
+// of the file: Windows locks are mandatory, and other instances still need
+// to read that.
+const lockOffset = 1 << 62
+
+// tryLock takes an exclusive lock on f without waiting, returning errLocked
+// if another process has it. Windows drops the lock when the process exits,
+// however that happens.
+func tryLock(f *os.File) error {
+	err := windows.LockFileEx(windows.Handle(f.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, lockRange())
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) || errors.Is(err, windows.ERROR_IO_PENDING) {
+		return errLocked
+	}
+	return err
+}
+
+func unlock(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, lockRange())
+}
+
+func lockRange() *windows.Overlapped {
+	return &windows.Overlapped{OffsetHigh: lockOffset >> 32}
+}
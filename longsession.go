@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// longSession is how long a session may run before it is flagged as
+// possibly forgotten, or 0 if long_session is not set.
+func (c config) longSession() time.Duration {
+	// loadConfig has checked that LongSession parses.
+	d, _ := time.ParseDuration(c.LongSession)
+	return d
+}
+
+func checkLongSession(s string) error {
+	if s == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(s); err != nil || d <= 0 {
+		return fmt.Errorf("invalid long_session %q (want e.g. 4h)", s)
+	}
+	return nil
+}
+
+// crossedLongSession reports whether a session that had tracked before and
+// now has tracked elapsed just went past the long_session limit.
+func (c config) crossedLongSession(before, elapsed time.Duration) bool {
+	limit := c.longSession()
+	return limit > 0 && before < limit && elapsed >= limit
+}
+
+func longSessionMessage(project string, elapsed time.Duration, cfg config) string {
+	return fmt.Sprintf("%s has been running for %s. Forgot to stop the timer?", track.ProjectLabel(project), cfg.durationLong(elapsed))
+}
+
+// longSessionAlert returns a notification command when the running session
+// goes past the long_session limit. With a daemon running the daemon sends
+// the notification instead.
+func (m model) longSessionAlert(before time.Duration) tea.Cmd {
+	if m.active == nil || !m.config.crossedLongSession(before, m.elapsed) {
+		return nil
+	}
+	if daemonRunning() {
+		return m.alertCmd()
+	}
+	return tea.Batch(m.alertCmd(), m.notifyCmd("Long session", longSessionMessage(m.active.project, m.elapsed, m.config)))
+}
+
+// viewLongSession warns that the running session has gone past the
+// long_session limit, or renders nothing.
+func (m model) viewLongSession() string {
+	if m.active == nil || m.config.longSession() <= 0 || m.elapsed < m.config.longSession() {
+		return ""
+	}
+	return warningStyle.Render("⚠ "+longSessionMessage(m.active.project, m.elapsed, m.config)) + "\n\n"
+}
@@ -1,44 +1,36 @@
 package main
 
 import (
-	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-)
 
-const historyFile = "history.txt"
+	"time-tracking/pkg/track"
+)
 
+// Files in the data directory; useDataDir turns them into paths there.
 var (
-	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("205")).
-			MarginBottom(1)
-
-	selectedStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("86")).
-			Bold(true)
-
-	normalStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("252"))
-
-	timerStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("212")).
-			Background(lipgloss.Color("236")).
-			Padding(0, 1)
-
-	historyItemStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("243"))
-
-	helpStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("241"))
+	dataFile    = "sessions.json"
+	sqliteFile  = "sessions.db"
+	activeFile  = "active.json"
+	configFile  = "config.json"
+	historyFile = "history.txt"
+	auditFile   = "audit.jsonl"
+	trashFile   = "trash.json"
+	socketFile  = "time-tracker.sock"
 )
 
+const noClientLabel = "(no client)"
+
 type view int
 
 const (
@@ -46,67 +38,260 @@ const (
 	trackingView
 	historyView
 	settingsView
+	projectView
+	summaryView
+	heatmapView
+	timelineView
+	gapsView
+	auditView
+	trashView
+	profilesView
+	statsView
+	todayView
 )
 
 type tickMsg time.Time
 
-type session struct {
-	start    time.Time
-	end      time.Time
-	duration time.Duration
-}
-
 type model struct {
 	currentView    view
 	cursor         int
 	menuItems      []string
-	tracking       bool
-	trackingStart  time.Time
+	active         *activeSession // nil when not tracking
 	elapsed        time.Duration
 	history        []session
+	storage        Storage
 	settingsCursor int
+	config         config
 	settings       map[string]bool
+	projectInput   textinput.Model
+	projectCursor  int
+	editInput      textinput.Model
+	editing        editField
+	editTarget     int             // index into history, or -1 for the running session
+	splitAt        time.Time       // where the session at editTarget is being split
+	interruptedAt  time.Time       // when the interruption being logged happened
+	marked         map[string]bool // sessions marked in history, by ID
+	collapsed      map[string]bool // history groups collapsed, by historyGroup.key
+	tagFilter      string
+	rangeFilter    string // date range spec for parseRange, "" for all time
+	searchInput    textinput.Model
+	searching      bool   // the search prompt is open
+	search         string // current search query, kept for n/N after enter
+	searchStart    int    // cursor position when the search began
+	summaryMode    summaryMode
+	historySort    historySort
+	heatmapPeriod  int       // index into heatmapPeriods
+	timelineDay    time.Time // day shown in the timeline, zero for today
+	gapRange       int       // index into gapRanges
+	gapCursor      int
+	filling        *gap // gap being filled from the project prompt
+	status         string
+	pending        *pendingDelete
+	deleteSeq      int
+	remindersSent  int           // trackingReminderEvery milestones already notified
+	breakSnoozed   time.Time     // break reminders are held off until then
+	breakNotified  time.Time     // when the last break notification was sent
+	startReminded  time.Time     // when the last reminder to start tracking was sent
+	width, height  int           // terminal size, 0 until the first WindowSizeMsg
+	historyOffset  int           // first history line shown when the list scrolls
+	unaudited      []auditEntry  // changes not yet written to auditFile
+	auditEntries   []auditEntry  // audit log shown in the audit view
+	auditOffset    int           // first audit line shown
+	untrashed      []trashRecord // deleted sessions not yet written to trashFile
+	restored       []trashRecord // sessions restored but not yet out of trashFile
+	trash          []trashRecord // trash shown in the trash view, newest first
+	trashCursor    int
+	profiles       []string // profiles shown in the profiles view
+	profileCursor  int
+
+	idleAfter       time.Duration // 0 disables idle detection
+	idleUnsupported bool
+	lastIdleCheck   time.Time
+	lastTick        time.Time // when the running session's timer last ticked
+	idlePaused      bool      // the open pause was started by idle detection
+	idlePrompt      bool
+
+	keys     keyMap
+	showHelp bool
+	dirty    bool // history has changes not yet written (Auto-save off)
+	readOnly bool // another instance has the history open; nothing is written
+
+	storageKind string // backend of storage, to open another profile's with
+
+	stored []session // history as last loaded from storage, to merge changes made elsewhere
+	stamp  fileStamp // of the storage file when stored was loaded
+	dialog *confirmDialog
 }
 
-func initialModel() model {
+func initialModel(cfg config, storage Storage, history []session, active *activeSession) model {
+	input := textinput.New()
+	input.Placeholder = "Project name"
+	input.CharLimit = 64
+
+	edit := textinput.New()
+	edit.CharLimit = 256
+	edit.Width = 50
+
+	// The overrides were checked by loadConfig.
+	keys, _ := newKeyMap(cfg.Keys)
+
+	search := textinput.New()
+	search.Prompt = "/"
+	search.CharLimit = 64
+
 	return model{
 		currentView: menuView,
 		menuItems: []string{
 			"Start tracking",
 			"Stop tracking",
+			"Today",
 			"View history",
+			"Summary",
+			"Stats",
+			"Heatmap",
+			"Timeline",
+			"Gaps",
+			"Trash",
+			"Audit log",
+			"Profiles",
 			"Settings",
 			"Quit",
 		},
-		history: loadHistory(),
-		settings: map[string]bool{
-			"Show seconds":    true,
-			"Auto-save":       true,
-			"Notifications":   false,
-			"Dark mode":       true,
-		},
+		history:      history,
+		storage:      storage,
+		active:       active,
+		projectInput: input,
+		editInput:    edit,
+		searchInput:  search,
+		keys:         keys,
+		config:       cfg,
+		settings:     cfg.Settings,
+		stored:       slices.Clone(history),
+		stamp:        storageStamp(storage),
 	}
 }
 
-func tickCmd() tea.Cmd {
-	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+// tickCmd schedules the next timer update: every second, or only when the
+// elapsed time reaches the next whole minute if seconds are hidden.
+func (m model) tickCmd() tea.Cmd {
+	interval := time.Second
+	if !m.settings[settingShowSeconds] {
+		interval = time.Minute - m.elapsed%time.Minute
+	}
+	if m.active != nil && m.active.target > m.elapsed {
+		// Wake up in time to sound the countdown alert.
+		interval = min(interval, m.active.target-m.elapsed)
+	}
+	return tea.Tick(interval, func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	if m.active != nil {
+		// Resume a session left running by a previous run or the CLI.
+		return tea.Batch(m.tickCmd(), m.reloadCmd())
+	}
+	return m.reloadCmd()
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tickMsg:
-		if m.tracking {
-			m.elapsed = time.Since(m.trackingStart)
-			return m, tickCmd()
+		if m.active != nil {
+			m.syncActive()
+		}
+		if m.active != nil && m.readOnly {
+			// Only follow the session; the instance holding the history
+			// handles sleep, midnight and alerts.
+			if !m.active.paused() {
+				m.elapsed = m.active.elapsed(time.Now())
+			}
+			return m, m.tickCmd()
+		}
+		if m.active != nil {
+			now := time.Now()
+			m.checkSleep(now)
+			if cmd := m.rollOverMidnight(now); cmd != nil {
+				if m.active == nil {
+					return m, cmd
+				}
+				return m, tea.Batch(cmd, m.tickCmd())
+			}
+			before := m.elapsed
+			if !m.active.paused() {
+				m.elapsed = m.active.elapsed(now)
+			}
+			return m, tea.Batch(m.tickCmd(), m.idleCheck(now), m.trackingReminder(), m.breakReminder(), m.thresholdWebhooks(before), m.goalReminder(before),
+				m.countdownAlert(before), m.longSessionAlert(before), m.budgetAlert(before))
 		}
 
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.scrollHistory()
+		return m, nil
+
+	case idleMsg:
+		return m.handleIdle(msg)
+
+	case webhookErrMsg:
+		m.status = msg.err.Error()
+		return m, nil
+
+	case hookErrMsg:
+		m.status = msg.err.Error()
+		return m, nil
+
+	case backupErrMsg:
+		m.status = msg.err.Error()
+		return m, nil
+
+	case reloadMsg:
+		m.reload()
+		return m, tea.Batch(m.reloadCmd(), m.startReminderAlert())
+
+	case flushDeleteMsg:
+		if m.pending != nil && msg.seq == m.deleteSeq {
+			m.flushDelete()
+			m.status = ""
+		}
+		return m, nil
+
 	case tea.KeyMsg:
+		if m.dialog != nil {
+			return m.updateConfirm(msg)
+		}
+		if msg.String() == "ctrl+c" {
+			return m.quit()
+		}
+		if m.idlePaused && !m.idlePrompt {
+			// Any key means the user is back; ask about the idle time
+			// before doing anything else.
+			m.idlePrompt = true
+			return m, nil
+		}
+		if m.idlePrompt {
+			return m.updateIdlePrompt(msg)
+		}
+
+		if m.editing != editNone {
+			return m.updateEdit(msg)
+		}
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+		if m.showHelp {
+			return m.updateHelp(msg)
+		}
+		if key.Matches(msg, m.keys.Save) {
+			m.saveNow()
+			return m, nil
+		}
+		if key.Matches(msg, m.keys.Help) && m.currentView != projectView {
+			m.showHelp = true
+			return m, nil
+		}
+
 		switch m.currentView {
 		case menuView:
 			return m.updateMenu(msg)
@@ -116,156 +301,883 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateHistory(msg)
 		case settingsView:
 			return m.updateSettings(msg)
+		case projectView:
+			return m.updateProject(msg)
+		case summaryView:
+			return m.updateSummary(msg)
+		case statsView:
+			return m.updateStats(msg)
+		case todayView:
+			return m.updateToday(msg)
+		case heatmapView:
+			return m.updateHeatmap(msg)
+		case timelineView:
+			return m.updateTimeline(msg)
+		case gapsView:
+			return m.updateGaps(msg)
+		case auditView:
+			return m.updateAudit(msg)
+		case trashView:
+			return m.updateTrash(msg)
+		case profilesView:
+			return m.updateProfiles(msg)
 		}
 	}
 
+	if m.currentView == projectView {
+		var cmd tea.Cmd
+		m.projectInput, cmd = m.projectInput.Update(msg)
+		return m, cmd
+	}
+
+	if m.editing != editNone {
+		var cmd tea.Cmd
+		m.editInput, cmd = m.editInput.Update(msg)
+		return m, cmd
+	}
+
+	if m.searching {
+		var cmd tea.Cmd
+		m.searchInput, cmd = m.searchInput.Update(msg)
+		return m, cmd
+	}
+
 	return m, nil
 }
 
 func (m model) updateMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "ctrl+c", "q":
-		return m, tea.Quit
-	case "up", "k":
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Up):
 		if m.cursor > 0 {
 			m.cursor--
 		}
-	case "down", "j":
+	case key.Matches(msg, m.keys.Down):
 		if m.cursor < len(m.menuItems)-1 {
 			m.cursor++
 		}
-	case "enter":
-		switch m.cursor {
-		case 0: // Start tracking
-			if !m.tracking {
-				m.tracking = true
-				m.trackingStart = time.Now()
-				m.elapsed = 0
-				m.currentView = trackingView
-				return m, tickCmd()
-			}
-		case 1: // Stop tracking
-			if m.tracking {
-				m.tracking = false
-				m.history = append(m.history, session{
-					start:    m.trackingStart,
-					end:      time.Now(),
-					duration: m.elapsed,
-				})
-				m.elapsed = 0
-				saveHistory(m.history)
-			}
-		case 2: // View history
+	case key.Matches(msg, m.keys.Start):
+		if m.blockedReadOnly() {
+			break
+		}
+		return m.openStart()
+	case key.Matches(msg, m.keys.Resume):
+		if m.blockedReadOnly() {
+			break
+		}
+		return m.resumeLast()
+	case key.Matches(msg, m.keys.Snooze):
+		m.snoozeBreak()
+	case key.Matches(msg, m.keys.Select):
+		switch m.menuItems[m.cursor] {
+		case "Start tracking":
+			if m.active == nil && m.blockedReadOnly() {
+				break
+			}
+			return m.openStart()
+		case "Stop tracking":
+			if m.active != nil && !m.blockedReadOnly() {
+				return m, m.stopTracking()
+			}
+		case "Today":
+			m.currentView = todayView
+		case "View history":
 			m.currentView = historyView
 			m.cursor = 0
-		case 3: // Settings
+		case "Summary":
+			m.currentView = summaryView
+			m.summaryMode = summaryByDay
+		case "Stats":
+			m.currentView = statsView
+		case "Heatmap":
+			m.currentView = heatmapView
+		case "Timeline":
+			m.currentView = timelineView
+			m.timelineDay = time.Time{}
+		case "Gaps":
+			m.currentView = gapsView
+			m.gapCursor = 0
+		case "Trash":
+			return m.openTrash()
+		case "Audit log":
+			return m.openAudit()
+		case "Profiles":
+			return m.openProfiles()
+		case "Settings":
 			m.currentView = settingsView
 			m.settingsCursor = 0
-		case 4: // Quit
-			return m, tea.Quit
+		case "Quit":
+			return m.quit()
 		}
 	}
 	return m, nil
 }
 
+// openStart shows the project prompt for a new session, or the running
+// session if there already is one.
+func (m model) openStart() (tea.Model, tea.Cmd) {
+	if m.active != nil {
+		m.currentView = trackingView
+		return m, nil
+	}
+	m.currentView = projectView
+	m.projectCursor = -1
+	m.projectInput.SetValue("")
+	return m, m.projectInput.Focus()
+}
+
+// lastSession returns the most recently started session in history.
+func (m model) lastSession() (session, bool) {
+	if len(m.history) == 0 {
+		return session{}, false
+	}
+	last := m.history[0]
+	for _, sess := range m.history[1:] {
+		if sess.Start.After(last.Start) {
+			last = sess
+		}
+	}
+	return last, true
+}
+
+// resumeLast starts a new session with the project, tags, note and billing of
+// the most recent one, or shows the running session if there is one.
+func (m model) resumeLast() (tea.Model, tea.Cmd) {
+	if m.active != nil {
+		m.currentView = trackingView
+		return m, nil
+	}
+	last, ok := m.lastSession()
+	if !ok {
+		return m, nil
+	}
+	m.startTracking(activeSession{
+		project:  last.Project,
+		note:     last.Note,
+		tags:     slices.Clone(last.Tags),
+		billable: last.Billable,
+		issue:    last.Issue,
+	})
+	m.currentView = trackingView
+	return m, m.startedCmd()
+}
+
 func (m model) updateTracking(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "ctrl+c", "q":
-		return m, tea.Quit
-	case "esc", "b":
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Back):
 		m.currentView = menuView
 		return m, nil
-	case "enter", "s":
-		if m.tracking {
-			m.tracking = false
-			m.history = append(m.history, session{
-				start:    m.trackingStart,
-				end:      time.Now(),
-				duration: m.elapsed,
-			})
-			m.elapsed = 0
+	case key.Matches(msg, m.keys.Snooze) && m.snoozeBreak():
+		return m, nil
+	case m.blockedReadOnly():
+		return m, nil
+	case key.Matches(msg, m.keys.EditNote):
+		if m.active != nil {
+			return m.startEdit(editNote, -1)
+		}
+	case key.Matches(msg, m.keys.AddNote):
+		if m.active != nil {
+			return m.startEdit(editAddNote, -1)
+		}
+	case key.Matches(msg, m.keys.EditTags):
+		if m.active != nil {
+			return m.startEdit(editTags, -1)
+		}
+	case key.Matches(msg, m.keys.EditIssue):
+		if m.active != nil {
+			return m.startEdit(editIssue, -1)
+		}
+	case key.Matches(msg, m.keys.Interrupt):
+		if m.active != nil {
+			return m.startEdit(editInterruption, -1)
+		}
+	case key.Matches(msg, m.keys.Stop):
+		if m.active != nil {
 			m.currentView = menuView
-			saveHistory(m.history)
+			return m, m.stopTracking()
+		}
+		return m, nil
+	case key.Matches(msg, m.keys.Pause):
+		if m.active != nil {
+			return m, m.togglePause()
+		}
+		return m, nil
+	case key.Matches(msg, m.keys.Billable):
+		if m.active != nil {
+			m.active.billable = !m.active.billable
+			saveActive(*m.active)
+		}
+		return m, nil
+	case key.Matches(msg, m.keys.Countdown):
+		if m.active != nil {
+			return m.startCountdownEdit()
 		}
 		return m, nil
 	}
-	return m, tickCmd()
+	// The tick loop started with the session keeps running; starting another
+	// here would redraw more often than the timer changes.
+	return m, nil
 }
 
-func (m model) updateHistory(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+// save persists the whole history and regenerates the report.
+func (m *model) save() error {
+	if m.readOnly {
+		return errors.New("the history is open read-only")
+	}
+	// The full save already leaves out any session pending deletion.
+	if m.pending != nil {
+		m.deleted(auditDelete, []session{m.pending.sess})
+		m.pending = nil
+	}
+	if err := m.storage.Save(m.history); err != nil {
+		return err
+	}
+	m.dirty = false
+	if err := clearJournal(); err != nil {
+		return err
+	}
+	if err := m.flushTrash(); err != nil {
+		return err
+	}
+	if err := m.flushAudit(); err != nil {
+		return err
+	}
+	return m.writeReport()
+}
+
+func (m model) writeReport() error {
+	return writeReport(historyFile, m.history, m.config)
+}
+
+// startTracking begins a new session from a, starting now, and records it in
+// activeFile so it survives a restart. The start time carries no monotonic
+// clock reading, just like one read back from activeFile, so elapsed time
+// always follows the wall clock and includes any time asleep until
+// checkSleep has asked about it.
+func (m *model) startTracking(a activeSession) {
+	a.start = time.Now().Round(0)
+	m.active = &a
+	m.elapsed = 0
+	m.remindersSent = 0
+	saveActive(*m.active)
+}
+
+// startedCmd starts the timer ticking and announces the session that has just
+// started.
+func (m model) startedCmd() tea.Cmd {
+	return tea.Batch(m.tickCmd(), m.notifyCmd("Tracking started", "Now tracking "+track.ProjectLabel(m.active.project)+"."),
+		m.webhookCmd(startEvent(*m.active)), m.hookCmd(startEvent(*m.active)))
+}
+
+// syncActive picks up changes made to the running session elsewhere, such as
+// `time-tracker stop` or another TUI attached to the same daemon.
+func (m *model) syncActive() {
+	active, err := loadActive()
+	if err != nil {
+		return
+	}
+	switch {
+	case active == nil:
+		m.active = nil
+		m.elapsed = 0
+		m.idlePaused = false
+		m.idlePrompt = false
+		if m.currentView == trackingView {
+			m.currentView = menuView
+		}
+		// The session was added to storage by whoever stopped it.
+		m.reload()
+		m.status = "Tracking was stopped elsewhere"
+	case !active.start.Equal(m.active.start):
+		m.active = active
+		m.elapsed = active.elapsed(time.Now())
+		m.remindersSent = int(m.elapsed / trackingReminderEvery)
+	default:
+		m.active = active
+	}
+}
+
+// togglePause pauses the running timer, or resumes it if already paused. It
+// returns a command for the pause or resume hook.
+func (m *model) togglePause() tea.Cmd {
+	now := time.Now()
+	ev := resumeEvent
+	if m.active.paused() {
+		m.active.pauses[len(m.active.pauses)-1].End = now
+	} else {
+		m.elapsed = m.active.elapsed(now)
+		m.active.pauses = append(m.active.pauses, pause{Start: now})
+		ev = pauseEvent
+	}
+	saveActive(*m.active)
+	return m.hookCmd(ev(*m.active, m.elapsed))
+}
+
+// stopTracking ends the running session, closing any open pause, and records
+// it in history. It returns a command for the "stopped" notification, webhooks
+// and hook.
+func (m *model) stopTracking() tea.Cmd {
+	sessions := m.config.finishSessions(*m.active, time.Now())
+	m.addSessions(sessions)
+	m.active = nil
+	m.elapsed = 0
+	m.idlePaused = false
+	m.idlePrompt = false
+	clearActive()
+
+	sess := sessions[len(sessions)-1]
+	return tea.Batch(
+		m.notifyCmd("Tracking stopped",
+			fmt.Sprintf("%s: %s tracked.", track.ProjectLabel(sess.Project), m.config.durationLong(sess.Duration))),
+		m.webhookCmd(stopEvent(sess)),
+		m.hookCmd(stopEvent(sess)),
+	)
+}
+
+// addSessions appends finished sessions to history, writing them out straight
+// away with Auto-save on. Until they are in storage they are also kept in
+// journalFile, so they are not lost if the program dies first.
+func (m *model) addSessions(sessions []session) {
+	journal(sessions)
+	m.history = append(m.history, sessions...)
+	if !m.autoSave() {
+		m.dirty = true
+		return
+	}
+	for _, sess := range sessions {
+		if m.storage.Append(sess) != nil {
+			return
+		}
+	}
+	clearJournal()
+	m.writeReport()
+}
+
+func (m model) updateProject(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	choices := m.projectChoices()
+
 	switch msg.String() {
-	case "ctrl+c", "q":
-		return m, tea.Quit
-	case "esc", "b":
+	case "ctrl+c":
+		return m.quit()
+	case "esc":
+		m.projectInput.Blur()
+		m.currentView = menuView
+		if m.filling != nil {
+			m.filling = nil
+			m.currentView = gapsView
+		}
+		return m, nil
+	case "up", "ctrl+p":
+		if m.projectCursor > 0 {
+			m.projectCursor--
+		}
+		return m, nil
+	case "down", "ctrl+n":
+		if m.projectCursor < len(choices)-1 {
+			m.projectCursor++
+		}
+		return m, nil
+	case "ctrl+f":
+		project, _ := track.SplitProjectTags(m.projectInput.Value())
+		if m.projectCursor >= 0 && m.projectCursor < len(choices) {
+			project = choices[m.projectCursor].project
+		}
+		if project != "" {
+			m.toggleFavorite(project)
+			if m.projectCursor >= 0 {
+				m.projectCursor = slices.IndexFunc(m.projectChoices(), func(c projectChoice) bool { return c.project == project })
+			}
+		}
+		return m, nil
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		// Digits pick a project only before anything is typed, so names can
+		// still contain them.
+		if n := int(msg.Runes[0] - '1'); m.projectInput.Value() == "" && n < len(choices) {
+			m.projectInput.SetValue(choices[n].project)
+			return m.submitProject()
+		}
+	case "enter":
+		if m.projectCursor >= 0 && m.projectCursor < len(choices) {
+			_, tags := track.SplitProjectTags(m.projectInput.Value())
+			m.projectInput.SetValue(strings.TrimSpace(choices[m.projectCursor].project + " " + track.FormatTags(tags)))
+		}
+		return m.submitProject()
+	}
+
+	var cmd tea.Cmd
+	before := m.projectInput.Value()
+	m.projectInput, cmd = m.projectInput.Update(msg)
+	if m.projectInput.Value() != before {
+		// Highlight the best match for the new text, if there is any text.
+		m.projectCursor = -1
+		if query, _ := track.SplitProjectTags(m.projectInput.Value()); query != "" {
+			m.projectCursor = 0
+		}
+	}
+	return m, cmd
+}
+
+// submitProject starts tracking the project and tags typed at the project
+// prompt, or fills the gap being filled with them.
+func (m model) submitProject() (tea.Model, tea.Cmd) {
+	m.projectInput.Blur()
+	if m.filling != nil {
+		return m.fillGap(m.projectInput.Value())
+	}
+	project, tags := track.SplitProjectTags(m.projectInput.Value())
+	a := activeSession{project: project, tags: tags, billable: m.config.billableByDefault(project), issue: branchIssue()}
+	m.config.categorizeActive(&a, gitBranch())
+	m.startTracking(a)
+	m.currentView = trackingView
+	return m, m.startedCmd()
+}
+
+// knownTags returns the distinct tags in history, in order of first
+// appearance.
+func (m model) knownTags() []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, sess := range m.history {
+		for _, tag := range sess.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// historyGroups returns the groups shown in the history view, in the chosen
+// sort order, limited to sessions carrying m.tagFilter and starting within
+// m.rangeFilter when those are set.
+func (m model) historyGroups() []historyGroup {
+	r := m.historyRange()
+	var indices []int
+	for i, sess := range m.history {
+		if m.visible(sess, r) {
+			indices = append(indices, i)
+		}
+	}
+	return sortHistory(m.history, indices, m.historySort)
+}
+
+// historyRow is one cursor stop in the history view: a session, or a whole
+// collapsed group.
+type historyRow struct {
+	group int // index into historyGroups
+	index int // index into history, or -1 for a collapsed group
+}
+
+// historyRows returns the rows of the history view in the order they are
+// shown.
+func (m model) historyRows() []historyRow {
+	var rows []historyRow
+	for g, group := range m.historyGroups() {
+		if m.collapsed[group.key] {
+			rows = append(rows, historyRow{group: g, index: -1})
+			continue
+		}
+		for _, i := range group.indices {
+			rows = append(rows, historyRow{group: g, index: i})
+		}
+	}
+	return rows
+}
+
+// cursorSession returns the index into history of the session under the
+// cursor. It reports false when the cursor is on a collapsed group or the
+// history is empty.
+func (m model) cursorSession() (int, bool) {
+	rows := m.historyRows()
+	if m.cursor >= len(rows) || rows[m.cursor].index < 0 {
+		return 0, false
+	}
+	return rows[m.cursor].index, true
+}
+
+// toggleGroup collapses the group under the cursor, or expands it when it
+// is collapsed, keeping the cursor on that group.
+func (m *model) toggleGroup() {
+	rows := m.historyRows()
+	if m.cursor >= len(rows) {
+		return
+	}
+	groups := m.historyGroups()
+	k := groups[rows[m.cursor].group].key
+	if m.collapsed == nil {
+		m.collapsed = make(map[string]bool)
+	}
+	if m.collapsed[k] {
+		delete(m.collapsed, k)
+	} else {
+		m.collapsed[k] = true
+	}
+	m.cursorToGroup(rows[m.cursor].group)
+}
+
+// toggleAllGroups collapses every group, or expands them all when they are
+// all collapsed already.
+func (m *model) toggleAllGroups() {
+	groups := m.historyGroups()
+	rows := m.historyRows()
+	if len(rows) == 0 {
+		return
+	}
+	g := rows[m.cursor].group
+	all := true
+	for _, group := range groups {
+		all = all && m.collapsed[group.key]
+	}
+	if all {
+		m.collapsed = nil
+	} else {
+		m.collapsed = make(map[string]bool)
+		for _, group := range groups {
+			m.collapsed[group.key] = true
+		}
+	}
+	m.cursorToGroup(g)
+}
+
+// cursorToGroup moves the cursor to the first row of group g.
+func (m *model) cursorToGroup(g int) {
+	for row, r := range m.historyRows() {
+		if r.group == g {
+			m.cursor = row
+			return
+		}
+	}
+}
+
+// nextTagFilter cycles the history filter through no filter and each known
+// tag in turn.
+func (m model) nextTagFilter() string {
+	tags := m.knownTags()
+	if m.tagFilter == "" {
+		if len(tags) == 0 {
+			return ""
+		}
+		return tags[0]
+	}
+	for i, tag := range tags {
+		if tag == m.tagFilter && i+1 < len(tags) {
+			return tags[i+1]
+		}
+	}
+	return ""
+}
+
+func (m model) updateHistory(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.status = ""
+	rows := m.historyRows()
+	i, onSession := m.cursorSession()
+
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Back):
+		if m.search != "" && msg.String() == "esc" {
+			m.search = ""
+			break
+		}
+		if len(m.marked) > 0 && msg.String() == "esc" {
+			m.marked = nil
+			break
+		}
+		m.flushDelete()
 		m.currentView = menuView
 		m.cursor = 0
-	case "up", "k":
+	case key.Matches(msg, m.keys.Up):
 		if m.cursor > 0 {
 			m.cursor--
 		}
-	case "down", "j":
-		if m.cursor < len(m.history)-1 {
+	case key.Matches(msg, m.keys.Down):
+		if m.cursor < len(rows)-1 {
 			m.cursor++
 		}
-	case "d", "backspace":
-		if len(m.history) > 0 && m.cursor < len(m.history) {
-			m.history = append(m.history[:m.cursor], m.history[m.cursor+1:]...)
-			if m.cursor >= len(m.history) && m.cursor > 0 {
-				m.cursor--
+	case key.Matches(msg, m.keys.PageUp):
+		m.cursor = max(0, m.cursor-max(1, m.historyPageSize()-1))
+	case key.Matches(msg, m.keys.PageDown):
+		m.cursor = max(0, min(len(rows)-1, m.cursor+max(1, m.historyPageSize()-1)))
+	case key.Matches(msg, m.keys.Top):
+		m.cursor = 0
+	case key.Matches(msg, m.keys.Bottom):
+		m.cursor = max(0, len(rows)-1)
+	case key.Matches(msg, m.keys.Search):
+		return m.startSearch()
+	case key.Matches(msg, m.keys.NextMatch), key.Matches(msg, m.keys.PrevMatch):
+		if m.search != "" && !m.nextMatch(m.cursor, key.Matches(msg, m.keys.PrevMatch)) {
+			m.status = fmt.Sprintf("No matches for %q", m.search)
+		}
+	case key.Matches(msg, m.keys.FilterTag):
+		m.tagFilter = m.nextTagFilter()
+		m.cursor = 0
+	case key.Matches(msg, m.keys.DateRange):
+		m.rangeFilter = nextRangePreset(m.rangeFilter)
+		m.cursor = 0
+	case key.Matches(msg, m.keys.CustomRange):
+		return m.startRangeEdit()
+	case key.Matches(msg, m.keys.NextMode):
+		m.historySort = (m.historySort + 1) % historySortCount
+		m.cursor = 0
+	case key.Matches(msg, m.keys.PrevMode):
+		m.historySort = (m.historySort + historySortCount - 1) % historySortCount
+		m.cursor = 0
+	case key.Matches(msg, m.keys.Export):
+		if len(m.markedIndices()) > 0 {
+			m.exportMarked()
+			break
+		}
+		sessions := m.filteredHistory()
+		if err := exportCSV(csvExportFile, sessions, m.config); err != nil {
+			m.status = fmt.Sprintf("Export failed: %v", err)
+		} else {
+			m.status = fmt.Sprintf("Exported %s to %s", exportSummary(sessions, m.config), csvExportFile)
+		}
+	case key.Matches(msg, m.keys.EditNote):
+		if onSession {
+			if dialog, ok := m.editable(i); !ok {
+				return dialog, nil
 			}
-			saveHistory(m.history)
+			return m.startEdit(editNote, i)
 		}
+	case key.Matches(msg, m.keys.EditTags):
+		if marked := m.markedIndices(); len(marked) > 0 {
+			if dialog, ok := m.editable(marked...); !ok {
+				return dialog, nil
+			}
+			return m.startMarkedTagEdit()
+		}
+		if onSession {
+			if dialog, ok := m.editable(i); !ok {
+				return dialog, nil
+			}
+			return m.startEdit(editTags, i)
+		}
+	case key.Matches(msg, m.keys.EditIssue):
+		if onSession {
+			if dialog, ok := m.editable(i); !ok {
+				return dialog, nil
+			}
+			return m.startEdit(editIssue, i)
+		}
+	case key.Matches(msg, m.keys.Project):
+		if onSession {
+			if dialog, ok := m.editable(append(m.markedIndices(), i)...); !ok {
+				return dialog, nil
+			}
+			return m.startProjectEdit(i)
+		}
+	case key.Matches(msg, m.keys.Delete):
+		if marked := m.markedIndices(); len(marked) > 0 {
+			if dialog, ok := m.editable(marked...); !ok {
+				return dialog, nil
+			}
+			return m.deleteMarked()
+		}
+		if onSession {
+			if dialog, ok := m.editable(i); !ok {
+				return dialog, nil
+			}
+			sess := m.history[i]
+			return m.confirm("🗑  Delete session?",
+				fmt.Sprintf("%s, %s – %s (%s)", track.ProjectLabel(sess.Project), sess.Start.Format("Jan 02 15:04"),
+					sess.End.Format("15:04"), m.config.duration(sess.Duration)),
+				confirmOption{"n", "Cancel", cancelDialog},
+				confirmOption{"y", "Delete", func(m model) (tea.Model, tea.Cmd) {
+					if m.cursor >= len(rows)-1 && m.cursor > 0 {
+						m.cursor--
+					}
+					cmd := m.deleteSession(i)
+					m.scrollHistory()
+					return m, cmd
+				}},
+			)
+		}
+	case key.Matches(msg, m.keys.ClearAll):
+		if len(m.history) > 0 {
+			for j := range m.history {
+				if dialog, ok := m.editable(j); !ok {
+					return dialog, nil
+				}
+			}
+			return m.confirm("🗑  Clear all history?",
+				fmt.Sprintf("All %d sessions will be moved to the trash.", len(m.history)),
+				confirmOption{"n", "Cancel", cancelDialog},
+				confirmOption{"y", "Delete everything", func(m model) (tea.Model, tea.Cmd) {
+					deleted := m.history
+					if m.pending != nil {
+						deleted = append(slices.Clone(deleted), m.pending.sess)
+					}
+					m.deleted(auditClear, deleted)
+					m.pending = nil
+					m.history = []session{}
+					m.cursor = 0
+					m.historyOffset = 0
+					m.changed()
+					m.status = "History cleared"
+					return m, nil
+				}},
+			)
+		}
+	case key.Matches(msg, m.keys.Billable):
+		if onSession {
+			if dialog, ok := m.editable(i); !ok {
+				return dialog, nil
+			}
+			before := m.history[i]
+			m.history[i].Billable = !m.history[i].Billable
+			m.auditEdited(i, before)
+			m.changed()
+		}
+	case key.Matches(msg, m.keys.Undo):
+		if !m.undoDelete() {
+			m.status = "Nothing to undo"
+		}
+	case key.Matches(msg, m.keys.Resolve):
+		if onSession {
+			return m.resolveOverlap(i)
+		}
+	case key.Matches(msg, m.keys.Split):
+		if onSession {
+			if dialog, ok := m.editable(i); !ok {
+				return dialog, nil
+			}
+			return m.startSplit(i)
+		}
+	case key.Matches(msg, m.keys.Mark):
+		if m.cursor < len(rows) {
+			if onSession {
+				m.toggleMark(i)
+			} else {
+				m.toggleGroupMarks(m.historyGroups()[rows[m.cursor].group])
+			}
+			if m.cursor < len(rows)-1 {
+				m.cursor++
+			}
+		}
+	case key.Matches(msg, m.keys.Collapse):
+		m.toggleGroup()
+	case key.Matches(msg, m.keys.CollapseAll):
+		m.toggleAllGroups()
+	case key.Matches(msg, m.keys.Merge):
+		if dialog, ok := m.editable(m.markedIndices()...); !ok {
+			return dialog, nil
+		}
+		return m.mergeMarked()
 	}
+	m.scrollHistory()
 	return m, nil
 }
 
 func (m model) updateSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	settingsKeys := m.getSettingsKeys()
 
-	switch msg.String() {
-	case "ctrl+c", "q":
-		return m, tea.Quit
-	case "esc", "b":
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Back):
 		m.currentView = menuView
 		m.cursor = 0
-	case "up", "k":
+	case key.Matches(msg, m.keys.Up):
 		if m.settingsCursor > 0 {
 			m.settingsCursor--
 		}
-	case "down", "j":
-		if m.settingsCursor < len(settingsKeys)-1 {
+	case key.Matches(msg, m.keys.Down):
+		// The daily goal comes after the toggles.
+		if m.settingsCursor < len(settingsKeys) {
 			m.settingsCursor++
 		}
-	case "enter", " ":
-		key := settingsKeys[m.settingsCursor]
-		m.settings[key] = !m.settings[key]
+	case key.Matches(msg, m.keys.Toggle) && m.settingsCursor == len(settingsKeys):
+		return m.startGoalEdit()
+	case key.Matches(msg, m.keys.Toggle):
+		name := settingsKeys[m.settingsCursor]
+		m.settings[name] = !m.settings[name]
+		m.config.Settings = m.settings
+		saveConfig(m.config)
+		switch {
+		case name == settingAutoSave && m.autoSave() && m.dirty:
+			m.saveNow()
+		case name == settingDarkMode:
+			applyPalette(m.config.palette())
+		}
 	}
 	return m, nil
 }
 
 func (m model) getSettingsKeys() []string {
-	return []string{"Show seconds", "Auto-save", "Notifications", "Dark mode"}
+	return []string{settingShowSeconds, settingDecimalHours, settingAutoSave, settingNotifications, settingSound, settingDarkMode}
 }
 
 func (m model) View() string {
+	if m.dialog != nil {
+		return m.viewConfirm()
+	}
+	if m.idlePrompt && m.active != nil {
+		return m.viewIdlePrompt()
+	}
+	if m.showHelp {
+		return m.viewHelp()
+	}
+
+	var s string
 	switch m.currentView {
 	case trackingView:
-		return m.viewTracking()
+		s = m.viewTracking()
 	case historyView:
-		return m.viewHistory()
+		s = m.viewHistory()
 	case settingsView:
-		return m.viewSettings()
+		s = m.viewSettings()
+	case projectView:
+		s = m.viewProject()
+	case summaryView:
+		s = m.viewSummary()
+	case statsView:
+		s = m.viewStats()
+	case todayView:
+		s = m.viewToday()
+	case heatmapView:
+		s = m.viewHeatmap()
+	case timelineView:
+		s = m.viewTimeline()
+	case gapsView:
+		s = m.viewGaps()
+	case auditView:
+		s = m.viewAudit()
+	case trashView:
+		s = m.viewTrash()
+	case profilesView:
+		s = m.viewProfiles()
 	default:
-		return m.viewMenu()
+		s = m.viewMenu()
 	}
+	return s + m.viewUnsaved() + m.viewReadOnly()
 }
 
 func (m model) viewMenu() string {
-	s := titleStyle.Render("⏱  Time Tracking") + "\n\n"
+	title := "⏱  Time Tracking"
+	if profile != defaultProfile {
+		title += " · " + profile
+	}
+	s := titleStyle.Render(title) + "\n\n"
 
-	if m.tracking {
-		s += timerStyle.Render(fmt.Sprintf("● Recording: %s", formatDuration(m.elapsed))) + "\n\n"
+	if m.active != nil {
+		if m.active.paused() {
+			s += timerStyle.Render(fmt.Sprintf("⏸ Paused: %s", m.config.timer(m.elapsed))) + "\n\n"
+		} else {
+			s += timerStyle.Render(fmt.Sprintf("● Recording: %s", m.config.timer(m.elapsed))) + "\n\n"
+		}
+		s += m.viewLongSession()
+		s += m.viewBreak()
 	}
+	s += m.viewStartReminder()
+	s += m.viewGoal()
+	s += m.viewSparkline()
+	s += m.viewStreak()
 
 	for i, item := range m.menuItems {
 		cursor := "  "
@@ -281,7 +1193,19 @@ func (m model) viewMenu() string {
 		}
 	}
 
-	s += "\n" + helpStyle.Render("↑/↓: navigate • enter: select • q: quit")
+	help := []key.Binding{pairHelp(m.keys.Up, m.keys.Down, "navigate"), m.keys.Select, m.keys.Start}
+	if last, ok := m.lastSession(); ok && m.active == nil {
+		line := "Last: " + track.ProjectLabel(last.Project)
+		if len(last.Tags) > 0 {
+			line += " " + track.FormatTags(last.Tags)
+		}
+		if last.Note != "" {
+			line += " · " + truncate(last.Note, 40)
+		}
+		s += "\n" + normalStyle.Render(line) + "\n"
+		help = append(help, m.keys.Resume)
+	}
+	s += "\n" + helpLine(append(help, m.keys.Help, m.keys.Quit)...)
 
 	return s
 }
@@ -289,48 +1213,345 @@ func (m model) viewMenu() string {
 func (m model) viewTracking() string {
 	s := titleStyle.Render("⏱  Tracking Time") + "\n\n"
 
-	s += timerStyle.Render(fmt.Sprintf("  %s  ", formatDuration(m.elapsed))) + "\n\n"
+	if m.active == nil {
+		return s + normalStyle.Render("Not tracking.") + "\n\n" + helpLine(m.keys.Back, m.keys.Quit)
+	}
+
+	timer := timerStyle
+	if m.active.nearTarget(m.elapsed) {
+		timer = timer.Foreground(warningStyle.GetForeground())
+	}
+	if m.active.paused() {
+		s += timer.Render(fmt.Sprintf("⏸ %s  ", m.config.timer(m.elapsed))) + "\n\n"
+	} else {
+		s += timer.Render(fmt.Sprintf("  %s  ", m.config.timer(m.elapsed))) + "\n\n"
+	}
+	s += m.viewCountdown()
+	s += m.viewLongSession()
+	s += m.viewBreak()
+	s += m.viewBudget()
+	s += m.viewGoal()
+
+	s += normalStyle.Render(fmt.Sprintf("Project: %s", track.ProjectLabel(m.active.project))) + "\n"
+	s += normalStyle.Render(fmt.Sprintf("Started: %s", m.active.start.Format("15:04:05"))) + "\n"
+	if len(m.active.pauses) > 0 {
+		s += normalStyle.Render(fmt.Sprintf("Paused:  %s (%d×)", m.config.duration(track.PausedTotal(m.active.pauses, time.Now())), len(m.active.pauses))) + "\n"
+	}
 
-	s += normalStyle.Render(fmt.Sprintf("Started: %s", m.trackingStart.Format("15:04:05"))) + "\n\n"
+	if m.editing != editNone {
+		return s + "\n" + m.viewEdit()
+	}
+
+	if len(m.active.tags) > 0 {
+		s += normalStyle.Render(fmt.Sprintf("Tags:    %s", track.FormatTags(m.active.tags))) + "\n"
+	}
+	if m.active.issue != "" {
+		s += normalStyle.Render(fmt.Sprintf("Issue:   %s", issueRef(m.active.issue))) + "\n"
+	}
+	if !m.active.billable {
+		s += normalStyle.Render("Billing: not billable") + "\n"
+	} else if rate := m.config.rateFor(m.active.project); rate > 0 {
+		s += normalStyle.Render(fmt.Sprintf("Earned:  %s (%s/h)", m.config.projectMoney(amountFor(m.elapsed, rate), m.active.project), m.config.projectMoney(rate, m.active.project))) + "\n"
+	}
+	if m.active.note != "" {
+		s += normalStyle.Render(fmt.Sprintf("Note:    %s", m.active.note)) + "\n"
+	}
+	s += m.viewInterruptions()
+	s += "\n"
 
 	s += selectedStyle.Render("> Stop and save") + "\n"
-	s += normalStyle.Render("  Press enter/s to stop, esc/b to go back (keeps running)") + "\n"
+	s += normalStyle.Render(fmt.Sprintf("  Press %s to stop, %s to go back (keeps running)",
+		m.keys.Stop.Help().Key, m.keys.Back.Help().Key)) + "\n"
+
+	// Every key the view takes, as in the help screen, in lines that fit
+	// 80 columns.
+	nav := []key.Binding{m.keys.Back, m.keys.Help, m.keys.Quit}
+	if m.breakDue(time.Now()) {
+		nav = append([]key.Binding{m.keys.Snooze}, nav...)
+	}
+	s += "\n" + helpLine(m.keys.Stop, m.keys.Pause, m.keys.Countdown, m.keys.EditNote, m.keys.AddNote) +
+		"\n" + helpLine(m.keys.EditTags, m.keys.EditIssue, m.keys.Billable, m.keys.Interrupt) +
+		"\n" + helpLine(nav...)
+
+	return s
+}
+
+func (m model) viewProject() string {
+	s := titleStyle.Render("⏱  Start Tracking") + "\n\n"
+	if m.filling != nil {
+		s = titleStyle.Render("➕ Fill Gap") + "\n\n"
+		s += normalStyle.Render(fmt.Sprintf("%s – %s", m.filling.start.Format("Mon Jan 02 15:04"), m.filling.end.Format("15:04"))) + "\n\n"
+	}
+
+	s += normalStyle.Render("Project:") + "\n"
+	s += m.projectInput.View() + "\n\n"
+
+	choices := m.projectChoices()
+	if len(choices) > 0 {
+		label := "Favorite and recent projects:"
+		if m.projectInput.Value() != "" {
+			label = "Matching projects:"
+		}
+		s += normalStyle.Render(label) + "\n"
+		for i, choice := range choices {
+			cursor := "  "
+			if m.projectCursor == i {
+				cursor = "> "
+			}
+			number := "   "
+			if i < quickPicks && m.projectInput.Value() == "" {
+				number = fmt.Sprintf("%d. ", i+1)
+			}
+			name := choice.project
+			switch {
+			case choice.create:
+				name = fmt.Sprintf("+ new project %q", choice.project)
+			case slices.Contains(m.config.Favorites, choice.project):
+				name = "★ " + name
+			}
+
+			line := cursor + number + name
+			if m.projectCursor == i {
+				s += selectedStyle.Render(line) + "\n"
+			} else {
+				s += historyItemStyle.Render(line) + "\n"
+			}
+		}
+	}
 
-	s += "\n" + helpStyle.Render("enter/s: stop • esc/b: back • q: quit")
+	s += "\n" + helpStyle.Render("type to search, #tags optional • 1-9: start that project • ↑/↓: pick • ctrl+f: pin/unpin • enter: start • esc: cancel")
 
 	return s
 }
 
 func (m model) viewHistory() string {
+	header, footer := m.historyHeader(), m.historyFooter()
+
+	lines, cursorLine := m.historyLines()
+	if page := m.historyPageSize(); page > 0 && len(lines) > page {
+		start := historyScroll(m.historyOffset, cursorLine, page, len(lines))
+		indicator := fmt.Sprintf("%d of %d", m.cursor+1, len(m.historyRows()))
+		if start > 0 {
+			indicator += " • ↑ more"
+		}
+		if start+page < len(lines) {
+			indicator += " • ↓ more"
+		}
+		lines = append(lines[start:start+page], helpStyle.Render(indicator))
+	}
+
+	s := header
+	for _, line := range lines {
+		s += line + "\n"
+	}
+	return s + footer
+}
+
+func (m model) historyHeader() string {
 	s := titleStyle.Render("📋 History") + "\n\n"
+	s += normalStyle.Render(fmt.Sprintf("Sort: %s (%s to change)", m.historySort, m.keys.NextMode.Help().Key)) + "\n"
+	if filter := m.filterLabel(); filter != "" {
+		s += normalStyle.Render("Filter: "+filter) + "\n"
+	}
+	if zone := m.config.timezoneLabel(); zone != "" {
+		s += normalStyle.Render("Times in "+zone) + "\n"
+	}
+	s += "\n"
+	if n := len(findOverlaps(m.history)); n > 0 {
+		s += matchStyle.Render(fmt.Sprintf("⚠ %d sessions overlap", n)) +
+			normalStyle.Render(fmt.Sprintf(" — press %s on one to trim or merge", m.keys.Resolve.Help().Key)) + "\n\n"
+	}
+	if n := len(m.markedIndices()); n > 0 {
+		s += normalStyle.Render(fmt.Sprintf("%d marked — %s: merge • %s: delete • %s: retag • %s: project • %s: export • esc: clear marks",
+			n, m.keys.Merge.Help().Key, m.keys.Delete.Help().Key, m.keys.EditTags.Help().Key,
+			m.keys.Project.Help().Key, m.keys.Export.Help().Key)) + "\n\n"
+	}
+	return s
+}
+
+func (m model) historyFooter() string {
+	if m.editing != editNone {
+		return "\n" + m.viewEdit()
+	}
 
+	var s string
+	if search := m.viewSearch(); search != "" {
+		s += "\n" + search + "\n"
+	}
+	if m.status != "" {
+		s += "\n" + normalStyle.Render(m.status) + "\n"
+	}
+	s += "\n" + helpLine(
+		pairHelp(m.keys.Up, m.keys.Down, "navigate"),
+		m.keys.Search,
+		m.keys.EditNote,
+		m.keys.Delete,
+		m.keys.Undo,
+		m.keys.Back,
+		m.keys.Help,
+		m.keys.Quit,
+	)
+	return s
+}
+
+// historyLines renders the history list one entry per line, group headers
+// included, and reports which line holds the cursor.
+func (m model) historyLines() ([]string, int) {
+	groups := m.historyGroups()
 	if len(m.history) == 0 {
-		s += normalStyle.Render("No tracking sessions yet.") + "\n"
-	} else {
-		for i, sess := range m.history {
+		return []string{normalStyle.Render("No tracking sessions yet.")}, 0
+	}
+	if len(groups) == 0 {
+		return []string{normalStyle.Render("No sessions match the filter.")}, 0
+	}
+
+	overlapping := findOverlaps(m.history)
+	// Day groups carry the date in their header.
+	startLayout := "Jan 02 15:04"
+	if m.historySort == sortNewest || m.historySort == sortOldest {
+		startLayout = "15:04"
+	}
+	var lines []string
+	cursorLine := 0
+	row := 0
+	for _, group := range groups {
+		header := fmt.Sprintf("%s (%s)", group.label, m.config.duration(group.total))
+		if m.config.hasRates() {
+			header = fmt.Sprintf("%s (%s, %s)", group.label, m.config.duration(group.total),
+				m.config.money(m.config.totalEarnings(m.history, group.indices)))
+		}
+		if m.collapsed[group.key] {
+			cursor := "  "
+			if m.cursor == row {
+				cursor = "> "
+				cursorLine = len(lines)
+			}
+			count := fmt.Sprintf("%d sessions", len(group.indices))
+			if len(group.indices) == 1 {
+				count = "1 session"
+			}
+			header = fmt.Sprintf("%s▸ %s · %s", cursor, header, count)
+			if m.cursor == row {
+				lines = append(lines, selectedStyle.Render(header))
+			} else {
+				lines = append(lines, projectHeaderStyle.Render(header))
+			}
+			row++
+			continue
+		}
+		lines = append(lines, projectHeaderStyle.Render("▾ "+header))
+
+		for _, i := range group.indices {
+			sess := m.history[i]
 			cursor := "  "
-			if m.cursor == i {
+			if m.cursor == row {
 				cursor = "> "
 			}
+			mark := ""
+			if m.isMarked(sess) {
+				mark = "● "
+			} else if len(m.marked) > 0 {
+				mark = "  "
+			}
 
-			line := fmt.Sprintf("%s%s - %s (%s)",
+			line := fmt.Sprintf("%s%s%s - %s (%s)",
 				cursor,
-				sess.start.Format("Jan 02 15:04"),
-				sess.end.Format("15:04"),
-				formatDuration(sess.duration),
+				mark,
+				sess.Start.Format(startLayout),
+				sess.End.Format("15:04"),
+				m.config.duration(sess.Duration),
 			)
+			if m.historySort != sortByProject {
+				line += " " + track.ProjectLabel(sess.Project)
+			}
+			if len(sess.Pauses) > 0 {
+				line += fmt.Sprintf(" ⏸ %s", m.config.duration(track.PausedTotal(sess.Pauses, sess.End)))
+			}
+			if !sess.Billable {
+				line += " (not billable)"
+			} else if m.config.rateFor(sess.Project) > 0 {
+				line += " " + m.config.projectMoney(m.config.earnings(sess), sess.Project)
+			}
+			if len(sess.Tags) > 0 {
+				line += " " + track.FormatTags(sess.Tags)
+			}
+			if sess.Issue != "" {
+				line += " → " + issueRef(sess.Issue)
+			}
+			if sess.Note != "" {
+				line += " · " + truncate(sess.Note, 40)
+			}
+			if overlapping[i] {
+				line += " ⚠ overlaps"
+			}
+			if _, _, closed := m.config.closedPeriod(sess.Start); closed {
+				line += " 🔒"
+			}
 
-			if m.cursor == i {
-				s += selectedStyle.Render(line) + "\n"
+			style := historyItemStyle
+			if m.cursor == row {
+				cursorLine = len(lines)
+				style = selectedStyle
+			}
+			if sessionMatches(sess, m.search) {
+				lines = append(lines, highlight(line, m.search, style))
 			} else {
-				s += historyItemStyle.Render(line) + "\n"
+				lines = append(lines, style.Render(line))
 			}
+			row++
 		}
 	}
+	return lines, cursorLine
+}
 
-	s += "\n" + helpStyle.Render("↑/↓: navigate • d: delete • esc/b: back • q: quit")
+// historyPageSize is how many history lines fit on screen alongside the
+// header, footer and position indicator. It is 0 until the terminal size is
+// known, which disables scrolling.
+func (m model) historyPageSize() int {
+	if m.height == 0 {
+		return 0
+	}
+	page := m.height - m.screenLines(m.historyHeader()) - m.screenLines(m.historyFooter()+m.viewUnsaved()) - 1
+	return max(page, 3)
+}
 
-	return s
+// screenLines is how many terminal rows s takes up once lines wider than the
+// terminal wrap.
+func (m model) screenLines(s string) int {
+	n := 0
+	for _, line := range strings.Split(s, "\n") {
+		n++
+		if w := lipgloss.Width(line); m.width > 0 && w > m.width {
+			n += (w - 1) / m.width
+		}
+	}
+	return n
+}
+
+// scrollHistory moves the history viewport so the cursor stays on screen.
+func (m *model) scrollHistory() {
+	lines, cursorLine := m.historyLines()
+	if page := m.historyPageSize(); page > 0 {
+		m.historyOffset = historyScroll(m.historyOffset, cursorLine, page, len(lines))
+	} else {
+		m.historyOffset = 0
+	}
+}
+
+// historyScroll returns the first visible line of a page-line window over
+// total lines, starting from offset and moved just enough to show cursorLine.
+// Showing the first entry also shows the project header above it.
+func historyScroll(offset, cursorLine, page, total int) int {
+	if cursorLine <= 1 {
+		return 0
+	}
+	if cursorLine < offset {
+		offset = cursorLine
+	}
+	if cursorLine >= offset+page {
+		offset = cursorLine - page + 1
+	}
+	return max(0, min(offset, total-page))
 }
 
 func (m model) viewSettings() string {
@@ -357,169 +1578,163 @@ func (m model) viewSettings() string {
 		}
 	}
 
-	s += "\n" + helpStyle.Render("↑/↓: navigate • enter/space: toggle • esc/b: back • q: quit")
-
-	return s
-}
-
-func formatDuration(d time.Duration) string {
-	hours := int(d.Hours())
-	minutes := int(d.Minutes()) % 60
-	seconds := int(d.Seconds()) % 60
-
-	if hours > 0 {
-		return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
-	}
-	return fmt.Sprintf("%02d:%02d", minutes, seconds)
-}
-
-func saveHistory(history []session) error {
-	var totalDuration time.Duration
-	for _, s := range history {
-		totalDuration += s.duration
-	}
-
-	var sb strings.Builder
-
-	sb.WriteString(`
- ╔════════════════════════════════════════════════════════════════╗
- ║                                                                ║
- ║    ████████╗██╗███╗   ███╗███████╗                             ║
- ║    ╚══██╔══╝██║████╗ ████║██╔════╝                             ║
- ║       ██║   ██║██╔████╔██║█████╗                               ║
- ║       ██║   ██║██║╚██╔╝██║██╔══╝                               ║
- ║       ██║   ██║██║ ╚═╝ ██║███████╗                             ║
- ║       ╚═╝   ╚═╝╚═╝     ╚═╝╚══════╝                             ║
- ║                                                                ║
- ║    ████████╗██████╗  █████╗  ██████╗██╗  ██╗███████╗██████╗    ║
- ║    ╚══██╔══╝██╔══██╗██╔══██╗██╔════╝██║ ██╔╝██╔════╝██╔══██╗   ║
- ║       ██║   ██████╔╝███████║██║     █████╔╝ █████╗  ██████╔╝   ║
- ║       ██║   ██╔══██╗██╔══██║██║     ██╔═██╗ ██╔══╝  ██╔══██╗   ║
- ║       ██║   ██║  ██║██║  ██║╚██████╗██║  ██╗███████╗██║  ██║   ║
- ║       ╚═╝   ╚═╝  ╚═╝╚═╝  ╚═╝ ╚═════╝╚═╝  ╚═╝╚══════╝╚═╝  ╚═╝   ║
- ║                                                                ║
- ╚════════════════════════════════════════════════════════════════╝
-`)
-
-	sb.WriteString(fmt.Sprintf("\n  Generated: %s\n", time.Now().Format("Mon Jan 02, 2006 at 03:04 PM")))
-	sb.WriteString(fmt.Sprintf("  Total Sessions: %d\n", len(history)))
-	sb.WriteString(fmt.Sprintf("  Total Time: %s\n", formatDurationLong(totalDuration)))
-
-	sb.WriteString(`
- ┌────────────────────────────────────────────────────────────────┐
- │                      SESSION HISTORY                           │
- └────────────────────────────────────────────────────────────────┘
-`)
-
-	if len(history) == 0 {
-		sb.WriteString("\n   No sessions recorded yet.\n")
+	goal := "none"
+	if m.config.DailyGoal != "" {
+		goal = m.config.DailyGoal
+	}
+	if m.settingsCursor == len(settingsKeys) {
+		s += selectedStyle.Render("> Daily goal: "+goal) + "\n"
 	} else {
-		for i, sess := range history {
-			sb.WriteString(fmt.Sprintf(`
-   ┌──────────────────────────────────────────┐
-   │  SESSION #%-3d                            │
-   ├──────────────────────────────────────────┤
-   │  Date:     %-29s │
-   │  Start:    %-29s │
-   │  End:      %-29s │
-   │  Duration: %-29s │
-   └──────────────────────────────────────────┘
-`,
-				i+1,
-				sess.start.Format("Monday, January 02, 2006"),
-				sess.start.Format("03:04:05 PM"),
-				sess.end.Format("03:04:05 PM"),
-				formatDurationLong(sess.duration),
-			))
-		}
-	}
-
-	sb.WriteString(`
- ╔════════════════════════════════════════════════════════════════╗
- ║                        END OF REPORT                           ║
- ╚════════════════════════════════════════════════════════════════╝
-`)
-
-	return os.WriteFile(historyFile, []byte(sb.String()), 0644)
-}
-
-func loadHistory() []session {
-	file, err := os.Open(historyFile)
-	if err != nil {
-		return []session{}
+		s += normalStyle.Render("  Daily goal: "+goal) + "\n"
 	}
-	defer file.Close()
 
-	var history []session
-	scanner := bufio.NewScanner(file)
+	if m.editing != editNone {
+		return s + "\n" + m.viewEdit()
+	}
+	if m.status != "" {
+		s += "\n" + normalStyle.Render(m.status) + "\n"
+	}
 
-	var currentSession *session
-	var dateStr, startStr, endStr string
+	s += "\n" + helpLine(pairHelp(m.keys.Up, m.keys.Down, "navigate"), m.keys.Toggle, m.keys.Back, m.keys.Help, m.keys.Quit)
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	return s
+}
 
-		if strings.Contains(line, "SESSION #") {
-			currentSession = &session{}
-			dateStr, startStr, endStr = "", "", ""
-		}
+func main() {
+	storageKind := flag.String("storage", storageJSON, "storage backend: json or sqlite")
+	csvPath := flag.String("export-csv", "", "write the history as CSV to `file` and exit")
+	idleAfter := flag.Duration("idle", 10*time.Minute, "pause tracking after this long without input (0 disables)")
+	rangeSpec := flag.String("range", "", "limit history and -export-csv to today, week, month, last-month or FROM..TO")
+	readOnly := flag.Bool("read-only", false, "browse the history without changing it")
+	openPath := flag.String("open", "", "browse the sessions in `file`, written by export -format json, read-only")
+	dataDir := flag.String("data-dir", "", "keep history and settings in `dir` instead of $"+dataDirEnv+" or $XDG_DATA_HOME/time-tracker")
+	profileName := flag.String("profile", "", "use the profile `name`, with its own history and settings, instead of $"+profileEnv+" or the default one")
+	flag.Parse()
+	switch {
+	case *openPath != "":
+		readOnlyReason = "browsing " + *openPath
+	case *readOnly:
+		readOnlyReason = readOnlyFlag
+	}
+	if err := useDataDir(*dataDir, *profileName); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	useTimezone()
+	if err := unlockData(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
-		if strings.Contains(line, "Date:") {
-			parts := strings.SplitN(line, "Date:", 2)
-			if len(parts) == 2 {
-				dateStr = strings.TrimSpace(strings.Split(parts[1], "│")[0])
-			}
+	if flag.NArg() > 0 {
+		err := runCommand(*storageKind, flag.Arg(0), flag.Args()[1:])
+		if err := backups.wait(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 		}
-
-		if strings.Contains(line, "Start:") && !strings.Contains(line, "──") {
-			parts := strings.SplitN(line, "Start:", 2)
-			if len(parts) == 2 {
-				startStr = strings.TrimSpace(strings.Split(parts[1], "│")[0])
-			}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
+		return
+	}
 
-		if strings.Contains(line, "End:") {
-			parts := strings.SplitN(line, "End:", 2)
-			if len(parts) == 2 {
-				endStr = strings.TrimSpace(strings.Split(parts[1], "│")[0])
+	if readOnlyReason == "" && *csvPath == "" {
+		var locked lockedError
+		err := lockHistory()
+		switch {
+		case errors.As(err, &locked):
+			if !askReadOnly(locked) {
+				os.Exit(1)
 			}
+			readOnlyReason = readOnlyLocked
+		case err != nil:
+			fmt.Printf("Error locking history: %v\n", err)
+			os.Exit(1)
 		}
+	}
+
+	var storage Storage
+	var err error
+	if *openPath != "" {
+		storage, err = openExport(*openPath)
+	} else {
+		storage, err = openStorage(*storageKind)
+	}
+	if err != nil {
+		fmt.Printf("Error opening storage: %v\n", err)
+		os.Exit(1)
+	}
 
-		if strings.Contains(line, "└──") && currentSession != nil && dateStr != "" && startStr != "" && endStr != "" {
-			startTime, err1 := time.Parse("Monday, January 02, 2006 03:04:05 PM", dateStr+" "+startStr)
-			endTime, err2 := time.Parse("Monday, January 02, 2006 03:04:05 PM", dateStr+" "+endStr)
+	history, err := storage.Load()
+	if err != nil {
+		fmt.Printf("Error loading history: %v\n", err)
+		os.Exit(1)
+	}
 
-			if err1 == nil && err2 == nil {
-				currentSession.start = startTime
-				currentSession.end = endTime
-				currentSession.duration = endTime.Sub(startTime)
-				history = append(history, *currentSession)
-			}
-			currentSession = nil
-		}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
 	}
 
-	return history
-}
+	dates, err := parseRange(*rangeSpec, time.Now())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 
-func formatDurationLong(d time.Duration) string {
-	hours := int(d.Hours())
-	minutes := int(d.Minutes()) % 60
-	seconds := int(d.Seconds()) % 60
+	if *csvPath != "" {
+		sessions := dates.Filter(history)
+		if err := exportCSV(*csvPath, sessions, cfg); err != nil {
+			fmt.Printf("Error exporting CSV: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Exported %s to %s\n", exportSummary(sessions, cfg), *csvPath)
+		return
+	}
 
-	if hours > 0 {
-		return fmt.Sprintf("%dh %dm %ds", hours, minutes, seconds)
+	// An export has no running session; the one in the data directory
+	// belongs to another history.
+	var active *activeSession
+	if *openPath == "" {
+		active, err = loadActive()
+		if err != nil {
+			fmt.Printf("Error loading running session: %v\n", err)
+			os.Exit(1)
+		}
 	}
-	if minutes > 0 {
-		return fmt.Sprintf("%dm %ds", minutes, seconds)
+
+	if _, ok := cfg.Settings[settingDarkMode]; !ok {
+		cfg.Settings[settingDarkMode] = lipgloss.HasDarkBackground()
+	}
+	applyPalette(cfg.palette())
+
+	m := initialModel(cfg, storage, history, active)
+	m.idleAfter = *idleAfter
+	m.rangeFilter = *rangeSpec
+	m.readOnly = readOnlyReason != ""
+	m.storageKind = *storageKind
+	if m.readOnly {
+		// The instance holding the history pauses for idle time itself.
+		m.idleAfter = 0
+	}
+	if active != nil {
+		m.elapsed = active.elapsed(time.Now())
+		m.remindersSent = int(m.elapsed / trackingReminderEvery)
 	}
-	return fmt.Sprintf("%ds", seconds)
-}
 
-func main() {
-	p := tea.NewProgram(initialModel())
-	if _, err := p.Run(); err != nil {
+	p := tea.NewProgram(m)
+	backups.report = func(err error) { p.Send(backupErrMsg{err}) }
+	final, err := p.Run()
+	if err != nil {
 		fmt.Printf("Error running program: %v", err)
 		os.Exit(1)
 	}
+	if fm, ok := final.(model); ok {
+		fm.flushDelete()
+	}
+	backups.report = nil
+	if err := backups.wait(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
 }
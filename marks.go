@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// toggleMark marks the session at index i in history, or unmarks it.
+func (m *model) toggleMark(i int) {
+	if m.marked == nil {
+		m.marked = make(map[string]bool)
+	}
+	id := m.history[i].ID
+	if m.marked[id] {
+		delete(m.marked, id)
+	} else {
+		m.marked[id] = true
+	}
+}
+
+// toggleGroupMarks marks every session in group, or unmarks them all when they
+// are all marked already.
+func (m *model) toggleGroupMarks(group historyGroup) {
+	all := true
+	for _, i := range group.indices {
+		all = all && m.isMarked(m.history[i])
+	}
+	for _, i := range group.indices {
+		if m.isMarked(m.history[i]) == all {
+			m.toggleMark(i)
+		}
+	}
+}
+
+func (m model) isMarked(sess session) bool {
+	return m.marked[sess.ID]
+}
+
+// markedIndices returns the indices into history of the marked sessions, in
+// start order.
+func (m model) markedIndices() []int {
+	var indices []int
+	for i, sess := range m.history {
+		if m.isMarked(sess) {
+			indices = append(indices, i)
+		}
+	}
+	sort.SliceStable(indices, func(a, b int) bool { return m.history[indices[a]].Start.Before(m.history[indices[b]].Start) })
+	return indices
+}
+
+// mergeMarked asks to combine the marked sessions into one. They must follow
+// each other with no unmarked session in between, so merging never swallows
+// other work.
+func (m model) mergeMarked() (tea.Model, tea.Cmd) {
+	indices := m.markedIndices()
+	if len(indices) < 2 {
+		m.status = fmt.Sprintf("Mark at least two sessions with %s to merge them", m.keys.Mark.Help().Key)
+		return m, nil
+	}
+	m.flushDelete()
+
+	merged := m.history[indices[0]]
+	for _, i := range indices[1:] {
+		merged = combineSessions(merged, m.history[i])
+	}
+	for _, sess := range m.history {
+		if !m.isMarked(sess) && overlaps(sess, merged) {
+			m.status = fmt.Sprintf("%s at %s lies between the marked sessions; mark it too or pick adjacent ones",
+				track.ProjectLabel(sess.Project), sess.Start.Format("Jan 02 15:04"))
+			return m, nil
+		}
+	}
+
+	return m.confirm("🔗 Merge sessions?",
+		fmt.Sprintf("Merge %d sessions into %s, %s – %s (%s).", len(indices), track.ProjectLabel(merged.Project),
+			merged.Start.Format("Jan 02 15:04"), merged.End.Format("15:04"), m.config.duration(merged.Duration)),
+		confirmOption{"n", "Cancel", cancelDialog},
+		confirmOption{"y", "Merge", func(m model) (tea.Model, tea.Cmd) {
+			var before []session
+			for _, i := range indices {
+				before = append(before, m.history[i])
+			}
+			m.audit(auditMerge, before, []session{merged})
+			m.history[indices[0]] = merged
+			rest := slices.Clone(indices[1:])
+			sort.Sort(sort.Reverse(sort.IntSlice(rest)))
+			for _, i := range rest {
+				m.history = slices.Delete(m.history, i, i+1)
+			}
+			m.marked = nil
+			m.cursor = max(0, min(m.cursor, len(m.historyRows())-1))
+			m.changed()
+			m.status = fmt.Sprintf("Merged %d sessions", len(indices))
+			return m, nil
+		}},
+	)
+}
+
+// deleteMarked asks to delete all marked sessions at once. Unlike a single
+// delete this is written straight away rather than held back for undo; the
+// sessions can still be restored from the trash.
+func (m model) deleteMarked() (tea.Model, tea.Cmd) {
+	indices := m.markedIndices()
+	var total time.Duration
+	for _, i := range indices {
+		total += m.history[i].Duration
+	}
+	return m.confirm("🗑  Delete marked sessions?",
+		fmt.Sprintf("%d sessions (%s) will be moved to the trash.", len(indices), m.config.durationLong(total)),
+		confirmOption{"n", "Cancel", cancelDialog},
+		confirmOption{"y", "Delete", func(m model) (tea.Model, tea.Cmd) {
+			m.flushDelete()
+			var deleted []session
+			for _, i := range m.markedIndices() {
+				deleted = append(deleted, m.history[i])
+			}
+			m.deleted(auditDelete, deleted)
+			m.history = slices.DeleteFunc(m.history, m.isMarked)
+			m.marked = nil
+			m.cursor = max(0, min(m.cursor, len(m.historyRows())-1))
+			m.changed()
+			m.status = fmt.Sprintf("Deleted %d sessions", len(indices))
+			return m, nil
+		}},
+	)
+}
+
+// commonTags returns the tags every marked session has.
+func (m model) commonTags() []string {
+	var common []string
+	for n, i := range m.markedIndices() {
+		tags := m.history[i].Tags
+		if n == 0 {
+			common = slices.Clone(tags)
+			continue
+		}
+		common = slices.DeleteFunc(common, func(tag string) bool { return !track.HasTag(tags, tag) })
+	}
+	return common
+}
+
+// startMarkedTagEdit opens the inline editor on the tags the marked sessions
+// share. Tags removed there are taken off every marked session and tags added
+// are given to all of them; other tags are left alone.
+func (m model) startMarkedTagEdit() (tea.Model, tea.Cmd) {
+	m.editing = editMarkedTags
+	m.editInput.Placeholder = "#billable #meeting"
+	m.editInput.SetValue(track.FormatTags(m.commonTags()))
+	m.editInput.CursorEnd()
+	return m, m.editInput.Focus()
+}
+
+func (m *model) applyMarkedTags(value string) {
+	common, tags := m.commonTags(), track.ParseTags(value)
+	m.flushDelete()
+	for _, i := range m.markedIndices() {
+		before := m.history[i]
+		sess := &m.history[i]
+		kept := slices.DeleteFunc(slices.Clone(sess.Tags), func(tag string) bool {
+			return track.HasTag(common, tag) && !track.HasTag(tags, tag)
+		})
+		sess.Tags = track.ParseTags(strings.Join(append(kept, tags...), " "))
+		m.auditEdited(i, before)
+	}
+	m.changed()
+	m.status = fmt.Sprintf("Retagged %d sessions", len(m.markedIndices()))
+}
+
+// startProjectEdit opens the inline editor on the project of the marked
+// sessions, or of the session at target when none are marked.
+func (m model) startProjectEdit(target int) (tea.Model, tea.Cmd) {
+	m.editing = editProject
+	m.editTarget = target
+	m.editInput.Placeholder = "project"
+	indices := m.markedIndices()
+	if len(indices) == 0 {
+		indices = []int{target}
+	}
+	// Suggest the current project when they all share one.
+	project := m.history[indices[0]].Project
+	for _, i := range indices[1:] {
+		if m.history[i].Project != project {
+			project = ""
+			break
+		}
+	}
+	m.editInput.SetValue(project)
+	m.editInput.CursorEnd()
+	return m, m.editInput.Focus()
+}
+
+func (m *model) applyProject(value string) {
+	indices := m.markedIndices()
+	if len(indices) == 0 {
+		if m.editTarget < 0 || m.editTarget >= len(m.history) {
+			return
+		}
+		indices = []int{m.editTarget}
+	}
+	project := strings.TrimSpace(value)
+	m.flushDelete()
+	for _, i := range indices {
+		before := m.history[i]
+		m.history[i].Project = project
+		m.auditEdited(i, before)
+	}
+	m.changed()
+	if len(indices) > 1 {
+		m.status = fmt.Sprintf("Moved %d sessions to %s", len(indices), track.ProjectLabel(project))
+	}
+}
+
+// exportMarked writes the marked sessions, in start order, to the CSV export
+// file.
+func (m *model) exportMarked() {
+	var sessions []session
+	for _, i := range m.markedIndices() {
+		sessions = append(sessions, m.history[i])
+	}
+	if err := exportCSV(csvExportFile, sessions, m.config); err != nil {
+		m.status = fmt.Sprintf("Export failed: %v", err)
+	} else {
+		m.status = fmt.Sprintf("Exported %s, all marked, to %s", exportSummary(sessions, m.config), csvExportFile)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// Values for at_midnight in config.json.
+const (
+	midnightSplit = "split"
+	midnightStop  = "stop"
+)
+
+func checkAtMidnight(s string) error {
+	switch s {
+	case "", midnightSplit, midnightStop:
+		return nil
+	}
+	return fmt.Errorf("invalid at_midnight %q (want %q or %q)", s, midnightSplit, midnightStop)
+}
+
+// nextMidnight returns the start of the day after the one t falls on.
+func nextMidnight(t time.Time) time.Time {
+	y, mo, d := t.Date()
+	return time.Date(y, mo, d+1, 0, 0, 0, 0, t.Location())
+}
+
+// cutAtMidnight applies the at_midnight setting to a running session at now.
+// With "split" every day it has run past is cut off as a finished session
+// and the rest carries on from the last midnight; with "stop" it ends at the
+// first midnight and rest is nil. Otherwise, or if it has not run past
+// midnight, done is empty and rest is a unchanged.
+func (c config) cutAtMidnight(a activeSession, now time.Time) (done []session, rest *activeSession) {
+	if c.AtMidnight != midnightSplit && c.AtMidnight != midnightStop {
+		return nil, &a
+	}
+	for cut := nextMidnight(a.start); !cut.After(now); cut = nextMidnight(a.start) {
+		day := a.finish(now)
+		day.SetBounds(a.start, cut)
+		done = append(done, day)
+		if c.AtMidnight == midnightStop {
+			return done, nil
+		}
+
+		var pauses []pause
+		for _, p := range a.pauses {
+			if !p.End.IsZero() && !p.End.After(cut) {
+				continue
+			}
+			pauses = append(pauses, pause{Start: later(p.Start, cut), End: p.End})
+		}
+		a.start, a.pauses = cut, pauses
+		a.interruptions = slices.DeleteFunc(slices.Clone(a.interruptions), func(in track.Interruption) bool { return in.At.Before(cut) })
+		// The countdown carries on where it was.
+		a.target = max(0, a.target-day.Duration)
+	}
+	return done, &a
+}
+
+// finishSessions ends a at now, as one session or, under at_midnight, as
+// several.
+func (c config) finishSessions(a activeSession, now time.Time) []session {
+	done, rest := c.cutAtMidnight(a, now)
+	if rest != nil {
+		done = append(done, rest.finish(now))
+	}
+	return done
+}
+
+// rollOverMidnight applies at_midnight to the running session once it has
+// run past midnight, adding the finished days to history.
+func (m *model) rollOverMidnight(now time.Time) tea.Cmd {
+	done, rest := m.config.cutAtMidnight(*m.active, now)
+	if len(done) == 0 {
+		return nil
+	}
+	m.addSessions(done)
+
+	var cmds []tea.Cmd
+	for _, sess := range done {
+		cmds = append(cmds, m.webhookCmd(stopEvent(sess)), m.hookCmd(stopEvent(sess)))
+	}
+	if rest == nil {
+		m.active = nil
+		m.elapsed = 0
+		m.idlePaused = false
+		m.idlePrompt = false
+		clearActive()
+		if m.currentView == trackingView {
+			m.currentView = menuView
+		}
+		m.status = "Tracking stopped at midnight"
+		return tea.Batch(append(cmds, m.notifyCmd("Tracking stopped", "The session was stopped at midnight."))...)
+	}
+	m.active = rest
+	m.elapsed = rest.elapsed(now)
+	m.remindersSent = int(m.elapsed / trackingReminderEvery)
+	saveActive(*rest)
+	m.status = "Session split at midnight"
+	return tea.Batch(append(cmds, m.webhookCmd(startEvent(*rest)), m.hookCmd(startEvent(*rest)))...)
+}
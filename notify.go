@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// trackingReminderEvery is how often a long-running session triggers a
+// "still tracking" notification.
+const trackingReminderEvery = 2 * time.Hour
+
+// notifyCmd sends a desktop notification in the background if the
+// Notifications setting is on. Failures are ignored: a missing notifier
+// should never interrupt tracking.
+func (m model) notifyCmd(title, body string) tea.Cmd {
+	if !m.settings[settingNotifications] {
+		return nil
+	}
+	return func() tea.Msg {
+		sendNotification(title, body)
+		return nil
+	}
+}
+
+// alertCmd rings the terminal bell and plays the alert_sound file, if the
+// Sound alerts setting is on, for those who miss the notifications.
+func (m model) alertCmd() tea.Cmd {
+	if !m.settings[settingSound] {
+		return nil
+	}
+	return tea.Batch(bellCmd(), m.soundCmd())
+}
+
+// soundCmd plays the alert_sound file in the background if the Sound alerts
+// setting is on and one is configured. Failures are ignored, as for
+// notifications.
+func (m model) soundCmd() tea.Cmd {
+	if !m.settings[settingSound] || m.config.AlertSound == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		playSound(m.config.AlertSound)
+		return nil
+	}
+}
+
+// trackingReminder returns a notification command each time the running
+// session passes another multiple of trackingReminderEvery.
+func (m *model) trackingReminder() tea.Cmd {
+	if m.active == nil {
+		return nil
+	}
+	reached := int(m.elapsed / trackingReminderEvery)
+	if reached <= m.remindersSent {
+		return nil
+	}
+	m.remindersSent = reached
+	if daemonRunning() {
+		// The daemon sends its own reminders.
+		return nil
+	}
+	return m.notifyCmd("Still tracking",
+		fmt.Sprintf("You've been tracking %s for %s.", track.ProjectLabel(m.active.project), m.config.durationLong(m.elapsed)))
+}
@@ -0,0 +1,18 @@
+//go:build darwin
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+func sendNotification(title, body string) error {
+	script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(body), strconv.Quote(title))
+	return exec.Command("osascript", "-e", script).Run()
+}
+
+func playSound(path string) error {
+	return exec.Command("afplay", path).Run()
+}
@@ -0,0 +1,18 @@
+//go:build linux
+
+package main
+
+import "os/exec"
+
+func sendNotification(title, body string) error {
+	return exec.Command("notify-send", "--app-name=Time Tracker", title, body).Run()
+}
+
+// playSound plays the sound file at path with PulseAudio or, failing that,
+// ALSA.
+func playSound(path string) error {
+	if err := exec.Command("paplay", path).Run(); err == nil {
+		return nil
+	}
+	return exec.Command("aplay", "-q", path).Run()
+}
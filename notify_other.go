@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+func sendNotification(title, body string) error {
+	return nil
+}
+
+func playSound(path string) error {
+	return nil
+}
@@ -0,0 +1,38 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// The scripts read their text from the environment rather than having it
+// spliced in, so no quote character in a project name or path can end a
+// PowerShell string early.
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:TIME_TRACKER_TITLE)) | Out-Null
+$text.Item(1).AppendChild($template.CreateTextNode($env:TIME_TRACKER_BODY)) | Out-Null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('Time Tracker').Show($toast)
+`
+
+const soundScript = `(New-Object Media.SoundPlayer $env:TIME_TRACKER_SOUND).PlaySync()`
+
+// powershell runs script with env added to the environment.
+func powershell(script string, env ...string) error {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.Run()
+}
+
+func sendNotification(title, body string) error {
+	return powershell(toastScript, "TIME_TRACKER_TITLE="+title, "TIME_TRACKER_BODY="+body)
+}
+
+func playSound(path string) error {
+	return powershell(soundScript, "TIME_TRACKER_SOUND="+path)
+}
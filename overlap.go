@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// overlaps reports whether a and b share any time.
+func overlaps(a, b session) bool {
+	return a.Start.Before(b.End) && b.Start.Before(a.End)
+}
+
+// findOverlaps returns the indices of sessions in history that overlap at
+// least one other session.
+func findOverlaps(history []session) map[int]bool {
+	order := make([]int, len(history))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return history[order[a]].Start.Before(history[order[b]].Start) })
+
+	found := make(map[int]bool)
+	for a, i := range order {
+		for _, j := range order[a+1:] {
+			if !history[j].Start.Before(history[i].End) {
+				break
+			}
+			found[i], found[j] = true, true
+		}
+	}
+	return found
+}
+
+// firstOverlap returns the index of the earliest session overlapping the one
+// at i, or -1 if there is none.
+func firstOverlap(history []session, i int) int {
+	found := -1
+	for j, sess := range history {
+		if j != i && overlaps(history[i], sess) && (found < 0 || sess.Start.Before(history[found].Start)) {
+			found = j
+		}
+	}
+	return found
+}
+
+// activeSpans returns the stretches of sess during which the timer ran, as
+// pause values used for their start and end.
+func activeSpans(sess session) []pause {
+	var spans []pause
+	from := sess.Start
+	for _, p := range sess.Pauses {
+		if p.Start.After(from) {
+			spans = append(spans, pause{Start: from, End: p.Start})
+		}
+		from = later(from, p.End)
+	}
+	if sess.End.After(from) {
+		spans = append(spans, pause{Start: from, End: sess.End})
+	}
+	return spans
+}
+
+// combineSessions combines a and b into one session covering both, keeping
+// a's project, billing and issue, both sets of tags, both notes and the
+// interruptions of both. Time during which neither was running becomes
+// pauses, so nothing is counted twice.
+func combineSessions(a, b session) session {
+	spans := append(activeSpans(a), activeSpans(b)...)
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start.Before(spans[j].Start) })
+
+	merged := a
+	merged.Tags = track.ParseTags(strings.Join(append(slices.Clone(a.Tags), b.Tags...), " "))
+	switch {
+	case a.Note == "":
+		merged.Note = b.Note
+	case b.Note != "" && b.Note != a.Note:
+		merged.Note = a.Note + "; " + b.Note
+	}
+	if merged.Issue == "" {
+		merged.Issue = b.Issue
+	}
+	merged.Interruptions = append(slices.Clone(a.Interruptions), b.Interruptions...)
+	sort.SliceStable(merged.Interruptions, func(i, j int) bool { return merged.Interruptions[i].At.Before(merged.Interruptions[j].At) })
+	merged.Start = earlier(a.Start, b.Start)
+	merged.End = later(a.End, b.End)
+	merged.Pauses = nil
+	covered := merged.Start
+	for _, span := range spans {
+		if span.Start.After(covered) {
+			merged.Pauses = append(merged.Pauses, pause{Start: covered, End: span.Start})
+		}
+		covered = later(covered, span.End)
+	}
+	merged.Duration = merged.End.Sub(merged.Start) - track.PausedTotal(merged.Pauses, merged.End)
+	return merged
+}
+
+// resolveOverlap asks how to resolve the overlap between the session at i and
+// the earliest session overlapping it: trim either one so they no longer
+// overlap, or merge them. Trimming is only offered when neither contains the
+// other.
+func (m model) resolveOverlap(i int) (tea.Model, tea.Cmd) {
+	j := firstOverlap(m.history, i)
+	if j < 0 {
+		m.status = "This session does not overlap another"
+		return m, nil
+	}
+	if dialog, ok := m.editable(i, j); !ok {
+		return dialog, nil
+	}
+	m.flushDelete()
+	this, other := m.history[i], m.history[j]
+
+	shared := earlier(this.End, other.End).Sub(later(this.Start, other.Start))
+	message := fmt.Sprintf("%s, %s – %s overlaps\n%s, %s – %s by %s.",
+		track.ProjectLabel(this.Project), this.Start.Format("Jan 02 15:04"), this.End.Format("15:04"),
+		track.ProjectLabel(other.Project), other.Start.Format("Jan 02 15:04"), other.End.Format("15:04"),
+		m.config.durationLong(shared))
+
+	// trim cuts the session at k back so that it ends where the one at keep
+	// starts, or starts where it ends.
+	trim := func(k, keep int) func(m model) (tea.Model, tea.Cmd) {
+		return func(m model) (tea.Model, tea.Cmd) {
+			sess, kept := &m.history[k], m.history[keep]
+			before := *sess
+			if sess.Start.Before(kept.Start) {
+				sess.SetBounds(sess.Start, kept.Start)
+			} else {
+				sess.SetBounds(kept.End, sess.End)
+			}
+			m.audit(auditTrim, []session{before}, []session{*sess})
+			m.changed()
+			m.status = "Overlap trimmed"
+			return m, nil
+		}
+	}
+	merge := func(m model) (tea.Model, tea.Cmd) {
+		m.history[i] = combineSessions(this, other)
+		m.audit(auditMerge, []session{this, other}, []session{m.history[i]})
+		m.history = slices.Delete(m.history, j, j+1)
+		if m.cursor > 0 && m.cursor >= len(m.historyRows()) {
+			m.cursor--
+		}
+		m.changed()
+		m.status = "Sessions merged"
+		return m, nil
+	}
+
+	options := []confirmOption{{"n", "Cancel", cancelDialog}}
+	contained := !this.Start.Before(other.Start) && !this.End.After(other.End) ||
+		!other.Start.Before(this.Start) && !other.End.After(this.End)
+	if !contained {
+		options = append(options,
+			confirmOption{"t", "Trim this", trim(i, j)},
+			confirmOption{"o", "Trim other", trim(j, i)},
+		)
+	}
+	options = append(options, confirmOption{"m", "Merge", merge})
+	return m.confirm("⚠  Overlapping sessions", message, options...)
+}
@@ -0,0 +1,101 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// NoTagLabel labels the group of sessions without tags.
+const NoTagLabel = "(no tag)"
+
+// KeyFunc returns the group sess belongs in: a key that sorts the groups and
+// a label to show for it.
+type KeyFunc func(sess track.Session) (key, label string)
+
+// Group is the sessions sharing one key, with their total active time.
+type Group struct {
+	Key      string
+	Label    string
+	Sessions []track.Session
+	Total    time.Duration
+}
+
+// GroupBy groups history by key, sorted by key, newest first for dates.
+func GroupBy(history []track.Session, key KeyFunc) []Group {
+	lookup := make(map[string]int)
+	var groups []Group
+	for _, sess := range history {
+		k, label := key(sess)
+		i, ok := lookup[k]
+		if !ok {
+			i = len(groups)
+			lookup[k] = i
+			groups = append(groups, Group{Key: k, Label: label})
+		}
+		groups[i].Sessions = append(groups[i].Sessions, sess)
+		groups[i].Total += sess.Duration
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key > groups[j].Key })
+	return groups
+}
+
+// Total sums the active time of sessions.
+func Total(sessions []track.Session) time.Duration {
+	var total time.Duration
+	for _, sess := range sessions {
+		total += sess.Duration
+	}
+	return total
+}
+
+func ByDay(sess track.Session) (string, string) {
+	return sess.Start.Format(DateLayout), sess.Start.Format("Mon Jan 02, 2006")
+}
+
+func ByWeek(sess track.Session) (string, string) {
+	year, week := sess.Start.ISOWeek()
+	monday := StartOfISOWeek(sess.Start)
+	sunday := monday.AddDate(0, 0, 6)
+	return fmt.Sprintf("%04d-W%02d", year, week),
+		fmt.Sprintf("%04d-W%02d (%s – %s)", year, week, monday.Format("Jan 02"), sunday.Format("Jan 02"))
+}
+
+func ByProject(sess track.Session) (string, string) {
+	return sess.Project, track.ProjectLabel(sess.Project)
+}
+
+// ByTag groups by a session's first tag; see SplitTags.
+func ByTag(sess track.Session) (string, string) {
+	if len(sess.Tags) == 0 {
+		return "", NoTagLabel
+	}
+	return sess.Tags[0], "#" + sess.Tags[0]
+}
+
+// SplitTags returns history with every session that has several tags
+// repeated once per tag, so ByTag counts it towards each of them. The groups
+// can then add up to more than the time tracked.
+func SplitTags(history []track.Session) []track.Session {
+	var tagged []track.Session
+	for _, sess := range history {
+		if len(sess.Tags) == 0 {
+			tagged = append(tagged, sess)
+		}
+		for _, tag := range sess.Tags {
+			one := sess
+			one.Tags = []string{tag}
+			tagged = append(tagged, one)
+		}
+	}
+	return tagged
+}
+
+// Largest sorts groups by their total, largest first, keeping the order of
+// groups with equal totals.
+func Largest(groups []Group) []Group {
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Total > groups[j].Total })
+	return groups
+}
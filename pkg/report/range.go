@@ -0,0 +1,113 @@
+// Package report totals time-tracker sessions over date ranges, per day,
+// week, project or tag, for programs that build their own reports from a
+// history read with package track.
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// DateLayout is how days are written in range specs.
+const DateLayout = "2006-01-02"
+
+// Range limits sessions to those starting in [From, To). A zero From or To
+// leaves that side open.
+type Range struct {
+	Label    string
+	From, To time.Time
+}
+
+func (r Range) Contains(t time.Time) bool {
+	if !r.From.IsZero() && t.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && !t.Before(r.To) {
+		return false
+	}
+	return true
+}
+
+// Filter returns the sessions in history that start within r.
+func (r Range) Filter(history []track.Session) []track.Session {
+	if r.From.IsZero() && r.To.IsZero() {
+		return history
+	}
+	var filtered []track.Session
+	for _, sess := range history {
+		if r.Contains(sess.Start) {
+			filtered = append(filtered, sess)
+		}
+	}
+	return filtered
+}
+
+// ParseRange reads a range spec relative to now: "" for everything, one of
+// "today", "week", "month" and "last-month", a single YYYY-MM-DD day, or
+// "FROM..TO" with inclusive YYYY-MM-DD days where either side may be left
+// out.
+func ParseRange(spec string, now time.Time) (Range, error) {
+	y, mo, d := now.Date()
+	today := time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+
+	switch spec {
+	case "", "all":
+		return Range{Label: "All time"}, nil
+	case "today":
+		return Range{Label: "Today", From: today, To: today.AddDate(0, 0, 1)}, nil
+	case "week":
+		monday := StartOfISOWeek(now)
+		return Range{Label: "This week", From: monday, To: monday.AddDate(0, 0, 7)}, nil
+	case "month":
+		first := time.Date(y, mo, 1, 0, 0, 0, 0, now.Location())
+		return Range{Label: "This month", From: first, To: first.AddDate(0, 1, 0)}, nil
+	case "last-month":
+		first := time.Date(y, mo, 1, 0, 0, 0, 0, now.Location())
+		return Range{Label: "Last month", From: first.AddDate(0, -1, 0), To: first}, nil
+	}
+
+	fromStr, toStr, isRange := strings.Cut(spec, "..")
+	if !isRange {
+		toStr = fromStr
+	}
+	var r Range
+	if fromStr != "" {
+		from, err := time.ParseInLocation(DateLayout, fromStr, now.Location())
+		if err != nil {
+			return r, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", fromStr)
+		}
+		r.From = from
+	}
+	if toStr != "" {
+		last, err := time.ParseInLocation(DateLayout, toStr, now.Location())
+		if err != nil {
+			return r, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", toStr)
+		}
+		r.To = last.AddDate(0, 0, 1)
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && !r.From.Before(r.To) {
+		return r, fmt.Errorf("range %q ends before it starts", spec)
+	}
+
+	switch {
+	case !isRange:
+		r.Label = r.From.Format("Mon Jan 02, 2006")
+	case r.From.IsZero():
+		r.Label = "Until " + r.To.AddDate(0, 0, -1).Format("Jan 02, 2006")
+	case r.To.IsZero():
+		r.Label = "Since " + r.From.Format("Jan 02, 2006")
+	default:
+		r.Label = r.From.Format("Jan 02, 2006") + " – " + r.To.AddDate(0, 0, -1).Format("Jan 02, 2006")
+	}
+	return r, nil
+}
+
+// StartOfISOWeek returns midnight on the Monday of t's ISO week.
+func StartOfISOWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	y, m, d := t.Date()
+	return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location())
+}
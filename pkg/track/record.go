@@ -0,0 +1,147 @@
+package track
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// StoreVersion is the current version of the sessions.json layout. Bump it
+// whenever the on-disk shape changes and teach Decode to upgrade older files.
+const StoreVersion = 5
+
+// storeFile is the on-disk layout of sessions.json.
+type storeFile struct {
+	Version  int      `json:"version"`
+	Sessions []Record `json:"sessions"`
+}
+
+// Record is a session as it is stored in sessions.json and the other JSON
+// files holding sessions.
+type Record struct {
+	ID       string        `json:"id,omitempty"`
+	Project  string        `json:"project,omitempty"`
+	Note     string        `json:"note,omitempty"`
+	Tags     []string      `json:"tags,omitempty"`
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Pauses   []PauseRecord `json:"pauses,omitempty"`
+	Billable bool          `json:"billable"`
+	Issue    string        `json:"issue,omitempty"`
+
+	Interruptions []InterruptionRecord `json:"interruptions,omitempty"`
+}
+
+type PauseRecord struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end,omitzero"`
+}
+
+type InterruptionRecord struct {
+	At   time.Time `json:"at"`
+	Note string    `json:"note,omitempty"`
+}
+
+func ToRecord(sess Session) Record {
+	rec := Record{
+		ID:       sess.ID,
+		Project:  sess.Project,
+		Note:     sess.Note,
+		Tags:     sess.Tags,
+		Start:    sess.Start,
+		End:      sess.End,
+		Billable: sess.Billable,
+		Issue:    sess.Issue,
+	}
+	for _, p := range sess.Pauses {
+		rec.Pauses = append(rec.Pauses, PauseRecord{Start: p.Start, End: p.End})
+	}
+	for _, in := range sess.Interruptions {
+		rec.Interruptions = append(rec.Interruptions, InterruptionRecord{At: in.At, Note: in.Note})
+	}
+	return rec
+}
+
+// FromRecord turns rec back into a session in the local time zone, working
+// out its duration and, for records from before sessions had IDs, its ID.
+func FromRecord(rec Record) Session {
+	sess := Session{
+		ID:       rec.ID,
+		Project:  rec.Project,
+		Note:     rec.Note,
+		Tags:     rec.Tags,
+		Start:    rec.Start,
+		End:      rec.End,
+		Billable: rec.Billable,
+		Issue:    rec.Issue,
+	}
+	for _, p := range rec.Pauses {
+		sess.Pauses = append(sess.Pauses, Pause{Start: p.Start, End: p.End})
+	}
+	for _, in := range rec.Interruptions {
+		sess.Interruptions = append(sess.Interruptions, Interruption{At: in.At, Note: in.Note})
+	}
+	sess.Duration = sess.End.Sub(sess.Start) - PausedTotal(sess.Pauses, sess.End)
+	if sess.ID == "" {
+		// Sessions got IDs in version 3.
+		sess.ID = LegacyID(sess)
+	}
+	return sess.InLocal()
+}
+
+// Decode parses data in the sessions.json layout, upgrading older versions.
+// name identifies the data in errors.
+func Decode(name string, data []byte) ([]Session, error) {
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", name, err)
+	}
+	if file.Version > StoreVersion {
+		return nil, fmt.Errorf("%s has version %d, newer than supported version %d", name, file.Version, StoreVersion)
+	}
+
+	history := make([]Session, 0, len(file.Sessions))
+	for _, rec := range file.Sessions {
+		sess := FromRecord(rec)
+		if file.Version < 2 {
+			// Version 1 had no billable flag; everything was billable.
+			sess.Billable = true
+		}
+		history = append(history, sess)
+	}
+	return history, nil
+}
+
+// Encode renders history in the current sessions.json layout.
+func Encode(history []Session) ([]byte, error) {
+	file := storeFile{
+		Version:  StoreVersion,
+		Sessions: make([]Record, 0, len(history)),
+	}
+	for _, sess := range history {
+		file.Sessions = append(file.Sessions, ToRecord(sess))
+	}
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// WriteJSON writes history to w in the sessions.json layout, which ReadJSON
+// reads back without loss.
+func WriteJSON(w io.Writer, history []Session) error {
+	data, err := Encode(history)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ReadJSON reads sessions written by WriteJSON, or a sessions.json file of
+// any supported version.
+func ReadJSON(r io.Reader, name string) ([]Session, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(name, data)
+}
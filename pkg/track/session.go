@@ -0,0 +1,245 @@
+// Package track is the time tracking domain of time-tracker without its
+// terminal UI: sessions and their pauses, the sessions.json layout, storage
+// backends and duration formatting. Other Go programs can import it to read
+// and write the same history the time-tracker command does.
+package track
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NoProjectLabel stands in for the empty project name.
+const NoProjectLabel = "(no project)"
+
+// Session is a stretch of tracked work.
+type Session struct {
+	// ID identifies the session in storage for good, whatever is edited.
+	ID       string
+	Project  string
+	Note     string
+	Tags     []string
+	Start    time.Time
+	End      time.Time
+	Duration time.Duration // active time, excluding pauses
+	Pauses   []Pause
+	Billable bool
+	// Issue is the URL of the issue or pull request worked on, if any.
+	Issue string
+	// Interruptions are the moments the work was interrupted, in order.
+	Interruptions []Interruption
+}
+
+// NewID returns the ID for a new session.
+func NewID() string {
+	return uuid.NewString()
+}
+
+// LegacyID derives an ID for a session stored before sessions had them from
+// its start and project, so it stays the same every time the session is
+// loaded until it is next saved with it.
+func LegacyID(sess Session) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(sess.Start.UTC().Format(time.RFC3339Nano)+"\x00"+sess.Project)).String()
+}
+
+// Pause is an interval during which the timer was not counting. A pause with
+// a zero end is still in progress.
+type Pause struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Interruption is a moment work on a session was interrupted, logged while
+// it ran.
+type Interruption struct {
+	At   time.Time
+	Note string
+}
+
+// InLocal moves the times of sess into the local time zone, so sessions
+// recorded under another UTC offset are shown, grouped into days and cut at
+// midnight by the clock on the wall now. Durations are unaffected: they are
+// differences between instants, which neither time zones nor daylight saving
+// changes move.
+func (sess Session) InLocal() Session {
+	sess.Start, sess.End = sess.Start.Local(), sess.End.Local()
+	sess.Pauses = PausesInLocal(sess.Pauses)
+	sess.Interruptions = InterruptionsInLocal(sess.Interruptions)
+	return sess
+}
+
+// InterruptionsInLocal returns interruptions with their times in the local
+// time zone.
+func InterruptionsInLocal(interruptions []Interruption) []Interruption {
+	var local []Interruption
+	for _, in := range interruptions {
+		in.At = in.At.Local()
+		local = append(local, in)
+	}
+	return local
+}
+
+// PausesInLocal returns pauses with their times in the local time zone. Zero
+// ends of open pauses stay zero.
+func PausesInLocal(pauses []Pause) []Pause {
+	var local []Pause
+	for _, p := range pauses {
+		p.Start = p.Start.Local()
+		if !p.End.IsZero() {
+			p.End = p.End.Local()
+		}
+		local = append(local, p)
+	}
+	return local
+}
+
+// Check reports what makes sess impossible: a missing start or end, an end
+// before its start, a pause that ends before it starts or lies outside the
+// session, or more time paused than the session spans. It returns nil for a
+// sound session.
+func (sess Session) Check() error {
+	switch {
+	case sess.Start.IsZero() || sess.End.IsZero():
+		return errors.New("missing start or end")
+	case sess.End.Before(sess.Start):
+		return errors.New("ends before it starts")
+	}
+	for _, p := range sess.Pauses {
+		switch {
+		case p.End.IsZero():
+			return errors.New("has a pause that never ends")
+		case p.End.Before(p.Start):
+			return errors.New("has a pause that ends before it starts")
+		case p.Start.Before(sess.Start) || p.End.After(sess.End):
+			return errors.New("has a pause outside it")
+		}
+	}
+	if sess.Duration < 0 {
+		return errors.New("has more time paused than it spans")
+	}
+	return nil
+}
+
+// SetBounds moves sess to [start, end), dropping or clipping pauses and
+// dropping interruptions that no longer fit, and recomputing its duration.
+func (sess *Session) SetBounds(start, end time.Time) {
+	var pauses []Pause
+	for _, p := range sess.Pauses {
+		if !p.End.After(start) || !p.Start.Before(end) {
+			continue
+		}
+		pauses = append(pauses, Pause{Start: later(p.Start, start), End: earlier(p.End, end)})
+	}
+	var interruptions []Interruption
+	for _, in := range sess.Interruptions {
+		if !in.At.Before(start) && in.At.Before(end) {
+			interruptions = append(interruptions, in)
+		}
+	}
+	sess.Start, sess.End, sess.Pauses, sess.Interruptions = start, end, pauses, interruptions
+	sess.Duration = end.Sub(start) - PausedTotal(pauses, end)
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// ClockOn returns the time offset past midnight on the wall clock of day's
+// date, so 09:00 stays 09:00 on days that are 23 or 25 hours long. Wall times
+// skipped by a daylight saving change come out past the change.
+func ClockOn(day time.Time, offset time.Duration) time.Time {
+	y, mo, d := day.Date()
+	return time.Date(y, mo, d, 0, 0, int(offset/time.Second), int(offset%time.Second), day.Location())
+}
+
+// SinceMidnight returns how far past midnight t is on the wall clock.
+func SinceMidnight(t time.Time) time.Duration {
+	h, mi, sec := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(mi)*time.Minute + time.Duration(sec)*time.Second +
+		time.Duration(t.Nanosecond())
+}
+
+// PausedTotal sums the length of pauses, counting an open pause up to now.
+func PausedTotal(pauses []Pause, now time.Time) time.Duration {
+	var total time.Duration
+	for _, p := range pauses {
+		end := p.End
+		if end.IsZero() {
+			end = now
+		}
+		total += end.Sub(p.Start)
+	}
+	return total
+}
+
+// ProjectLabel returns project, or NoProjectLabel if it is empty.
+func ProjectLabel(project string) string {
+	if project == "" {
+		return NoProjectLabel
+	}
+	return project
+}
+
+// ParseTags splits s into tags on whitespace and commas, dropping any leading
+// '#' and duplicates.
+func ParseTags(s string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	for _, f := range fields {
+		tag := strings.TrimLeft(f, "#")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// SplitProjectTags separates "#tag" words from the project name in input, so
+// "website #billable" starts project "website" tagged "billable".
+func SplitProjectTags(input string) (string, []string) {
+	var words, tagWords []string
+	for _, w := range strings.Fields(input) {
+		if strings.HasPrefix(w, "#") {
+			tagWords = append(tagWords, w)
+		} else {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " "), ParseTags(strings.Join(tagWords, " "))
+}
+
+// FormatTags renders tags as "#a #b".
+func FormatTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return "#" + strings.Join(tags, " #")
+}
+
+// HasTag reports whether tag is among tags.
+func HasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
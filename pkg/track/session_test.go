@@ -0,0 +1,156 @@
+package track
+
+import (
+	"testing"
+	"time"
+)
+
+// berlin makes Europe/Berlin the local time zone for the test. In 2026 its
+// clocks go forward an hour at 02:00 on March 29 and back at 03:00 on
+// October 25.
+func berlin(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	local := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = local })
+	return loc
+}
+
+func utc(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestInLocalAcrossDST(t *testing.T) {
+	berlin(t)
+	tests := []struct {
+		name             string
+		start, end       string // UTC
+		pause            [2]string
+		wantStart        string // wall clock in Berlin
+		wantEnd          string
+		wantPauseStart   string
+		wantPauseEnd     string
+		wantSpan, paused time.Duration
+	}{
+		{
+			name:  "spring forward",
+			start: "2026-03-29T00:00:00Z", end: "2026-03-29T02:00:00Z",
+			pause:     [2]string{"2026-03-29T00:30:00Z", "2026-03-29T01:30:00Z"},
+			wantStart: "01:00 CET", wantEnd: "04:00 CEST",
+			wantPauseStart: "01:30 CET", wantPauseEnd: "03:30 CEST",
+			wantSpan: 2 * time.Hour, paused: time.Hour,
+		},
+		{
+			name:  "fall back",
+			start: "2026-10-24T23:30:00Z", end: "2026-10-25T02:30:00Z",
+			pause:     [2]string{"2026-10-25T00:30:00Z", "2026-10-25T01:30:00Z"},
+			wantStart: "01:30 CEST", wantEnd: "03:30 CET",
+			wantPauseStart: "02:30 CEST", wantPauseEnd: "02:30 CET",
+			wantSpan: 3 * time.Hour, paused: time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess := Session{
+				Start:  utc(tt.start),
+				End:    utc(tt.end),
+				Pauses: []Pause{{Start: utc(tt.pause[0]), End: utc(tt.pause[1])}},
+			}
+			sess.Duration = sess.End.Sub(sess.Start) - PausedTotal(sess.Pauses, sess.End)
+			local := sess.InLocal()
+
+			if got := local.Start.Format("15:04 MST"); got != tt.wantStart {
+				t.Errorf("start = %s, want %s", got, tt.wantStart)
+			}
+			if got := local.End.Format("15:04 MST"); got != tt.wantEnd {
+				t.Errorf("end = %s, want %s", got, tt.wantEnd)
+			}
+			p := local.Pauses[0]
+			if got := p.Start.Format("15:04 MST"); got != tt.wantPauseStart {
+				t.Errorf("pause start = %s, want %s", got, tt.wantPauseStart)
+			}
+			if got := p.End.Format("15:04 MST"); got != tt.wantPauseEnd {
+				t.Errorf("pause end = %s, want %s", got, tt.wantPauseEnd)
+			}
+			if got := local.End.Sub(local.Start); got != tt.wantSpan {
+				t.Errorf("span = %s, want %s", got, tt.wantSpan)
+			}
+			if got := PausedTotal(local.Pauses, local.End); got != tt.paused {
+				t.Errorf("paused = %s, want %s", got, tt.paused)
+			}
+			if local.Duration != tt.wantSpan-tt.paused {
+				t.Errorf("duration = %s, want %s", local.Duration, tt.wantSpan-tt.paused)
+			}
+		})
+	}
+}
+
+func TestPausesInLocalKeepsOpenPause(t *testing.T) {
+	berlin(t)
+	pauses := PausesInLocal([]Pause{{Start: utc("2026-03-29T00:30:00Z")}})
+	if !pauses[0].End.IsZero() {
+		t.Errorf("open pause end = %v, want zero", pauses[0].End)
+	}
+	if got := pauses[0].Start.Format("15:04 MST"); got != "01:30 CET" {
+		t.Errorf("pause start = %s, want 01:30 CET", got)
+	}
+}
+
+func TestClockOnAcrossDST(t *testing.T) {
+	loc := berlin(t)
+	tests := []struct {
+		name      string
+		day       time.Time
+		dayLength time.Duration
+		noon      string // UTC instant of 12:00 on the wall clock
+	}{
+		{"spring forward", time.Date(2026, 3, 29, 15, 0, 0, 0, loc), 23 * time.Hour, "2026-03-29T10:00:00Z"},
+		{"fall back", time.Date(2026, 10, 25, 15, 0, 0, 0, loc), 25 * time.Hour, "2026-10-25T11:00:00Z"},
+		{"ordinary day", time.Date(2026, 6, 1, 15, 0, 0, 0, loc), 24 * time.Hour, "2026-06-01T10:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			midnight := ClockOn(tt.day, 0)
+			if got := midnight.Format("2006-01-02 15:04"); got != tt.day.Format("2006-01-02")+" 00:00" {
+				t.Errorf("ClockOn(day, 0) = %s, want midnight", got)
+			}
+			if got := ClockOn(tt.day, 24*time.Hour).Sub(midnight); got != tt.dayLength {
+				t.Errorf("day length = %s, want %s", got, tt.dayLength)
+			}
+			noon := ClockOn(tt.day, 12*time.Hour)
+			if !noon.Equal(utc(tt.noon)) {
+				t.Errorf("ClockOn(day, 12h) = %s, want %s", noon.UTC(), tt.noon)
+			}
+			if got := SinceMidnight(noon); got != 12*time.Hour {
+				t.Errorf("SinceMidnight(noon) = %s, want 12h", got)
+			}
+		})
+	}
+}
+
+func TestSinceMidnightAcrossDST(t *testing.T) {
+	berlin(t)
+	tests := []struct {
+		at   string // UTC
+		want time.Duration
+	}{
+		{"2026-03-29T00:59:00Z", time.Hour + 59*time.Minute},   // 01:59 CET
+		{"2026-03-29T01:00:00Z", 3 * time.Hour},                // 03:00 CEST, 02:00 never happens
+		{"2026-10-25T00:30:00Z", 2*time.Hour + 30*time.Minute}, // 02:30 CEST, first pass
+		{"2026-10-25T01:30:00Z", 2*time.Hour + 30*time.Minute}, // 02:30 CET, second pass
+		{"2026-10-25T22:59:00Z", 23*time.Hour + 59*time.Minute},
+	}
+	for _, tt := range tests {
+		if got := SinceMidnight(utc(tt.at).Local()); got != tt.want {
+			t.Errorf("SinceMidnight(%s) = %s, want %s", tt.at, got, tt.want)
+		}
+	}
+}
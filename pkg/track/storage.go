@@ -0,0 +1,157 @@
+package track
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Storage persists the session history. Load returns sessions in the order
+// they were saved and appended, and implementations must keep that order
+// stable across Append and Delete so the TUI can mirror changes in memory
+// without reloading. Single sessions are addressed by their ID.
+type Storage interface {
+	// Load returns every stored session.
+	Load() ([]Session, error)
+	// Save replaces the stored sessions with history.
+	Save(history []Session) error
+	// Append adds sess after the existing sessions.
+	Append(sess Session) error
+	// Delete removes the session with the given ID.
+	Delete(id string) error
+}
+
+// MemoryStorage keeps sessions in memory only. Nothing survives a restart; it
+// serves as a reference backend and for callers that must not touch disk.
+type MemoryStorage struct {
+	Sessions []Session
+}
+
+func (s *MemoryStorage) Load() ([]Session, error) {
+	return append([]Session{}, s.Sessions...), nil
+}
+
+func (s *MemoryStorage) Save(history []Session) error {
+	s.Sessions = append([]Session{}, history...)
+	return nil
+}
+
+func (s *MemoryStorage) Append(sess Session) error {
+	s.Sessions = append(s.Sessions, sess)
+	return nil
+}
+
+func (s *MemoryStorage) Delete(id string) error {
+	i := slices.IndexFunc(s.Sessions, func(sess Session) bool { return sess.ID == id })
+	if i < 0 {
+		return fmt.Errorf("no session with ID %s", id)
+	}
+	s.Sessions = slices.Delete(s.Sessions, i, i+1)
+	return nil
+}
+
+// JSONStorage keeps the history in a sessions.json file at Path, such as the
+// one in time-tracker's data directory. A missing file is an empty history.
+// By itself it does not read encrypted files; those need the time-tracker
+// command, which sets the hooks below.
+type JSONStorage struct {
+	Path string
+
+	// ReadFile and WriteFile, when set, replace reading Path and writing it
+	// atomically, e.g. to decrypt and encrypt it.
+	ReadFile  func(path string) ([]byte, error)
+	WriteFile func(path string, data []byte) error
+	// Missing, when set, supplies the history while Path does not exist yet,
+	// e.g. from an older format.
+	Missing func() ([]Session, error)
+}
+
+func (s *JSONStorage) Load() ([]Session, error) {
+	read := s.ReadFile
+	if read == nil {
+		read = os.ReadFile
+	}
+	data, err := read(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if s.Missing != nil {
+			return s.Missing()
+		}
+		return []Session{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Decode(s.Path, data)
+}
+
+func (s *JSONStorage) Save(history []Session) error {
+	data, err := Encode(history)
+	if err != nil {
+		return err
+	}
+	if s.WriteFile != nil {
+		return s.WriteFile(s.Path, data)
+	}
+	return WriteFileAtomic(s.Path, data, 0644)
+}
+
+// Append loads the file, adds sess and writes it back.
+func (s *JSONStorage) Append(sess Session) error {
+	history, err := s.Load()
+	if err != nil {
+		return err
+	}
+	return s.Save(append(history, sess))
+}
+
+// Delete loads the file, removes the session with the given ID and writes
+// it back.
+func (s *JSONStorage) Delete(id string) error {
+	history, err := s.Load()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(history, func(sess Session) bool { return sess.ID == id })
+	if i < 0 {
+		return fmt.Errorf("no session with ID %s", id)
+	}
+	return s.Save(slices.Delete(history, i, i+1))
+}
+
+// WriteFileAtomic writes data to path so that a crash part way through leaves
+// either the old file or the new one, never a mix: the data goes to a
+// temporary file in the same directory, is synced to disk and then renamed
+// over path.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
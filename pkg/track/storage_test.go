@@ -0,0 +1,47 @@
+package track
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJSONStorageHooks(t *testing.T) {
+	start := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	legacy := Session{ID: "old", Project: "p", Start: start, End: start.Add(time.Hour), Duration: time.Hour}
+	files := map[string][]byte{}
+	s := &JSONStorage{
+		Path: filepath.Join(t.TempDir(), "sessions.json"),
+		ReadFile: func(path string) ([]byte, error) {
+			data, ok := files[path]
+			if !ok {
+				return nil, fs.ErrNotExist
+			}
+			return data, nil
+		},
+		WriteFile: func(path string, data []byte) error {
+			files[path] = data
+			return nil
+		},
+		Missing: func() ([]Session, error) { return []Session{legacy}, nil },
+	}
+
+	// Appending to a missing file builds on what Missing supplies.
+	sess := legacy
+	sess.ID = "new"
+	if err := s.Append(sess); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.Path); !os.IsNotExist(err) {
+		t.Errorf("Save wrote %s itself rather than through WriteFile", s.Path)
+	}
+	history, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].ID != "old" || history[1].ID != "new" {
+		t.Errorf("history = %v, want old and new", history)
+	}
+}
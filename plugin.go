@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// What a plugin can provide, as listed in its "provides".
+const (
+	provideExport = "export"
+	provideImport = "import"
+	providePush   = "push"
+	provideEvents = "events"
+)
+
+// pluginProtocol is the version of the requests sent to plugins, so they can
+// tell if they are run by a time-tracker they do not understand.
+const pluginProtocol = 1
+
+// pluginTimeout bounds how long a plugin may take to answer.
+const pluginTimeout = 2 * time.Minute
+
+// exporter writes sessions in one format for `export -format`.
+type exporter interface {
+	Export(w io.Writer, sessions []session, cfg config) error
+}
+
+// importer reads sessions from path for `import -from`.
+type importer interface {
+	Import(path string) ([]session, error)
+}
+
+// syncTarget receives sessions for `push`, returning what to tell the user.
+type syncTarget interface {
+	Push(sessions []session, cfg config) (string, error)
+}
+
+type exportFunc func(w io.Writer, sessions []session, cfg config) error
+
+func (f exportFunc) Export(w io.Writer, sessions []session, cfg config) error {
+	return f(w, sessions, cfg)
+}
+
+type importFunc func(path string) ([]session, error)
+
+func (f importFunc) Import(path string) ([]session, error) {
+	return f(path)
+}
+
+// exporters, importers and syncTargets are the formats and targets built
+// into the binary, by name. Plugins from config.json come on top of them.
+var (
+	exporters = map[string]exporter{
+		"json": exportFunc(func(w io.Writer, sessions []session, _ config) error { return track.WriteJSON(w, sessions) }),
+		"ics":  exportFunc(writeICS),
+		"csv":  exportFunc(writeCSV),
+	}
+	importers = map[string]importer{
+		importJSON:        importFunc(loadJSONExport),
+		importTimewarrior: importFunc(loadTimewarrior),
+		importWatson:      importFunc(loadWatson),
+	}
+	syncTargets = map[string]syncTarget{}
+)
+
+// registerExporter, registerImporter and registerSyncTarget add compiled-in
+// plugins: a Go file dropped into this package calls them from its init
+// function.
+func registerExporter(name string, e exporter)     { exporters[name] = e }
+func registerImporter(name string, i importer)     { importers[name] = i }
+func registerSyncTarget(name string, t syncTarget) { syncTargets[name] = t }
+
+// pluginConfig is a program in config.json that adds formats and targets
+// under its name, spoken to in JSON over stdin and stdout.
+type pluginConfig struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	// Provides lists what it does: export, import, push and events.
+	Provides []string `json:"provides"`
+}
+
+func (p pluginConfig) provides(what string) bool {
+	return slices.Contains(p.Provides, what)
+}
+
+func checkPlugins(plugins []pluginConfig) error {
+	seen := make(map[string]bool)
+	for _, p := range plugins {
+		switch {
+		case p.Name == "" || strings.ContainsAny(p.Name, " \t"):
+			return fmt.Errorf("invalid plugin name %q", p.Name)
+		case seen[p.Name]:
+			return fmt.Errorf("plugin %s is listed twice", p.Name)
+		case p.Command == "":
+			return fmt.Errorf("plugin %s has no command", p.Name)
+		case len(p.Provides) == 0:
+			return fmt.Errorf("plugin %s provides nothing; list export, import, push or events", p.Name)
+		}
+		seen[p.Name] = true
+		for _, what := range p.Provides {
+			var builtin bool
+			switch what {
+			case provideExport:
+				_, builtin = exporters[p.Name]
+			case provideImport:
+				_, builtin = importers[p.Name]
+			case providePush:
+				_, builtin = syncTargets[p.Name]
+			case provideEvents:
+			default:
+				return fmt.Errorf("plugin %s: unknown %q in provides (want %s, %s, %s or %s)", p.Name, what,
+					provideExport, provideImport, providePush, provideEvents)
+			}
+			if builtin {
+				return fmt.Errorf("plugin %s: %s %s is built in", p.Name, what, p.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// plugin returns the plugin providing what under name.
+func (c config) plugin(name, what string) (execPlugin, bool) {
+	for _, p := range c.Plugins {
+		if p.Name == name && p.provides(what) {
+			return execPlugin{p}, true
+		}
+	}
+	return execPlugin{}, false
+}
+
+func (c config) exporter(name string) (exporter, error) {
+	if e, ok := exporters[name]; ok {
+		return e, nil
+	}
+	if p, ok := c.plugin(name, provideExport); ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown format %q (want %s)", name, orList(available(c, exporters, provideExport)))
+}
+
+func (c config) importer(name string) (importer, error) {
+	if i, ok := importers[name]; ok {
+		return i, nil
+	}
+	if p, ok := c.plugin(name, provideImport); ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown import format %q (want %s)", name, orList(available(c, importers, provideImport)))
+}
+
+func (c config) syncTarget(name string) (syncTarget, error) {
+	if t, ok := syncTargets[name]; ok {
+		return t, nil
+	}
+	if p, ok := c.plugin(name, providePush); ok {
+		return p, nil
+	}
+	if names := available(c, syncTargets, providePush); len(names) > 0 {
+		return nil, fmt.Errorf("unknown sync target %q (want %s)", name, orList(names))
+	}
+	return nil, fmt.Errorf("unknown sync target %q; add a plugin providing push to %s", name, configFile)
+}
+
+// available lists the built-in names in builtin and the plugins in cfg
+// providing what, sorted.
+func available[T any](cfg config, builtin map[string]T, what string) []string {
+	names := slices.Collect(maps.Keys(builtin))
+	for _, p := range cfg.Plugins {
+		if p.provides(what) {
+			names = append(names, p.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// orList renders names as "a, b or c".
+func orList(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// pluginRequest is written to a plugin's stdin. Only the fields its action
+// needs are set.
+type pluginRequest struct {
+	Protocol int            `json:"protocol"`
+	Action   string         `json:"action"` // export, import, push or event
+	Sessions []track.Record `json:"sessions,omitempty"`
+	Path     string         `json:"path,omitempty"`
+	Event    *webhookEvent  `json:"event,omitempty"`
+}
+
+// pluginResponse is read from a plugin's stdout.
+type pluginResponse struct {
+	Output   string         `json:"output,omitempty"`   // export: the exported file
+	Sessions []track.Record `json:"sessions,omitempty"` // import
+	Message  string         `json:"message,omitempty"`  // push: what to tell the user
+	Error    string         `json:"error,omitempty"`
+}
+
+// execPlugin runs a plugin's command once per request.
+type execPlugin struct {
+	pluginConfig
+}
+
+// call sends req to the plugin and reads its answer. Anything it writes to
+// stderr is only shown when it fails.
+func (p execPlugin) call(req pluginRequest) (pluginResponse, error) {
+	var resp pluginResponse
+	req.Protocol = pluginProtocol
+	in, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", p.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", p.Command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return resp, fmt.Errorf("plugin %s: %w: %s", p.Name, err, msg)
+		}
+		return resp, fmt.Errorf("plugin %s: %w", p.Name, err)
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return resp, fmt.Errorf("plugin %s: parsing its answer: %w", p.Name, err)
+	}
+	if resp.Error != "" {
+		return resp, fmt.Errorf("plugin %s: %s", p.Name, resp.Error)
+	}
+	return resp, nil
+}
+
+func records(sessions []session) []track.Record {
+	recs := make([]track.Record, 0, len(sessions))
+	for _, sess := range sessions {
+		recs = append(recs, track.ToRecord(sess))
+	}
+	return recs
+}
+
+func (p execPlugin) Export(w io.Writer, sessions []session, _ config) error {
+	resp, err := p.call(pluginRequest{Action: provideExport, Sessions: records(sessions)})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, resp.Output)
+	return err
+}
+
+// Import gives sessions without an ID a new one, so the plugin need not.
+func (p execPlugin) Import(path string) ([]session, error) {
+	resp, err := p.call(pluginRequest{Action: provideImport, Path: path})
+	if err != nil {
+		return nil, err
+	}
+	var imported []session
+	for _, rec := range resp.Sessions {
+		if rec.ID == "" {
+			rec.ID = track.NewID()
+		}
+		imported = append(imported, track.FromRecord(rec))
+	}
+	return imported, nil
+}
+
+func (p execPlugin) Push(sessions []session, _ config) (string, error) {
+	resp, err := p.call(pluginRequest{Action: providePush, Sessions: records(sessions)})
+	if err != nil {
+		return "", err
+	}
+	if resp.Message == "" {
+		return fmt.Sprintf("Pushed %d sessions to %s", len(sessions), p.Name), nil
+	}
+	return resp.Message, nil
+}
+
+// notifyPlugins tells the plugins providing events that tracking started,
+// stopped, paused or resumed.
+func (c config) notifyPlugins(ev webhookEvent) error {
+	var errs []error
+	for _, p := range c.Plugins {
+		if p.provides(provideEvents) {
+			_, err := execPlugin{p}.call(pluginRequest{Action: "event", Event: &ev})
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c config) hasEventPlugins() bool {
+	return slices.ContainsFunc(c.Plugins, func(p pluginConfig) bool { return p.provides(provideEvents) })
+}
+
+// loadJSONExport reads a file written by `export -format json`, or one from
+// stdin if path is "-".
+func loadJSONExport(path string) ([]session, error) {
+	if path == "-" {
+		return track.ReadJSON(os.Stdin, "stdin")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return track.ReadJSON(f, path)
+}
+
+// runPush sends the history, or the part of it in -range, to a sync target
+// provided by a plugin.
+func runPush(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("push", flag.ExitOnError)
+	rangeSpec := fs.String("range", "", "only push today, week, month, last-month or FROM..TO")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: push [-range R] TARGET")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	target, err := cfg.syncTarget(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	dates, err := parseRange(*rangeSpec, time.Now())
+	if err != nil {
+		return err
+	}
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+	msg, err := target.Push(dates.Filter(history), cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Println(msg)
+	return nil
+}
+
+// runPlugins lists the export formats, import sources and sync targets
+// available, built in and from plugins.
+func runPlugins(args []string) error {
+	fs := flag.NewFlagSet("plugins", flag.ExitOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Export formats: %s\n", strings.Join(available(cfg, exporters, provideExport), ", "))
+	fmt.Printf("Import formats: %s\n", strings.Join(available(cfg, importers, provideImport), ", "))
+	if targets := available(cfg, syncTargets, providePush); len(targets) > 0 {
+		fmt.Printf("Sync targets:   %s\n", strings.Join(targets, ", "))
+	}
+	for _, p := range cfg.Plugins {
+		fmt.Printf("\n%s: %s\n  provides %s\n", p.Name, p.Command, strings.Join(p.Provides, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// profileEnv names the environment variable that picks a profile, as
+// -profile does.
+const profileEnv = "TIME_TRACKER_PROFILE"
+
+// defaultProfile keeps its files in the data directory itself; every other
+// profile has a directory of its own under profilesDir in it.
+const (
+	defaultProfile = "default"
+	profilesDir    = "profiles"
+)
+
+// dataRoot is the data directory, and profile the profile whose files are
+// in use.
+var (
+	dataRoot string
+	profile  = defaultProfile
+)
+
+func checkProfileName(name string) error {
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	return nil
+}
+
+func profileDir(name string) string {
+	if name == defaultProfile {
+		return dataRoot
+	}
+	return filepath.Join(dataRoot, profilesDir, name)
+}
+
+// useProfile keeps all files in the directory of the named profile, or the
+// default one if name is empty, creating it as needed unless nothing may be
+// written.
+func useProfile(name string) error {
+	if name == "" {
+		name = defaultProfile
+	}
+	if err := checkProfileName(name); err != nil {
+		return err
+	}
+	dir := profileDir(name)
+	if readOnlyReason == "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	for _, file := range []*string{&dataFile, &sqliteFile, &activeFile, &configFile, &historyFile,
+		&auditFile, &trashFile, &journalFile, &lockFile, &socketFile, &gcalTokenFile, &syncFile} {
+		*file = filepath.Join(dir, filepath.Base(*file))
+	}
+	profile = name
+	return nil
+}
+
+// listProfiles returns the default profile followed by the others by name.
+func listProfiles() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dataRoot, profilesDir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	slices.Sort(names)
+	return append([]string{defaultProfile}, names...), nil
+}
+
+// openProfiles lists the profiles to switch to.
+func (m model) openProfiles() (tea.Model, tea.Cmd) {
+	profiles, err := listProfiles()
+	if err != nil {
+		m.status = err.Error()
+		return m, nil
+	}
+	m.profiles = profiles
+	m.profileCursor = max(0, slices.Index(profiles, profile))
+	m.currentView = profilesView
+	m.status = ""
+	return m, nil
+}
+
+func (m model) updateProfiles(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Back):
+		m.currentView = menuView
+		m.cursor = 0
+		m.status = ""
+	case key.Matches(msg, m.keys.Up):
+		if m.profileCursor > 0 {
+			m.profileCursor--
+		}
+	case key.Matches(msg, m.keys.Down):
+		if m.profileCursor < len(m.profiles)-1 {
+			m.profileCursor++
+		}
+	case key.Matches(msg, m.keys.Select):
+		if m.profileCursor < len(m.profiles) {
+			return m.switchProfile(m.profiles[m.profileCursor])
+		}
+	}
+	return m, nil
+}
+
+// switchProfile closes the history of the current profile and opens the one
+// of name in its place. Nothing may be running or unsaved, and an encrypted
+// profile can only be opened this way when its passphrase is in
+// passphraseEnv, as there is no asking for it inside the UI.
+func (m model) switchProfile(name string) (tea.Model, tea.Cmd) {
+	switch {
+	case name == profile:
+		m.currentView = menuView
+		return m, nil
+	case m.active != nil:
+		m.status = "Stop tracking before switching profiles"
+		return m, nil
+	case m.dirty:
+		m.status = fmt.Sprintf("Save your changes with %s before switching profiles", m.keys.Save.Help().Key)
+		return m, nil
+	}
+	m.flushDelete()
+	backups.wait()
+
+	from, fromKey := profile, dataKey
+	next, err := m.openProfile(name)
+	if err != nil {
+		unlockHistory()
+		useProfile(from)
+		dataKey = fromKey
+		// openProfile may have moved to the time zone of name already.
+		useTimezone()
+		if !m.readOnly {
+			lockHistory()
+		}
+		m.status = fmt.Sprintf("Cannot switch to %s: %v", name, err)
+		return m, nil
+	}
+	next.status = fmt.Sprintf("Switched to profile %s", name)
+	if next.active != nil {
+		return next, next.tickCmd()
+	}
+	return next, nil
+}
+
+// openProfile makes name the profile in use and loads everything in it into
+// a fresh model, keeping the window and the command line options of m.
+func (m model) openProfile(name string) (model, error) {
+	unlockHistory()
+	if err := useProfile(name); err != nil {
+		return m, err
+	}
+	dataKey = nil
+	if dataEncrypted() && os.Getenv(passphraseEnv) == "" {
+		return m, fmt.Errorf("it is encrypted; start with time-tracker -profile %s", name)
+	}
+	if err := unlockData(); err != nil {
+		return m, err
+	}
+	if !m.readOnly {
+		if err := lockHistory(); err != nil {
+			return m, err
+		}
+	}
+
+	useTimezone()
+	storage, err := openStorage(m.storageKind)
+	if err != nil {
+		return m, err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return m, err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return m, err
+	}
+	active, err := loadActive()
+	if err != nil {
+		return m, err
+	}
+	if _, ok := cfg.Settings[settingDarkMode]; !ok {
+		cfg.Settings[settingDarkMode] = m.settings[settingDarkMode]
+	}
+	applyPalette(cfg.palette())
+
+	next := initialModel(cfg, storage, history, active)
+	next.storageKind = m.storageKind
+	next.width, next.height = m.width, m.height
+	next.idleAfter = m.idleAfter
+	next.rangeFilter = m.rangeFilter
+	next.readOnly = m.readOnly
+	if active != nil {
+		next.elapsed = active.elapsed(time.Now())
+		next.remindersSent = int(next.elapsed / trackingReminderEvery)
+	}
+	return next, nil
+}
+
+func (m model) viewProfiles() string {
+	s := titleStyle.Render("🗂  Profiles") + "\n\n"
+
+	for i, name := range m.profiles {
+		line := name
+		if name == profile {
+			line += " (current)"
+		}
+		if i == m.profileCursor {
+			s += selectedStyle.Render("> "+line) + "\n"
+		} else {
+			s += historyItemStyle.Render("  "+line) + "\n"
+		}
+	}
+	s += "\n" + helpStyle.Render("Start with -profile NAME to create a new profile.") + "\n"
+
+	if m.status != "" {
+		s += "\n" + normalStyle.Render(m.status) + "\n"
+	}
+	s += "\n" + helpLine(pairHelp(m.keys.Up, m.keys.Down, "navigate"), withDesc(m.keys.Select, "switch"), m.keys.Back, m.keys.Help, m.keys.Quit)
+	return s
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"time-tracking/pkg/track"
+)
+
+var errReadOnly = errors.New("read-only")
+
+// readOnlyReason says why nothing may be written this run, or is empty while
+// the data directory may be changed. Every write to it checks it, so
+// browsing a backup or someone else's export cannot touch the files.
+var readOnlyReason string
+
+// Reasons for readOnlyReason.
+const (
+	readOnlyFlag       = "opened with -read-only"
+	readOnlyLocked     = "another time-tracker has the history open"
+	readOnlyUnwritable = "the data directory cannot be written to"
+)
+
+// checkWritable returns an error saying why if nothing may be written.
+func checkWritable() error {
+	if readOnlyReason == "" {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", errReadOnly, readOnlyReason)
+}
+
+// writableDir reports whether files can be created in dir, which they cannot
+// on a read-only file system or without permission.
+func writableDir(dir string) bool {
+	f, err := os.CreateTemp(dir, ".write-test-*")
+	if err != nil {
+		return false
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return true
+}
+
+// openExport reads sessions from a file written by export -format json, to
+// browse them in place of the stored history.
+func openExport(path string) (Storage, error) {
+	history, err := loadJSONExport(path)
+	if err != nil {
+		return nil, err
+	}
+	return &track.MemoryStorage{Sessions: history}, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// reloadEvery is how often the TUI checks whether the stored history was
+// changed by someone else: the CLI, another instance or a sync tool. It
+// polls the file's stamp rather than watching for change notifications,
+// which no dependency here provides and which network and synced folders
+// do not reliably deliver; a stat every couple of seconds costs little.
+const reloadEvery = 2 * time.Second
+
+// reloadMsg asks for the check.
+type reloadMsg struct{}
+
+// fileStamp tells whether a file has changed since it was last looked at.
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+// storageStamp stamps the file behind storage, or is zero for storage that
+// has none.
+func storageStamp(storage Storage) fileStamp {
+	var path string
+	switch s := storage.(type) {
+	case *jsonStorage:
+		path = s.Path
+	case *sqliteStorage:
+		path = s.path
+	case *backedUpStorage:
+		return storageStamp(s.Storage)
+	default:
+		return fileStamp{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{mod: info.ModTime(), size: info.Size()}
+}
+
+func (m model) reloadCmd() tea.Cmd {
+	return tea.Tick(reloadEvery, func(time.Time) tea.Msg { return reloadMsg{} })
+}
+
+// reload merges changes made to the stored history elsewhere into the one in
+// memory, keeping any unsaved changes made here, so the next save does not
+// undo them. It waits while a deletion is pending, a dialog is open or a
+// session is being edited, as those hold indices into history that merging
+// could shift onto a different session.
+func (m *model) reload() {
+	stamp := storageStamp(m.storage)
+	if stamp == m.stamp || m.pending != nil || m.dialog != nil || m.editing != editNone {
+		return
+	}
+	m.stamp = stamp
+	stored, err := m.storage.Load()
+	if err != nil {
+		m.status = fmt.Sprintf("Reload failed: %v", err)
+		return
+	}
+	history, changes := mergeHistory(m.stored, stored, m.history)
+	m.stored = stored
+	if changes == 0 {
+		return
+	}
+	m.history = history
+	m.cursor = max(0, min(m.cursor, len(m.historyRows())-1))
+	m.scrollHistory()
+	if changes == 1 {
+		m.status = "Reloaded 1 change made elsewhere"
+	} else {
+		m.status = fmt.Sprintf("Reloaded %d changes made elsewhere", changes)
+	}
+}
+
+// mergeHistory applies the changes between base, the stored history as last
+// loaded, and stored, as it is now, to history in memory, counting them.
+// Sessions are matched by ID. A session changed or deleted in
+// storage is only updated or dropped in memory if it has not been changed
+// here too; local changes win.
+func mergeHistory(base, stored, history []session) ([]session, int) {
+	history = slices.Clone(history)
+	changes := 0
+	find := func(list []session, sess session) int {
+		return slices.IndexFunc(list, func(other session) bool { return other.ID == sess.ID })
+	}
+	for _, sess := range stored {
+		b := find(base, sess)
+		h := find(history, sess)
+		switch {
+		case b < 0 && h < 0:
+			at, _ := slices.BinarySearchFunc(history, sess.Start, func(s session, t time.Time) int { return s.Start.Compare(t) })
+			history = slices.Insert(history, at, sess)
+			changes++
+		case b >= 0 && h >= 0 && !identical(base[b], sess) && identical(history[h], base[b]):
+			history[h] = sess
+			changes++
+		}
+	}
+	for _, sess := range base {
+		if find(stored, sess) >= 0 {
+			continue
+		}
+		if h := find(history, sess); h >= 0 && identical(history[h], sess) {
+			history = slices.Delete(history, h, h+1)
+			changes++
+		}
+	}
+	return history, changes
+}
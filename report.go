@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// writeReport writes the human-readable report for history to path, with
+// earnings at the rates in cfg and the use of any budgets.
+func writeReport(path string, history []session, cfg config) error {
+	if dataKey != nil {
+		// The report would give away the encrypted history.
+		return nil
+	}
+	return writeFileAtomic(path, []byte(renderReport(history, cfg)+renderBudgets(history, cfg)), 0644)
+}
+
+// renderReport formats history as the human-readable ASCII-art report.
+// Earnings are included once cfg has an hourly rate. Totals are rounded as
+// cfg says; each session shows its exact duration.
+func renderReport(history []session, cfg config) string {
+	totalDuration, totalEarnings := cfg.billed(history)
+
+	var sb strings.Builder
+
+	sb.WriteString(`
+ ╔════════════════════════════════════════════════════════════════╗
+ ║                                                                ║
+ ║    ████████╗██╗███╗   ███╗███████╗                             ║
+ ║    ╚══██╔══╝██║████╗ ████║██╔════╝                             ║
+ ║       ██║   ██║██╔████╔██║█████╗                               ║
+ ║       ██║   ██║██║╚██╔╝██║██╔══╝                               ║
+ ║       ██║   ██║██║ ╚═╝ ██║███████╗                             ║
+ ║       ╚═╝   ╚═╝╚═╝     ╚═╝╚══════╝                             ║
+ ║                                                                ║
+ ║    ████████╗██████╗  █████╗  ██████╗██╗  ██╗███████╗██████╗    ║
+ ║    ╚══██╔══╝██╔══██╗██╔══██╗██╔════╝██║ ██╔╝██╔════╝██╔══██╗   ║
+ ║       ██║   ██████╔╝███████║██║     █████╔╝ █████╗  ██████╔╝   ║
+ ║       ██║   ██╔══██╗██╔══██║██║     ██╔═██╗ ██╔══╝  ██╔══██╗   ║
+ ║       ██║   ██║  ██║██║  ██║╚██████╗██║  ██╗███████╗██║  ██║   ║
+ ║       ╚═╝   ╚═╝  ╚═╝╚═╝  ╚═╝ ╚═════╝╚═╝  ╚═╝╚══════╝╚═╝  ╚═╝   ║
+ ║                                                                ║
+ ╚════════════════════════════════════════════════════════════════╝
+`)
+
+	sb.WriteString(fmt.Sprintf("\n  Generated: %s\n", time.Now().Format("Mon Jan 02, 2006 at 03:04 PM")))
+	sb.WriteString(fmt.Sprintf("  Total Sessions: %d\n", len(history)))
+	sb.WriteString(fmt.Sprintf("  Total Time: %s\n", cfg.durationLong(totalDuration)))
+	if billable, nonBillable := cfg.billableSplit(history); nonBillable > 0 {
+		sb.WriteString(fmt.Sprintf("  Billable: %s, not billable: %s\n", cfg.durationLong(billable), cfg.durationLong(nonBillable)))
+	}
+	if cfg.hasRates() {
+		sb.WriteString(fmt.Sprintf("  Total Earnings: %s\n", cfg.money(totalEarnings)))
+	}
+	if label := cfg.Rounding.label(); label != "" {
+		sb.WriteString(fmt.Sprintf("  Rounding: %s\n", label))
+	}
+
+	sb.WriteString(`
+ ┌────────────────────────────────────────────────────────────────┐
+ │                      SESSION HISTORY                           │
+ └────────────────────────────────────────────────────────────────┘
+`)
+
+	if len(history) == 0 {
+		sb.WriteString("\n   No sessions recorded yet.\n")
+	} else {
+		n := 0
+		for _, group := range groupByProject(history) {
+			var sessions []session
+			for _, i := range group.indices {
+				sessions = append(sessions, history[i])
+			}
+			total, earnings := cfg.billed(sessions)
+			sb.WriteString(fmt.Sprintf("\n  ▸ %s — %d session(s), %s",
+				track.ProjectLabel(group.name),
+				len(group.indices),
+				cfg.durationLong(total),
+			))
+			if cfg.hasRates() {
+				sb.WriteString(", " + cfg.money(earnings))
+			}
+			sb.WriteString("\n")
+
+			for _, i := range group.indices {
+				sess := history[i]
+				n++
+				sb.WriteString(fmt.Sprintf(`
+   ┌──────────────────────────────────────────┐
+   │  SESSION #%-3d                            │
+   ├──────────────────────────────────────────┤
+   │  Project:  %-29s │
+   │  Date:     %-29s │
+   │  Start:    %-29s │
+   │  End:      %-29s │
+   │  Duration: %-29s │
+%s%s%s%s%s%s   └──────────────────────────────────────────┘
+`,
+					n,
+					sess.Project,
+					sess.Start.Format("Monday, January 02, 2006"),
+					sess.Start.Format("03:04:05 PM"),
+					sess.End.Format("03:04:05 PM"),
+					cfg.durationLong(sess.Duration),
+					reportRoundingLines(sess, cfg),
+					reportPauseLines(sess.Pauses, cfg),
+					reportBillingLines(sess, cfg),
+					reportTagLines(sess.Tags),
+					reportIssueLines(sess.Issue),
+					reportNoteLines(sess.Note),
+				))
+			}
+		}
+	}
+
+	sb.WriteString(`
+ ╔════════════════════════════════════════════════════════════════╗
+ ║                        END OF REPORT                           ║
+ ╚════════════════════════════════════════════════════════════════╝
+`)
+
+	return sb.String()
+}
+
+// reportRoundingLines renders a session's duration after rounding as a box
+// row for the report, when sessions are rounded one by one and it differs.
+func reportRoundingLines(sess session, cfg config) string {
+	if !cfg.Rounding.perSession() {
+		return ""
+	}
+	rounded := cfg.Rounding.round(sess.Duration)
+	if rounded == sess.Duration {
+		return ""
+	}
+	return fmt.Sprintf("   │  Rounded:  %-29s │\n", cfg.durationLong(rounded))
+}
+
+// reportPauseLines renders a session's pauses as box rows for the report: a
+// total paused time followed by one row per interval.
+func reportPauseLines(pauses []pause, cfg config) string {
+	if len(pauses) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("   │  Paused:   %-29s │\n", cfg.durationLong(track.PausedTotal(pauses, time.Time{}))))
+	for _, p := range pauses {
+		sb.WriteString(fmt.Sprintf("   │  Pause:    %-29s │\n",
+			p.Start.Format("03:04:05 PM")+" - "+p.End.Format("03:04:05 PM")))
+	}
+	return sb.String()
+}
+
+// reportBillingLines renders a session's earnings, or marks it as not
+// billable, as box rows for the report.
+func reportBillingLines(sess session, cfg config) string {
+	if !sess.Billable {
+		return fmt.Sprintf("   │  Billable: %-29s │\n", "no")
+	}
+	rate := cfg.rateFor(sess.Project)
+	if rate == 0 {
+		return ""
+	}
+	earned := cfg.earnings(sess)
+	if cfg.Rounding.perSession() {
+		earned = amountFor(cfg.Rounding.round(sess.Duration), rate)
+	}
+	return fmt.Sprintf("   │  Earnings: %-29s │\n",
+		fmt.Sprintf("%s (%s/h)", cfg.projectMoney(earned, sess.Project), cfg.projectMoney(rate, sess.Project)))
+}
+
+// reportTagLines renders a session's tags as box rows for the report.
+func reportTagLines(tags []string) string {
+	var sb strings.Builder
+	for _, line := range wrapWords(track.FormatTags(tags), 29) {
+		sb.WriteString(fmt.Sprintf("   │  Tags:     %-29s │\n", line))
+	}
+	return sb.String()
+}
+
+// reportIssueLines renders the issue a session is linked to as a box row for
+// the report.
+func reportIssueLines(issue string) string {
+	if issue == "" {
+		return ""
+	}
+	return fmt.Sprintf("   │  Issue:    %-29s │\n", truncate(issueRef(issue), 29))
+}
+
+// reportNoteLines renders a session note as box rows for the report, wrapping
+// long notes over several "Note:" rows.
+func reportNoteLines(note string) string {
+	var sb strings.Builder
+	for _, line := range wrapWords(note, 29) {
+		sb.WriteString(fmt.Sprintf("   │  Note:     %-29s │\n", line))
+	}
+	return sb.String()
+}
+
+// renderTotals formats rows from summarizeByProject or summarizeByTag as a
+// table of time, sessions, share of the time tracked in history and, once cfg
+// has an hourly rate, earnings.
+func renderTotals(title string, rows []summaryRow, history []session, cfg config) string {
+	total, earnings := cfg.billed(history)
+	billable, nonBillable := cfg.billableSplit(history)
+	split := nonBillable > 0
+
+	var sb strings.Builder
+	if label := cfg.Rounding.label(); label != "" {
+		sb.WriteString("Time " + label + "\n\n")
+	}
+	sb.WriteString(fmt.Sprintf("%-30s %12s %9s %7s", title, "Time", "Sessions", "Share"))
+	if split {
+		sb.WriteString(fmt.Sprintf(" %12s", "Billable"))
+	}
+	if cfg.hasRates() {
+		sb.WriteString(fmt.Sprintf(" %12s", "Earnings"))
+	}
+	sb.WriteString("\n")
+
+	line := func(label string, d, billed time.Duration, sessions int, earned float64) {
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(d) / float64(total)
+		}
+		sb.WriteString(fmt.Sprintf("%-30s %12s %9d %6.1f%%", truncate(label, 30), cfg.durationLong(d), sessions, share))
+		if split {
+			sb.WriteString(fmt.Sprintf(" %12s", cfg.durationLong(billed)))
+		}
+		if cfg.hasRates() {
+			sb.WriteString(fmt.Sprintf(" %12s", cfg.money(earned)))
+		}
+		sb.WriteString("\n")
+	}
+	for _, row := range rows {
+		line(row.label, row.total, row.billable, row.sessions, row.earnings)
+	}
+	sb.WriteString("\n")
+	line("Total", total, billable, len(history), earnings)
+	return sb.String()
+}
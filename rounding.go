@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"time-tracking/pkg/report"
+	"time-tracking/pkg/track"
+)
+
+// Values for the mode and per fields of rounding in config.json.
+const (
+	roundNearest    = "nearest"
+	roundUpward     = "up"
+	roundPerSession = "session"
+	roundPerDay     = "day"
+)
+
+// rounding is how billed time is rounded in reports, the summary and
+// invoices. Stored sessions always keep their exact durations.
+type rounding struct {
+	// To is the unit to round to, e.g. "6m" or "15m". Empty turns rounding
+	// off.
+	To string `json:"to,omitempty"`
+	// Mode is "nearest" (the default) or "up".
+	Mode string `json:"mode,omitempty"`
+	// Per is "session" (the default) to round every session, or "day" to
+	// round what each project got on a day.
+	Per string `json:"per,omitempty"`
+}
+
+func checkRounding(r rounding) error {
+	if r.To == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(r.To); err != nil || d <= 0 {
+		return fmt.Errorf("invalid rounding to %q (want e.g. 6m or 15m)", r.To)
+	}
+	switch r.Mode {
+	case "", roundNearest, roundUpward:
+	default:
+		return fmt.Errorf("invalid rounding mode %q (want %q or %q)", r.Mode, roundNearest, roundUpward)
+	}
+	switch r.Per {
+	case "", roundPerSession, roundPerDay:
+	default:
+		return fmt.Errorf("invalid rounding per %q (want %q or %q)", r.Per, roundPerSession, roundPerDay)
+	}
+	return nil
+}
+
+// unit is the multiple durations are rounded to, or 0 if rounding is off.
+func (r rounding) unit() time.Duration {
+	// loadConfig has checked that To parses.
+	d, _ := time.ParseDuration(r.To)
+	return d
+}
+
+func (r rounding) perDay() bool {
+	return r.unit() > 0 && r.Per == roundPerDay
+}
+
+func (r rounding) perSession() bool {
+	return r.unit() > 0 && r.Per != roundPerDay
+}
+
+// round rounds d to the unit, up or to the nearest multiple.
+func (r rounding) round(d time.Duration) time.Duration {
+	unit := r.unit()
+	if unit <= 0 {
+		return d
+	}
+	if r.Mode == roundUpward {
+		return roundUp(d, unit)
+	}
+	return d.Round(unit)
+}
+
+// label describes the rounding, e.g. "rounded up to 15m per day", or is
+// empty when it is off.
+func (r rounding) label() string {
+	if r.unit() <= 0 {
+		return ""
+	}
+	how := "rounded to the nearest"
+	if r.Mode == roundUpward {
+		how = "rounded up to"
+	}
+	per := roundPerSession
+	if r.perDay() {
+		per = roundPerDay
+	}
+	return fmt.Sprintf("%s %s per %s", how, track.FormatMinutesLong(r.unit()), per)
+}
+
+// billed totals the time and earnings, in the home currency, of sessions
+// after rounding. Rounding per day rounds the total of each project's
+// sessions on one day, billable and non-billable ones apart.
+func (c config) billed(sessions []session) (total time.Duration, earnings float64) {
+	r := c.Rounding
+	if !r.perDay() {
+		for _, sess := range sessions {
+			d := r.round(sess.Duration)
+			total += d
+			if sess.Billable {
+				earnings += c.toHome(amountFor(d, c.rateFor(sess.Project)), c.currencyFor(sess.Project))
+			}
+		}
+		return total, earnings
+	}
+
+	type dayGroup struct {
+		project  string
+		billable bool
+		total    time.Duration
+	}
+	lookup := make(map[string]int)
+	var groups []dayGroup
+	for _, sess := range sessions {
+		key := fmt.Sprintf("%s\x00%s\x00%t", sess.Start.Format(report.DateLayout), sess.Project, sess.Billable)
+		i, ok := lookup[key]
+		if !ok {
+			i = len(groups)
+			lookup[key] = i
+			groups = append(groups, dayGroup{project: sess.Project, billable: sess.Billable})
+		}
+		groups[i].total += sess.Duration
+	}
+	for _, g := range groups {
+		d := r.round(g.total)
+		total += d
+		if g.billable {
+			earnings += c.toHome(amountFor(d, c.rateFor(g.project)), c.currencyFor(g.project))
+		}
+	}
+	return total, earnings
+}
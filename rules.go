@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// rule categorizes sessions as they are started or imported: when its
+// patterns match, it fills in the project and adds its tags.
+type rule struct {
+	// Note, Title and Branch are regular expressions matched against the
+	// session's note, the title of the window or calendar event it was made
+	// from and the git branch checked out when it was started. All those
+	// given must match; a session without a title or branch matches no
+	// pattern for it.
+	Note    string   `json:"note,omitempty"`
+	Title   string   `json:"title,omitempty"`
+	Branch  string   `json:"branch,omitempty"`
+	Project string   `json:"project,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	// Billable, if set, overrides whether the session is billed.
+	Billable *bool `json:"billable,omitempty"`
+
+	note, title, branch *regexp.Regexp // compiled patterns, nil if not given
+}
+
+// parse checks the rule and compiles its patterns.
+func (r *rule) parse() error {
+	if r.Note == "" && r.Title == "" && r.Branch == "" {
+		return errors.New("rule without a note, title or branch pattern")
+	}
+	if r.Project == "" && len(r.Tags) == 0 && r.Billable == nil {
+		return fmt.Errorf("rule %s sets no project, tags or billable", r)
+	}
+	for _, p := range []struct {
+		pattern string
+		re      **regexp.Regexp
+	}{{r.Note, &r.note}, {r.Title, &r.title}, {r.Branch, &r.branch}} {
+		if p.pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(p.pattern)
+		if err != nil {
+			return fmt.Errorf("rule %s: %w", r, err)
+		}
+		*p.re = re
+	}
+	return nil
+}
+
+// String describes the rule by its patterns, for error messages.
+func (r *rule) String() string {
+	var parts []string
+	for _, p := range [][2]string{{"note", r.Note}, {"title", r.Title}, {"branch", r.Branch}} {
+		if p[1] != "" {
+			parts = append(parts, fmt.Sprintf("%s %q", p[0], p[1]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// matches reports whether the rule applies to a session with note, made
+// from title and started on branch.
+func (r rule) matches(note, title, branch string) bool {
+	for _, p := range []struct {
+		re    *regexp.Regexp
+		value string
+	}{{r.note, note}, {r.title, title}, {r.branch, branch}} {
+		if p.re != nil && (p.value == "" || !p.re.MatchString(p.value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// categorize applies the matching rules to sess, made from title and
+// started on branch, in order: the first to give a project sets it unless
+// sess has one, every match adds its tags, and the last to set billable
+// decides it. A project set by a rule is billed as configured for it unless
+// a rule says otherwise.
+func (c config) categorize(sess *session, title, branch string) {
+	billableSet := false
+	for _, r := range c.Rules {
+		if !r.matches(sess.Note, title, branch) {
+			continue
+		}
+		if sess.Project == "" && r.Project != "" {
+			sess.Project = r.Project
+			if !billableSet {
+				sess.Billable = c.billableByDefault(r.Project)
+			}
+		}
+		for _, tag := range r.Tags {
+			if !slices.Contains(sess.Tags, tag) {
+				sess.Tags = append(sess.Tags, tag)
+			}
+		}
+		if r.Billable != nil {
+			sess.Billable, billableSet = *r.Billable, true
+		}
+	}
+}
+
+// categorizeActive applies the rules to a session starting on branch.
+func (c config) categorizeActive(a *activeSession, branch string) {
+	sess := session{Project: a.project, Note: a.note, Tags: slices.Clone(a.tags), Billable: a.billable}
+	c.categorize(&sess, "", branch)
+	a.project, a.tags, a.billable = sess.Project, sess.Tags, sess.Billable
+}
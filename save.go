@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// autoSave reports whether history changes are written to storage as they
+// happen. With the setting off they stay in memory until saved with ctrl+s.
+func (m model) autoSave() bool {
+	return m.settings[settingAutoSave]
+}
+
+// changed records a change to the in-memory history, saving it straight away
+// if Auto-save is on and otherwise marking it unsaved.
+func (m *model) changed() {
+	if m.autoSave() {
+		m.save()
+		return
+	}
+	m.dirty = true
+}
+
+// saveNow writes out unsaved changes and reports the result in the status
+// line.
+func (m *model) saveNow() {
+	if err := m.save(); err != nil {
+		m.status = fmt.Sprintf("Save failed: %v", err)
+		return
+	}
+	m.status = fmt.Sprintf("Saved %d sessions", len(m.history))
+}
+
+// quit exits the program, first asking whether to stop, discard or keep a
+// running session and then whether to save any unsaved changes.
+func (m model) quit() (tea.Model, tea.Cmd) {
+	if m.active != nil {
+		return m.confirm("⏱  Quit while tracking?",
+			fmt.Sprintf("%s has been running for %s.", track.ProjectLabel(m.active.project), m.config.duration(m.elapsed)),
+			confirmOption{"s", "Stop & save", func(m model) (tea.Model, tea.Cmd) {
+				notify := m.stopTracking()
+				next, cmd := m.quitUnsaved()
+				return next, tea.Sequence(notify, cmd)
+			}},
+			confirmOption{"d", "Discard", func(m model) (tea.Model, tea.Cmd) {
+				m.active = nil
+				if err := clearActive(); err != nil {
+					m.status = fmt.Sprintf("Discard failed: %v", err)
+					return m, nil
+				}
+				return m.quitUnsaved()
+			}},
+			confirmOption{"k", "Keep running", func(m model) (tea.Model, tea.Cmd) {
+				// The session is already in activeFile and resumes on the
+				// next start, or can be stopped with "time-tracker stop".
+				return m.quitUnsaved()
+			}},
+		)
+	}
+	return m.quitUnsaved()
+}
+
+// quitUnsaved exits the program, asking first whether to save if there are
+// unsaved changes.
+func (m model) quitUnsaved() (tea.Model, tea.Cmd) {
+	if !m.dirty {
+		return m, tea.Quit
+	}
+	return m.confirm("💾 Unsaved changes", "Save your changes before quitting?",
+		confirmOption{"y", "Save & quit", func(m model) (tea.Model, tea.Cmd) {
+			if err := m.save(); err != nil {
+				m.status = fmt.Sprintf("Save failed: %v", err)
+				return m, nil
+			}
+			return m, tea.Quit
+		}},
+		confirmOption{"n", "Quit without saving", func(m model) (tea.Model, tea.Cmd) {
+			// Drop the changes, including any deletion still in its undo
+			// window, so nothing is written on the way out.
+			m.dirty = false
+			m.pending = nil
+			m.unaudited = nil
+			m.untrashed = nil
+			m.restored = nil
+			clearJournal()
+			return m, tea.Quit
+		}},
+	)
+}
+
+// viewUnsaved is the indicator shown under every view while there are
+// unsaved changes.
+func (m model) viewUnsaved() string {
+	if !m.dirty {
+		return ""
+	}
+	return "\n\n" + selectedStyle.Render("● Unsaved changes") + helpStyle.Render(" • ") + helpLine(m.keys.Save)
+}
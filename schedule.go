@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/report"
+	"time-tracking/pkg/track"
+)
+
+// defaultWorkHours is used when config.json sets no work_hours.
+const defaultWorkHours = "09:00-17:00"
+
+// defaultWorkDays are the days worked when config.json sets no work_days.
+var defaultWorkDays = []string{"mon", "tue", "wed", "thu", "fri"}
+
+// workSchedule is when work is expected: the same hours on each working
+// day.
+type workSchedule struct {
+	days     [7]bool       // indexed by time.Weekday
+	from, to time.Duration // offsets from midnight
+}
+
+// schedule returns the configured work schedule.
+func (c config) schedule() workSchedule {
+	var s workSchedule
+	s.from, s.to = c.workHours()
+	days := c.WorkDays
+	if len(days) == 0 {
+		days = defaultWorkDays
+	}
+	// loadConfig has checked the days.
+	s.days, _ = parseWorkDays(days)
+	return s
+}
+
+// workHours returns the start and end of the working day as offsets from
+// midnight.
+func (c config) workHours() (from, to time.Duration) {
+	spec := c.WorkHours
+	if spec == "" {
+		spec = defaultWorkHours
+	}
+	// loadConfig has checked the spec.
+	from, to, _ = parseWorkHours(spec)
+	return from, to
+}
+
+// parseWorkHours reads "HH:MM-HH:MM".
+func parseWorkHours(spec string) (from, to time.Duration, err error) {
+	fromStr, toStr, ok := strings.Cut(spec, "-")
+	if ok {
+		var f, t time.Time
+		f, err = time.Parse("15:04", strings.TrimSpace(fromStr))
+		if err == nil {
+			t, err = time.Parse("15:04", strings.TrimSpace(toStr))
+		}
+		from = time.Duration(f.Hour())*time.Hour + time.Duration(f.Minute())*time.Minute
+		to = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	if !ok || err != nil || to <= from {
+		return 0, 0, fmt.Errorf("invalid work_hours %q (want e.g. 09:00-17:00)", spec)
+	}
+	return from, to, nil
+}
+
+// parseWorkDays reads day names such as "mon" or "Monday".
+func parseWorkDays(names []string) (days [7]bool, err error) {
+	for _, name := range names {
+		found := false
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			full := strings.ToLower(wd.String())
+			if n := strings.ToLower(strings.TrimSpace(name)); n == full || n == full[:3] {
+				days[wd], found = true, true
+			}
+		}
+		if !found {
+			return days, fmt.Errorf("invalid work_days entry %q (want e.g. mon or monday)", name)
+		}
+	}
+	return days, nil
+}
+
+// hours returns the working hours on the day t falls on, with ok false on
+// a day off.
+func (s workSchedule) hours(t time.Time) (start, end time.Time, ok bool) {
+	if !s.days[t.Weekday()] {
+		return time.Time{}, time.Time{}, false
+	}
+	return track.ClockOn(t, s.from), track.ClockOn(t, s.to), true
+}
+
+// total sums the working hours between from and until.
+func (s workSchedule) total(from, until time.Time) time.Duration {
+	var total time.Duration
+	y, mo, d := from.Date()
+	for day := time.Date(y, mo, d, 0, 0, 0, 0, until.Location()); day.Before(until); day = day.AddDate(0, 0, 1) {
+		start, end, ok := s.hours(day)
+		if !ok {
+			continue
+		}
+		start, end = later(start, from), earlier(end, until)
+		if start.Before(end) {
+			total += end.Sub(start)
+		}
+	}
+	return total
+}
+
+// String describes the schedule, e.g. "09:00–17:00 Mon–Fri".
+func (s workSchedule) String() string {
+	var names []string
+	for i := 1; i <= 7; i++ {
+		// Weeks start on Monday.
+		if wd := time.Weekday(i % 7); s.days[wd] {
+			names = append(names, wd.String()[:3])
+		}
+	}
+	days := strings.Join(names, ", ")
+	if s.days == [7]bool{false, true, true, true, true, true, false} {
+		days = "Mon–Fri"
+	}
+	return fmt.Sprintf("%s–%s %s", track.FormatMinutes(s.from), track.FormatMinutes(s.to), days)
+}
+
+// utilization is the time tracked in a range against the working hours in
+// it up to now.
+type utilization struct {
+	tracked, scheduled time.Duration
+}
+
+// utilizationIn measures the sessions of history, already filtered to r,
+// against the schedule. A range with no start counts from the first session.
+func (c config) utilizationIn(history []session, r dateRange, now time.Time) utilization {
+	from := r.From
+	if from.IsZero() {
+		for _, sess := range history {
+			if from.IsZero() || sess.Start.Before(from) {
+				from = sess.Start
+			}
+		}
+		y, mo, d := from.Date()
+		from = time.Date(y, mo, d, 0, 0, 0, 0, from.Location())
+	}
+	to := now
+	if !r.To.IsZero() && r.To.Before(now) {
+		to = r.To
+	}
+	u := utilization{tracked: report.Total(history)}
+	if len(history) > 0 || !r.From.IsZero() {
+		u.scheduled = c.schedule().total(from, to)
+	}
+	return u
+}
+
+// describe says how much of the scheduled time was tracked, or nothing if
+// none was scheduled.
+func (u utilization) describe(cfg config) string {
+	if u.scheduled <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("Utilization: %s tracked of %s scheduled (%.0f%%)",
+		cfg.durationLong(u.tracked), cfg.durationLong(u.scheduled), 100*u.tracked.Hours()/u.scheduled.Hours())
+}
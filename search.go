@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"time-tracking/pkg/track"
+)
+
+// sessionMatches reports whether query appears, ignoring case, in the
+// session's project, note, tags or date.
+func sessionMatches(sess session, query string) bool {
+	if query == "" {
+		return false
+	}
+	query = strings.ToLower(query)
+	fields := []string{
+		sess.Project,
+		sess.Note,
+		track.FormatTags(sess.Tags),
+		issueRef(sess.Issue),
+		sess.Start.Format("Jan 02 15:04"),
+		sess.Start.Format("2006-01-02"),
+		sess.Start.Format("Monday"),
+	}
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchMatches returns the history rows, as positions in historyRows, whose
+// sessions match the current search. A collapsed day matches when any of its
+// sessions does.
+func (m model) searchMatches() []int {
+	var rows []int
+	groups := m.historyGroups()
+	for row, r := range m.historyRows() {
+		indices := []int{r.index}
+		if r.index < 0 {
+			indices = groups[r.group].indices
+		}
+		for _, i := range indices {
+			if sessionMatches(m.history[i], m.search) {
+				rows = append(rows, row)
+				break
+			}
+		}
+	}
+	return rows
+}
+
+// nextMatch moves the cursor to the next matching row after from, wrapping
+// around, or the previous one when backward is set. It reports whether
+// anything matched.
+func (m *model) nextMatch(from int, backward bool) bool {
+	rows := m.searchMatches()
+	if len(rows) == 0 {
+		return false
+	}
+	if backward {
+		for i := len(rows) - 1; i >= 0; i-- {
+			if rows[i] < from {
+				m.cursor = rows[i]
+				return true
+			}
+		}
+		m.cursor = rows[len(rows)-1]
+		return true
+	}
+	for _, row := range rows {
+		if row > from {
+			m.cursor = row
+			return true
+		}
+	}
+	m.cursor = rows[0]
+	return true
+}
+
+func (m model) startSearch() (tea.Model, tea.Cmd) {
+	m.searching = true
+	m.searchStart = m.cursor
+	m.searchInput.SetValue("")
+	m.search = ""
+	return m, m.searchInput.Focus()
+}
+
+// updateSearch handles keys while the search prompt is open, moving the
+// cursor to the first match as the query is typed.
+func (m model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m.quit()
+	case "esc":
+		m.searching = false
+		m.search = ""
+		m.cursor = m.searchStart
+		m.searchInput.Blur()
+		m.scrollHistory()
+		return m, nil
+	case "enter":
+		m.searching = false
+		m.searchInput.Blur()
+		if m.search != "" && len(m.searchMatches()) == 0 {
+			m.status = fmt.Sprintf("No matches for %q", m.search)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	m.search = m.searchInput.Value()
+	if !m.nextMatch(m.searchStart-1, false) {
+		m.cursor = m.searchStart
+	}
+	m.scrollHistory()
+	return m, cmd
+}
+
+func (m model) viewSearch() string {
+	if m.searching {
+		return m.searchInput.View()
+	}
+	if m.search == "" {
+		return ""
+	}
+	return normalStyle.Render(fmt.Sprintf("Search: %s (%d matches) • ", m.search, len(m.searchMatches()))) +
+		helpLine(pairHelp(m.keys.NextMatch, m.keys.PrevMatch, "next/previous"), m.keys.Search)
+}
+
+// highlight renders line in style with every case-insensitive occurrence of
+// query picked out in matchStyle.
+func highlight(line, query string, style lipgloss.Style) string {
+	if query == "" {
+		return style.Render(line)
+	}
+
+	lower := strings.ToLower(line)
+	q := strings.ToLower(query)
+	var sb strings.Builder
+	for {
+		i := strings.Index(lower, q)
+		if i < 0 || len(lower) != len(line) {
+			// Lowercasing changed the byte length, so indices into lower
+			// no longer line up with line; fall back to no highlight.
+			sb.WriteString(style.Render(line))
+			break
+		}
+		if i > 0 {
+			sb.WriteString(style.Render(line[:i]))
+		}
+		sb.WriteString(matchStyle.Render(line[i : i+len(q)]))
+		line, lower = line[i+len(q):], lower[i+len(q):]
+		if line == "" {
+			break
+		}
+	}
+	return sb.String()
+}
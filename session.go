@@ -0,0 +1,40 @@
+package main
+
+import (
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// The tracking domain lives in package track, so other programs can embed
+// it; the UI works with its types under their old names.
+type (
+	session = track.Session
+	pause   = track.Pause
+)
+
+// projectGroup is a run of sessions that share a project, identified by their
+// indices into the history slice.
+type projectGroup struct {
+	name    string
+	indices []int
+	total   time.Duration
+}
+
+// groupByProject groups sessions by project, keeping projects in order of first
+// appearance and sessions in their original order within each project.
+func groupByProject(history []session) []projectGroup {
+	var groups []projectGroup
+	lookup := make(map[string]int)
+	for i, sess := range history {
+		g, ok := lookup[sess.Project]
+		if !ok {
+			g = len(groups)
+			lookup[sess.Project] = g
+			groups = append(groups, projectGroup{name: sess.Project})
+		}
+		groups[g].indices = append(groups[g].indices, i)
+		groups[g].total += sess.Duration
+	}
+	return groups
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+const slackProfileAPI = "https://slack.com/api/users.profile.set"
+
+// slackStatusLimit is the longest status text Slack accepts, in characters.
+const slackStatusLimit = 100
+
+var slackClient = &http.Client{Timeout: 10 * time.Second}
+
+// slackConfig is the "slack" section of config.json: the status set while
+// tracking, cleared again when tracking stops.
+type slackConfig struct {
+	// Token is a user token with the users.profile:write scope; SLACK_TOKEN
+	// overrides it.
+	Token string `json:"token,omitempty"`
+	// Emoji is the status emoji, e.g. ":hammer_and_wrench:".
+	Emoji string `json:"emoji,omitempty"`
+	// Text is the status text, in which {project} and {note} are replaced
+	// by the session's.
+	Text string `json:"text,omitempty"`
+}
+
+// Defaults for the status while tracking.
+const (
+	defaultSlackEmoji = ":stopwatch:"
+	defaultSlackText  = "Working on {project}"
+)
+
+func (s slackConfig) token() string {
+	if env := os.Getenv("SLACK_TOKEN"); env != "" {
+		return env
+	}
+	return s.Token
+}
+
+// enabled reports whether the Slack status follows tracking.
+func (s slackConfig) enabled() bool {
+	return s.token() != ""
+}
+
+func checkSlack(s slackConfig) error {
+	if s.Emoji != "" && (len(s.Emoji) < 3 || !strings.HasPrefix(s.Emoji, ":") || !strings.HasSuffix(s.Emoji, ":")) {
+		return fmt.Errorf("slack: invalid emoji %q (want e.g. :stopwatch:)", s.Emoji)
+	}
+	if (s.Emoji != "" || s.Text != "") && s.token() == "" {
+		return errors.New("slack: no token; set slack.token or SLACK_TOKEN")
+	}
+	return nil
+}
+
+// status returns the emoji and text to show while ev's session runs.
+func (s slackConfig) status(ev webhookEvent) (emoji, text string) {
+	emoji, text = s.Emoji, s.Text
+	if emoji == "" {
+		emoji = defaultSlackEmoji
+	}
+	if text == "" {
+		text = defaultSlackText
+	}
+	text = strings.NewReplacer("{project}", track.ProjectLabel(ev.Project), "{note}", ev.Note).Replace(text)
+	text = strings.TrimSpace(text)
+	if r := []rune(text); len(r) > slackStatusLimit {
+		text = string(r[:slackStatusLimit-1]) + "…"
+	}
+	return emoji, text
+}
+
+// updateSlack sets the Slack status when tracking starts and clears it when
+// it stops, unless another session has started since, as when a session is
+// split at midnight. Other events leave it as it is.
+func (c config) updateSlack(ev webhookEvent) error {
+	if !c.Slack.enabled() {
+		return nil
+	}
+	switch ev.Event {
+	case eventStart:
+		emoji, text := c.Slack.status(ev)
+		return setSlackStatus(c.Slack.token(), emoji, text)
+	case eventStop:
+		if a, err := loadActive(); err == nil && a != nil {
+			return nil
+		}
+		return setSlackStatus(c.Slack.token(), "", "")
+	}
+	return nil
+}
+
+// setSlackStatus sets the status of the token's user; empty emoji and text
+// clear it.
+func setSlackStatus(token, emoji, text string) error {
+	body, err := json.Marshal(map[string]any{
+		"profile": map[string]any{
+			"status_emoji":      emoji,
+			"status_text":       text,
+			"status_expiration": 0,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, slackProfileAPI, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := slackClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack: %s", resp.Status)
+	}
+	// Slack reports failures such as a revoked token with 200 OK.
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("slack: setting the status: %s", result.Error)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// sleepGap is how much longer than expected the wall clock may move between
+// two ticks before the machine is taken to have been suspended. Ticks come
+// at least once a minute.
+const sleepGap = 3 * time.Minute
+
+// checkSleep looks for a suspend between the previous tick and now while a
+// session was running, and opens a dialog asking whether to keep, discard or
+// split off the suspended time. Wall clock readings are compared because the
+// monotonic clock does not advance while the machine sleeps.
+func (m *model) checkSleep(now time.Time) {
+	last := m.lastTick
+	m.lastTick = now
+	if last.IsZero() || m.active == nil || last.Before(m.active.start) || m.active.paused() || m.dialog != nil || m.idlePaused {
+		return
+	}
+	from, to := last.Round(0), now.Round(0)
+	if to.Sub(from) < time.Minute+sleepGap {
+		return
+	}
+	// Idle sampling would see the same stretch as idle time; hold it off
+	// until the question is answered.
+	m.lastIdleCheck = now
+	start := m.active.start
+
+	// still reports whether the session the question is about is running.
+	still := func(m model) bool { return m.active != nil && m.active.start.Equal(start) }
+	discard := func(m model) (tea.Model, tea.Cmd) {
+		if still(m) {
+			m.active.pauses = append(m.active.pauses, pause{Start: from, End: to})
+			m.elapsed = m.active.elapsed(time.Now())
+			saveActive(*m.active)
+			m.status = fmt.Sprintf("Discarded %s of sleep", m.config.durationLong(to.Sub(from)))
+		}
+		return m, nil
+	}
+	split := func(m model) (tea.Model, tea.Cmd) {
+		if !still(m) {
+			return m, nil
+		}
+		sess := m.active.finish(to)
+		sess.SetBounds(sess.Start, from)
+		m.addSessions([]session{sess})
+		rest := *m.active
+		rest.start, rest.pauses = to, nil
+		rest.target = max(0, rest.target-sess.Duration)
+		m.active = &rest
+		m.elapsed = rest.elapsed(time.Now())
+		m.remindersSent = int(m.elapsed / trackingReminderEvery)
+		saveActive(rest)
+		m.status = "Session split around the sleep"
+		return m, tea.Batch(m.webhookCmd(stopEvent(sess)), m.hookCmd(stopEvent(sess)),
+			m.webhookCmd(startEvent(rest)), m.hookCmd(startEvent(rest)))
+	}
+
+	m.dialog = &confirmDialog{
+		title: "💤 Computer was asleep",
+		message: fmt.Sprintf("%s was running while the computer slept from %s to %s (%s).",
+			track.ProjectLabel(m.active.project), from.Format("Jan 02 15:04"), to.Format("15:04"), m.config.durationLong(to.Sub(from))),
+		options: []confirmOption{
+			{"k", "Keep", cancelDialog},
+			{"d", "Discard", discard},
+			{"s", "Split", split},
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"time-tracking/pkg/report"
+)
+
+// sparklineDays is how many days, today included, the menu's sparkline
+// covers.
+const sparklineDays = 14
+
+// sparkBars draw a day's total from a sliver to the largest of the days
+// shown; days with nothing tracked are drawn as heatmapShades[0].
+var sparkBars = []string{"▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"}
+
+// sparkline draws totals as a row of bars scaled to the largest.
+func sparkline(totals []time.Duration) string {
+	var most time.Duration
+	for _, d := range totals {
+		most = max(most, d)
+	}
+	var b strings.Builder
+	for _, d := range totals {
+		if d <= 0 {
+			b.WriteString(heatmapShades[0])
+			continue
+		}
+		level := int(int64(len(sparkBars)-1) * int64(d) / int64(most))
+		b.WriteString(sparkBars[level])
+	}
+	return b.String()
+}
+
+// viewSparkline renders the totals of the last sparklineDays days and the
+// time tracked today for the menu, or nothing before anything is tracked.
+func (m model) viewSparkline() string {
+	if len(m.history) == 0 && m.active == nil {
+		return ""
+	}
+	daily := m.dailyTotals()
+	now := time.Now()
+	totals := make([]time.Duration, sparklineDays)
+	for i := range totals {
+		key, _ := report.ByDay(session{Start: now.AddDate(0, 0, i-sparklineDays+1)})
+		totals[i] = daily[key]
+	}
+	line := fmt.Sprintf("Last %d days %s  Today %s", sparklineDays, sparkline(totals), m.config.duration(totals[sparklineDays-1]))
+	return normalStyle.Render(line) + "\n\n"
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+const splitTimeLayout = "15:04"
+
+// splitSession cuts sess in two at t, which must fall strictly inside it.
+// Pauses are divided between the halves, and the second half is a new
+// session with an ID of its own.
+func splitSession(sess session, t time.Time) (first, second session) {
+	first, second = sess, sess
+	first.SetBounds(sess.Start, t)
+	second.SetBounds(t, sess.End)
+	second.ID = track.NewID()
+	second.Tags = slices.Clone(sess.Tags)
+	return first, second
+}
+
+// parseSplitTime reads value as a clock time on the day sess starts, or the
+// next day for sessions running past midnight, and checks it falls inside
+// sess.
+func parseSplitTime(sess session, value string) (time.Time, error) {
+	clock, err := time.Parse(splitTimeLayout, strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (want HH:MM)", value)
+	}
+	y, mo, d := sess.Start.Date()
+	t := time.Date(y, mo, d, clock.Hour(), clock.Minute(), 0, 0, sess.Start.Location())
+	if !t.After(sess.Start) {
+		t = t.AddDate(0, 0, 1)
+	}
+	if !t.After(sess.Start) || !t.Before(sess.End) {
+		return time.Time{}, fmt.Errorf("%s is not between %s and %s", value,
+			sess.Start.Format(splitTimeLayout), sess.End.Format(splitTimeLayout))
+	}
+	return t, nil
+}
+
+// startSplit asks where to split the session at index i, suggesting the
+// middle of it.
+func (m model) startSplit(i int) (tea.Model, tea.Cmd) {
+	sess := m.history[i]
+	m.editing = editSplit
+	m.editTarget = i
+	m.editInput.Placeholder = "12:30"
+	m.editInput.SetValue(sess.Start.Add(sess.End.Sub(sess.Start) / 2).Format(splitTimeLayout))
+	m.editInput.CursorEnd()
+	return m, m.editInput.Focus()
+}
+
+// applySplitTime checks the split time typed for the session being split and
+// then asks for the second half's project and tags.
+func (m model) applySplitTime(value string) (tea.Model, tea.Cmd) {
+	if m.editTarget >= len(m.history) {
+		m.editing = editNone
+		m.editInput.Blur()
+		return m, nil
+	}
+	sess := m.history[m.editTarget]
+	t, err := parseSplitTime(sess, value)
+	if err != nil {
+		m.editing = editNone
+		m.editInput.Blur()
+		m.status = err.Error()
+		return m, nil
+	}
+	m.splitAt = t
+	m.editing = editSplitProject
+	m.editInput.Placeholder = "project #tags"
+	m.editInput.SetValue(strings.TrimSpace(sess.Project + " " + track.FormatTags(sess.Tags)))
+	m.editInput.CursorEnd()
+	return m, nil
+}
+
+// applySplit splits the session being edited at m.splitAt, giving the second
+// half the project and tags in input.
+func (m *model) applySplit(input string) {
+	if m.editTarget >= len(m.history) {
+		return
+	}
+	m.flushDelete()
+	first, second := splitSession(m.history[m.editTarget], m.splitAt)
+	second.Project, second.Tags = track.SplitProjectTags(input)
+	m.audit(auditSplit, []session{m.history[m.editTarget]}, []session{first, second})
+	m.history[m.editTarget] = first
+	m.history = slices.Insert(m.history, m.editTarget+1, second)
+	m.changed()
+	m.status = fmt.Sprintf("Split at %s", m.splitAt.Format(splitTimeLayout))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// startReminder is how long nothing may be tracked within working hours
+// before a reminder to start, or 0 if start_reminder is not set.
+func (c config) startReminder() time.Duration {
+	// loadConfig has checked that StartReminder parses.
+	d, _ := time.ParseDuration(c.StartReminder)
+	return d
+}
+
+func checkStartReminder(s string) error {
+	if s == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(s); err != nil || d <= 0 {
+		return fmt.Errorf("invalid start_reminder %q (want e.g. 15m)", s)
+	}
+	return nil
+}
+
+// untrackedSince returns when the untracked time at now began, given the
+// end of the last session: that end or the start of today's working hours,
+// whichever is later. ok is false outside working hours.
+func (c config) untrackedSince(lastEnd, now time.Time) (since time.Time, ok bool) {
+	start, end, ok := c.schedule().hours(now)
+	if !ok || now.Before(start) || !now.Before(end) {
+		return time.Time{}, false
+	}
+	return later(start, lastEnd), true
+}
+
+// startReminderDue reports whether to remind to start tracking at now,
+// with the last session ending at lastEnd and the last reminder sent at
+// reminded: once start_reminder has passed untracked within working hours,
+// and again each time it passes after that.
+func (c config) startReminderDue(lastEnd, reminded, now time.Time) bool {
+	every := c.startReminder()
+	if every <= 0 {
+		return false
+	}
+	since, ok := c.untrackedSince(lastEnd, now)
+	return ok && now.Sub(later(since, reminded)) >= every
+}
+
+func startReminderMessage(since time.Time) string {
+	return fmt.Sprintf("Nothing tracked since %s. Start a session?", since.Format("15:04"))
+}
+
+// lastEnd returns when the last session of history ended.
+func lastEnd(history []session) time.Time {
+	var end time.Time
+	for _, sess := range history {
+		end = later(end, sess.End)
+	}
+	return end
+}
+
+// startReminderAlert returns a notification command when nothing has been
+// tracked for start_reminder within working hours. With a daemon running
+// the daemon sends it instead.
+func (m *model) startReminderAlert() tea.Cmd {
+	now := time.Now()
+	if m.active != nil || m.readOnly || !m.config.startReminderDue(lastEnd(m.history), m.startReminded, now) {
+		return nil
+	}
+	m.startReminded = now
+	if daemonRunning() {
+		return nil
+	}
+	since, _ := m.config.untrackedSince(lastEnd(m.history), now)
+	return m.notifyCmd("Not tracking", startReminderMessage(since))
+}
+
+// viewStartReminder offers to start tracking once nothing has been tracked
+// for start_reminder within working hours, or renders nothing.
+func (m model) viewStartReminder() string {
+	now := time.Now()
+	if m.active != nil || !m.config.startReminderDue(lastEnd(m.history), time.Time{}, now) {
+		return ""
+	}
+	since, _ := m.config.untrackedSince(lastEnd(m.history), now)
+	line := fmt.Sprintf("⏰ Not tracking since %s. Press %s to start", since.Format("15:04"), m.keys.Start.Help().Key)
+	if last, ok := m.lastSession(); ok && m.keys.Resume.Enabled() {
+		line += fmt.Sprintf(" or %s to resume %s", m.keys.Resume.Help().Key, track.ProjectLabel(last.Project))
+	}
+	return selectedStyle.Render(line+".") + "\n\n"
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/report"
+	"time-tracking/pkg/track"
+)
+
+// focusDays is how many days back the stats view lists fragmentation for.
+const focusDays = 7
+
+// trendWeeks is how many weeks, this one included, the stats view compares.
+const trendWeeks = 4
+
+// overallStats are aggregate figures over a whole history.
+type overallStats struct {
+	total time.Duration
+	days  int // days with time tracked
+	first time.Time
+	// longest is the session with the most active time.
+	longest session
+	// topProject is the project with the most time tracked, and its time.
+	topProject      string
+	topProjectTotal time.Duration
+	// busiest is the weekday with the most time tracked, its time and the
+	// number of those days with time tracked.
+	busiest      time.Weekday
+	busiestTotal time.Duration
+	busiestDays  int
+}
+
+// computeStats works out the aggregate figures of history.
+func computeStats(history []session) overallStats {
+	var st overallStats
+	days := make(map[string]bool)
+	var weekdays [7]time.Duration
+	var weekdayDays [7]int
+	for _, sess := range history {
+		st.total += sess.Duration
+		if st.first.IsZero() || sess.Start.Before(st.first) {
+			st.first = sess.Start
+		}
+		if sess.Duration > st.longest.Duration {
+			st.longest = sess
+		}
+		key, _ := report.ByDay(sess)
+		if !days[key] {
+			days[key] = true
+			weekdayDays[sess.Start.Weekday()]++
+		}
+		weekdays[sess.Start.Weekday()] += sess.Duration
+	}
+	st.days = len(days)
+	for _, g := range report.GroupBy(history, report.ByProject) {
+		if total := report.Total(g.Sessions); total > st.topProjectTotal {
+			st.topProject, st.topProjectTotal = g.Key, total
+		}
+	}
+	for wd, total := range weekdays {
+		if total > st.busiestTotal {
+			st.busiest, st.busiestTotal, st.busiestDays = time.Weekday(wd), total, weekdayDays[wd]
+		}
+	}
+	return st
+}
+
+// weekTotals sums the time tracked in each of the n ISO weeks up to and
+// including the one today falls in, oldest first.
+func weekTotals(history []session, today time.Time, n int) []time.Duration {
+	first := report.StartOfISOWeek(today).AddDate(0, 0, -7*(n-1))
+	totals := make([]time.Duration, n)
+	for _, sess := range history {
+		if sess.Start.Before(first) {
+			continue
+		}
+		if w := int(report.StartOfISOWeek(sess.Start).Sub(first).Hours()/24/7 + 0.5); w < n {
+			totals[w] += sess.Duration
+		}
+	}
+	return totals
+}
+
+// focusDay measures how fragmented a day's tracked time was.
+type focusDay struct {
+	key, label string
+	tracked    time.Duration
+	// switches counts changes of project from one session to the next.
+	switches int
+	// blocks counts the stretches worked without a pause, a logged
+	// interruption or a change of project.
+	blocks int
+}
+
+// avgBlock is the average length of the day's uninterrupted blocks.
+func (d focusDay) avgBlock() time.Duration {
+	if d.blocks == 0 {
+		return 0
+	}
+	return d.tracked / time.Duration(d.blocks)
+}
+
+// focusByDay measures the days of history, oldest first. Sessions of one
+// project following each other make one block where nothing came between
+// them.
+func focusByDay(history []session) []focusDay {
+	sorted := make([]session, len(history))
+	copy(sorted, history)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	var days []focusDay
+	var last session
+	for _, sess := range sorted {
+		key, label := report.ByDay(sess)
+		if len(days) == 0 || days[len(days)-1].key != key {
+			days = append(days, focusDay{key: key, label: label})
+		} else if sess.Project != last.Project {
+			days[len(days)-1].switches++
+		}
+		d := &days[len(days)-1]
+		d.tracked += sess.Duration
+
+		blocks := 0
+		for _, span := range activeSpans(sess) {
+			blocks++
+			for _, in := range sess.Interruptions {
+				if in.At.After(span.Start) && in.At.Before(span.End) {
+					blocks++
+				}
+			}
+		}
+		// A session carrying straight on from the last one of its project,
+		// as when tracking was stopped and started again, continues its
+		// block.
+		if blocks > 0 && d.blocks > 0 && sess.Project == last.Project && sess.Start.Sub(last.End) <= time.Minute {
+			blocks--
+		}
+		d.blocks += blocks
+		last = sess
+	}
+	return days
+}
+
+func (m model) updateStats(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Back):
+		m.currentView = menuView
+		m.cursor = 0
+	}
+	return m, nil
+}
+
+// viewStats renders statistics computed from the history and the running
+// session: aggregate figures, the trend over the last weeks and how
+// fragmented the last days were.
+func (m model) viewStats() string {
+	s := titleStyle.Render("📊 Stats") + "\n\n"
+
+	history := m.history
+	if m.active != nil {
+		history = append(history[:len(history):len(history)], m.active.finish(time.Now()))
+	}
+	s += m.viewOverall(history)
+	s += "\n" + m.viewTrends(history)
+	s += "\n" + m.viewFocus(history)
+
+	s += "\n" + helpLine(m.keys.Back, m.keys.Help, m.keys.Quit)
+	return s
+}
+
+// viewOverall shows the aggregate figures of the whole history.
+func (m model) viewOverall(history []session) string {
+	s := normalStyle.Render("All time") + "\n"
+	st := computeStats(history)
+	if st.days == 0 {
+		return s + historyItemStyle.Render("  Nothing tracked.") + "\n"
+	}
+	lines := [][2]string{
+		{"Total", fmt.Sprintf("%s on %d days since %s", m.config.duration(st.total), st.days, st.first.Format("Jan 02, 2006"))},
+		{"Per day", fmt.Sprintf("%s on average on days tracked", m.config.duration(st.total/time.Duration(st.days)))},
+		{"Longest", fmt.Sprintf("%s, %s on %s", m.config.duration(st.longest.Duration), track.ProjectLabel(st.longest.Project), st.longest.Start.Format("Mon Jan 02, 2006"))},
+		{"Top project", fmt.Sprintf("%s, %s (%.0f%%)", track.ProjectLabel(st.topProject), m.config.duration(st.topProjectTotal), 100*st.topProjectTotal.Hours()/st.total.Hours())},
+		{"Busiest day", fmt.Sprintf("%ss, %s on average", st.busiest, m.config.duration(st.busiestTotal/time.Duration(st.busiestDays)))},
+	}
+	if current, best := m.streakCounts(); best > 0 {
+		lines = append(lines, [2]string{"Streak", fmt.Sprintf("%d days, best %d (%s)", current, best, m.config.streakDesc())})
+	}
+	for _, line := range lines {
+		s += historyItemStyle.Render(fmt.Sprintf("  %-12s %s", line[0], line[1])) + "\n"
+	}
+	return s
+}
+
+// viewTrends compares the time tracked in the last trendWeeks weeks, each
+// with the week before.
+func (m model) viewTrends(history []session) string {
+	now := time.Now()
+	s := normalStyle.Render(fmt.Sprintf("Last %d weeks", trendWeeks)) + "\n"
+	// One more week to compare the first with.
+	totals := weekTotals(history, now, trendWeeks+1)
+	monday := report.StartOfISOWeek(now).AddDate(0, 0, -7*(trendWeeks-1))
+	for i, total := range totals[1:] {
+		label, _ := report.ByWeek(session{Start: monday.AddDate(0, 0, 7*i)})
+		line := fmt.Sprintf("  %-10s %10s", label, m.config.duration(total))
+		if prev := totals[i]; prev > 0 {
+			line += fmt.Sprintf("  %+4.0f%%", 100*(total.Hours()-prev.Hours())/prev.Hours())
+		}
+		if i == trendWeeks-1 {
+			line += "  (so far)"
+		}
+		s += historyItemStyle.Render(line) + "\n"
+	}
+	return s
+}
+
+// viewFocus lists the project switches and average uninterrupted block of
+// the last focusDays days that have tracked time.
+func (m model) viewFocus(history []session) string {
+	now := time.Now()
+	y, mo, d := now.Date()
+	from := time.Date(y, mo, d-focusDays+1, 0, 0, 0, 0, now.Location())
+	days := focusByDay(dateRange{From: from}.Filter(history))
+
+	s := normalStyle.Render(fmt.Sprintf("Focus, last %d days", focusDays)) + "\n"
+	if len(days) == 0 {
+		return s + historyItemStyle.Render("  Nothing tracked.") + "\n"
+	}
+	var switches, blocks int
+	var tracked time.Duration
+	for _, day := range days {
+		switches += day.switches
+		blocks += day.blocks
+		tracked += day.tracked
+		s += historyItemStyle.Render(fmt.Sprintf("  %-18s %3d switches  %3d blocks of %s on average",
+			day.label, day.switches, day.blocks, m.config.duration(day.avgBlock()))) + "\n"
+	}
+	avg := focusDay{tracked: tracked, blocks: blocks}.avgBlock()
+	s += normalStyle.Render(fmt.Sprintf("  %.1f switches a day, blocks of %s on average",
+		float64(switches)/float64(len(days)), m.config.duration(avg))) + "\n"
+	return s
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"time-tracking/pkg/track"
+)
+
+// Storage persists the session history; see track.Storage.
+type Storage = track.Storage
+
+const (
+	storageJSON   = "json"
+	storageSQLite = "sqlite"
+)
+
+// openStorage returns the storage backend named by kind, first recovering
+// any sessions an interrupted stop left in journalFile. While another
+// instance holds lockFile the journal is left to it, and in read-only mode
+// it is left alone. With backups in
+// config.json, every change is uploaded to them.
+func openStorage(kind string) (Storage, error) {
+	var storage Storage
+	switch kind {
+	case storageJSON:
+		storage = newJSONStorage(dataFile)
+		backups.path = dataFile
+	case storageSQLite:
+		s, err := openSQLiteStorage(sqliteFile)
+		if err != nil {
+			return nil, err
+		}
+		storage = s
+		backups.path = sqliteFile
+	default:
+		return nil, fmt.Errorf("unknown storage %q (want %s or %s)", kind, storageJSON, storageSQLite)
+	}
+	// A broken config.json is reported by whoever loads it next.
+	if cfg, err := loadConfig(); err == nil && len(cfg.Backups) > 0 {
+		backups.targets = cfg.Backups
+		storage = &backedUpStorage{storage}
+	}
+	if lockedElsewhere() || readOnlyReason != "" {
+		return storage, nil
+	}
+	if err := replayJournal(storage); err != nil {
+		return nil, fmt.Errorf("recovering stopped sessions from %s: %w", journalFile, err)
+	}
+	return storage, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"time-tracking/pkg/track"
+)
+
+// jsonStorage keeps the history in a versioned JSON file with
+// track.JSONStorage, but encrypted along with the other data files when
+// they are, and migrated from a legacy history.txt report when missing.
+type jsonStorage struct {
+	track.JSONStorage
+}
+
+func newJSONStorage(path string) *jsonStorage {
+	s := &jsonStorage{track.JSONStorage{Path: path, ReadFile: readData, WriteFile: writeData}}
+	s.Missing = s.migrateLegacyHistory
+	return s
+}
+
+// migrateLegacyHistory imports sessions from a history.txt report, if there is
+// one, and saves them to the JSON file.
+func (s *jsonStorage) migrateLegacyHistory() ([]session, error) {
+	history, err := loadLegacyHistory(historyFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []session{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("migrating %s: %w", historyFile, err)
+	}
+	if readOnlyReason != "" {
+		return history, nil
+	}
+
+	// Keep the original around in case the parser missed anything; the
+	// report is regenerated below from the migrated sessions.
+	if err := os.Rename(historyFile, historyFile+".bak"); err != nil {
+		return nil, fmt.Errorf("migrating %s: %w", historyFile, err)
+	}
+	if err := s.Save(history); err != nil {
+		return nil, fmt.Errorf("migrating %s: %w", historyFile, err)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := writeReport(historyFile, history, cfg); err != nil {
+		return nil, fmt.Errorf("migrating %s: %w", historyFile, err)
+	}
+	return history, nil
+}
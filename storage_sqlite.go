@@ -0,0 +1,419 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"time-tracking/pkg/track"
+)
+
+const sqliteSchemaVersion = 5
+
+// sqliteMigrations upgrade a database one schema version at a time:
+// sqliteMigrations[0] takes version 1 to 2, and so on. Sessions upgraded to
+// version 3 are given their IDs by assignSQLiteIDs.
+var sqliteMigrations = []string{
+	`ALTER TABLE sessions ADD COLUMN billable INTEGER NOT NULL DEFAULT 1`,
+	`ALTER TABLE sessions ADD COLUMN uuid TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE sessions ADD COLUMN issue TEXT NOT NULL DEFAULT ''`,
+	sqliteInterruptionsTable,
+}
+
+// sqliteInterruptionsTable is part of the schema since version 5.
+const sqliteInterruptionsTable = `CREATE TABLE IF NOT EXISTS interruptions (
+	session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+	at         TEXT NOT NULL,
+	note       TEXT NOT NULL DEFAULT ''
+);`
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS projects (
+	id   INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id               INTEGER PRIMARY KEY,
+	uuid             TEXT NOT NULL DEFAULT '',
+	project_id       INTEGER REFERENCES projects(id),
+	note             TEXT NOT NULL DEFAULT '',
+	start            TEXT NOT NULL,
+	end              TEXT NOT NULL,
+	duration_seconds INTEGER NOT NULL,
+	billable         INTEGER NOT NULL DEFAULT 1,
+	issue            TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS sessions_start ON sessions(start);
+CREATE INDEX IF NOT EXISTS sessions_uuid ON sessions(uuid);
+
+CREATE TABLE IF NOT EXISTS pauses (
+	session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+	start      TEXT NOT NULL,
+	end        TEXT NOT NULL
+);
+
+` + sqliteInterruptionsTable + `
+
+CREATE TABLE IF NOT EXISTS tags (
+	id   INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS session_tags (
+	session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+	tag_id     INTEGER NOT NULL REFERENCES tags(id),
+	PRIMARY KEY (session_id, tag_id)
+);
+`
+
+// sqliteStorage keeps the history in a SQLite database so sessions, projects
+// and tags can be queried directly.
+type sqliteStorage struct {
+	db   *sql.DB
+	path string
+}
+
+func openSQLiteStorage(path string) (*sqliteStorage, error) {
+	if readOnlyReason != "" {
+		return openSQLiteReadOnly(path)
+	}
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		return nil, err
+	}
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	if version > sqliteSchemaVersion {
+		db.Close()
+		return nil, fmt.Errorf("%s has schema version %d, newer than supported version %d", path, version, sqliteSchemaVersion)
+	}
+	// A fresh database (version 0) gets the current schema directly.
+	for v := version; v > 0 && v < sqliteSchemaVersion; v++ {
+		if _, err := db.Exec(sqliteMigrations[v-1]); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("upgrading %s to schema version %d: %w", path, v+1, err)
+		}
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating schema in %s: %w", path, err)
+	}
+	if version > 0 && version < 3 {
+		if err := assignSQLiteIDs(db); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("upgrading %s to schema version 3: %w", path, err)
+		}
+	}
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", sqliteSchemaVersion)); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqliteStorage{db: db, path: path}, nil
+}
+
+// openSQLiteReadOnly opens the database at path without creating, upgrading
+// or otherwise changing it, which leaves older schema versions unreadable.
+func openSQLiteReadOnly(path string) (*sqliteStorage, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	if version != sqliteSchemaVersion {
+		db.Close()
+		return nil, fmt.Errorf("%s has schema version %d and needs upgrading to %d, which read-only mode does not do", path, version, sqliteSchemaVersion)
+	}
+	return &sqliteStorage{db: db, path: path}, nil
+}
+
+// assignSQLiteIDs gives the sessions stored before they had IDs the same
+// ones a sessions.json of that time gets, so moving between the two keeps
+// them.
+func assignSQLiteIDs(db *sql.DB) error {
+	rows, err := db.Query(`
+		SELECT s.id, COALESCE(p.name, ''), s.start
+		FROM sessions s LEFT JOIN projects p ON p.id = s.project_id
+		WHERE s.uuid = ''`)
+	if err != nil {
+		return err
+	}
+	ids := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var sess session
+		var start string
+		if err := rows.Scan(&id, &sess.Project, &start); err != nil {
+			rows.Close()
+			return err
+		}
+		if sess.Start, err = time.Parse(time.RFC3339Nano, start); err != nil {
+			rows.Close()
+			return fmt.Errorf("session %d: %w", id, err)
+		}
+		ids[id] = track.LegacyID(sess)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, uuid := range ids {
+		if _, err := db.Exec("UPDATE sessions SET uuid = ? WHERE id = ?", uuid, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads all sessions, oldest first.
+func (s *sqliteStorage) Load() ([]session, error) {
+	rows, err := s.db.Query(`
+		SELECT s.id, s.uuid, COALESCE(p.name, ''), s.note, s.start, s.end, s.billable, s.issue
+		FROM sessions s LEFT JOIN projects p ON p.id = s.project_id
+		ORDER BY s.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	history := []session{}
+	for rows.Next() {
+		var id int64
+		var sess session
+		var start, end string
+		if err := rows.Scan(&id, &sess.ID, &sess.Project, &sess.Note, &start, &end, &sess.Billable, &sess.Issue); err != nil {
+			return nil, err
+		}
+		if sess.Start, err = time.Parse(time.RFC3339Nano, start); err != nil {
+			return nil, fmt.Errorf("session %d: %w", id, err)
+		}
+		if sess.End, err = time.Parse(time.RFC3339Nano, end); err != nil {
+			return nil, fmt.Errorf("session %d: %w", id, err)
+		}
+		ids = append(ids, id)
+		history = append(history, sess)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, id := range ids {
+		pauses, err := s.loadPauses(id)
+		if err != nil {
+			return nil, err
+		}
+		tags, err := s.loadTags(id)
+		if err != nil {
+			return nil, err
+		}
+		interruptions, err := s.loadInterruptions(id)
+		if err != nil {
+			return nil, err
+		}
+		history[i].Pauses = pauses
+		history[i].Tags = tags
+		history[i].Interruptions = interruptions
+		history[i].Duration = history[i].End.Sub(history[i].Start) - track.PausedTotal(pauses, history[i].End)
+		history[i] = history[i].InLocal()
+	}
+	return history, nil
+}
+
+func (s *sqliteStorage) loadPauses(sessionID int64) ([]pause, error) {
+	rows, err := s.db.Query("SELECT start, end FROM pauses WHERE session_id = ? ORDER BY start", sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pauses []pause
+	for rows.Next() {
+		var start, end string
+		if err := rows.Scan(&start, &end); err != nil {
+			return nil, err
+		}
+		var p pause
+		if p.Start, err = time.Parse(time.RFC3339Nano, start); err != nil {
+			return nil, fmt.Errorf("session %d pause: %w", sessionID, err)
+		}
+		if p.End, err = time.Parse(time.RFC3339Nano, end); err != nil {
+			return nil, fmt.Errorf("session %d pause: %w", sessionID, err)
+		}
+		pauses = append(pauses, p)
+	}
+	return pauses, rows.Err()
+}
+
+func (s *sqliteStorage) loadInterruptions(sessionID int64) ([]track.Interruption, error) {
+	rows, err := s.db.Query("SELECT at, note FROM interruptions WHERE session_id = ? ORDER BY at", sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var interruptions []track.Interruption
+	for rows.Next() {
+		var at string
+		var in track.Interruption
+		if err := rows.Scan(&at, &in.Note); err != nil {
+			return nil, err
+		}
+		if in.At, err = time.Parse(time.RFC3339Nano, at); err != nil {
+			return nil, fmt.Errorf("session %d interruption: %w", sessionID, err)
+		}
+		interruptions = append(interruptions, in)
+	}
+	return interruptions, rows.Err()
+}
+
+func (s *sqliteStorage) loadTags(sessionID int64) ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT t.name FROM session_tags st JOIN tags t ON t.id = st.tag_id
+		WHERE st.session_id = ? ORDER BY st.rowid`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// Save replaces the stored sessions with history in a single transaction.
+func (s *sqliteStorage) Save(history []session) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM sessions"); err != nil {
+		return err
+	}
+	for _, sess := range history {
+		if err := insertSession(tx, sess); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM projects WHERE id NOT IN (SELECT project_id FROM sessions WHERE project_id IS NOT NULL)"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM session_tags)"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Append inserts sess as the newest session.
+func (s *sqliteStorage) Append(sess session) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertSession(tx, sess); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes the session with the given ID.
+func (s *sqliteStorage) Delete(id string) error {
+	res, err := s.db.Exec("DELETE FROM sessions WHERE id = (SELECT id FROM sessions WHERE uuid = ? ORDER BY id LIMIT 1)", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("no session with ID %s", id)
+	}
+	return nil
+}
+
+func insertSession(tx *sql.Tx, sess session) error {
+	var projectID sql.NullInt64
+	if sess.Project != "" {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO projects (name) VALUES (?)", sess.Project); err != nil {
+			return err
+		}
+		if err := tx.QueryRow("SELECT id FROM projects WHERE name = ?", sess.Project).Scan(&projectID); err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.Exec("INSERT INTO sessions (uuid, project_id, note, start, end, duration_seconds, billable, issue) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		sess.ID,
+		projectID,
+		sess.Note,
+		sess.Start.Format(time.RFC3339Nano),
+		sess.End.Format(time.RFC3339Nano),
+		int64(sess.Duration.Seconds()),
+		sess.Billable,
+		sess.Issue,
+	)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range sess.Pauses {
+		if _, err := tx.Exec("INSERT INTO pauses (session_id, start, end) VALUES (?, ?, ?)",
+			id,
+			p.Start.Format(time.RFC3339Nano),
+			p.End.Format(time.RFC3339Nano),
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, in := range sess.Interruptions {
+		if _, err := tx.Exec("INSERT INTO interruptions (session_id, at, note) VALUES (?, ?, ?)",
+			id,
+			in.At.Format(time.RFC3339Nano),
+			in.Note,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, tag := range sess.Tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", tag); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO session_tags (session_id, tag_id) SELECT ?, id FROM tags WHERE name = ?", id, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqliteStorage) Close() error {
+	return s.db.Close()
+}
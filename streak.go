@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// streakMinimum is the time a day needs tracked to count towards a streak,
+// or 0 if any time counts.
+func (c config) streakMinimum() time.Duration {
+	// loadConfig has checked that StreakMinimum parses.
+	d, _ := time.ParseDuration(c.StreakMinimum)
+	return d
+}
+
+func checkStreakMinimum(s string) error {
+	if s == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(s); err != nil || d <= 0 || d > 24*time.Hour {
+		return fmt.Errorf("invalid streak_minimum %q (want e.g. 4h)", s)
+	}
+	return nil
+}
+
+// streakCounts returns the current streak and the longest one since the
+// first session, counting the running session.
+func (m model) streakCounts() (current, best int) {
+	now := time.Now()
+	first := now
+	for _, sess := range m.history {
+		first = earlier(first, sess.Start)
+	}
+	y, mo, d := first.Date()
+	from := time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+	y, mo, d = now.Date()
+	today := time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+	return streaks(m.dailyTotals(), from, today, m.config.streakMinimum())
+}
+
+// streakDesc describes what a day needs to count towards a streak.
+func (c config) streakDesc() string {
+	if least := c.streakMinimum(); least > 0 {
+		return "days with " + track.FormatMinutes(least) + " or more"
+	}
+	return "days tracked"
+}
+
+// viewStreak renders the current and best streaks for the menu, or nothing
+// before there is one.
+func (m model) viewStreak() string {
+	current, best := m.streakCounts()
+	if best == 0 {
+		return ""
+	}
+	line := fmt.Sprintf("Streak %d days • best %d (%s)", current, best, m.config.streakDesc())
+	if current > 0 && current == best {
+		return selectedStyle.Render(line+"  🔥") + "\n\n"
+	}
+	return normalStyle.Render(line) + "\n\n"
+}
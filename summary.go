@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"time-tracking/pkg/report"
+)
+
+// summaryBarWidth is the width in cells of the bar drawn for the largest
+// row of the summary; the others are scaled to it.
+const summaryBarWidth = 20
+
+// barEighths are the partial blocks ending a bar, by eighths of a cell.
+var barEighths = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// summaryBar draws d as a bar of width cells for most, padded to width.
+func summaryBar(d, most time.Duration, width int) string {
+	if most <= 0 {
+		return strings.Repeat(" ", width)
+	}
+	eighths := int(int64(8*width) * int64(min(d, most)) / int64(most))
+	if eighths == 0 && d > 0 {
+		eighths = 1 // anything tracked gets a sliver
+	}
+	bar := strings.Repeat("█", eighths/8) + barEighths[eighths%8]
+	return bar + strings.Repeat(" ", width-(eighths+7)/8)
+}
+
+// summaryMode selects how the summary view groups sessions.
+type summaryMode int
+
+const (
+	summaryByDay summaryMode = iota
+	summaryByWeek
+	summaryByProject
+	summaryByTag
+	summaryByClient
+	summaryModeCount
+)
+
+func (s summaryMode) String() string {
+	switch s {
+	case summaryByWeek:
+		return "Week"
+	case summaryByProject:
+		return "Project"
+	case summaryByTag:
+		return "Tag"
+	case summaryByClient:
+		return "Client"
+	default:
+		return "Day"
+	}
+}
+
+// summaryRow is the total time tracked for one day, week, project, tag or
+// client.
+type summaryRow struct {
+	key      string // sort key
+	label    string
+	total    time.Duration
+	billable time.Duration
+	sessions int
+	earnings float64
+}
+
+// summarize totals history into rows grouped by key, with time rounded and
+// earnings at the rates in cfg. Rows are sorted by key, newest first for
+// dates.
+func summarize(history []session, cfg config, key report.KeyFunc) []summaryRow {
+	var rows []summaryRow
+	for _, g := range report.GroupBy(history, key) {
+		row := summaryRow{key: g.Key, label: g.Label, sessions: len(g.Sessions)}
+		row.total, row.earnings = cfg.billed(g.Sessions)
+		row.billable, _ = cfg.billableSplit(g.Sessions)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// summarizeByProject totals history per project, largest first.
+func summarizeByProject(history []session, cfg config) []summaryRow {
+	rows := summarize(history, cfg, report.ByProject)
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].total > rows[j].total })
+	return rows
+}
+
+// summarizeByTag totals history per tag, largest first. A session with
+// several tags counts towards each of them, so the rows can add up to more
+// than the time tracked.
+func summarizeByTag(history []session, cfg config) []summaryRow {
+	rows := summarize(report.SplitTags(history), cfg, report.ByTag)
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].total > rows[j].total })
+	return rows
+}
+
+// summaryRows totals the sessions passing the history view's filters.
+func (m model) summaryRows() []summaryRow {
+	history := m.filteredHistory()
+	switch m.summaryMode {
+	case summaryByWeek:
+		return summarize(history, m.config, report.ByWeek)
+	case summaryByProject:
+		return summarizeByProject(history, m.config)
+	case summaryByTag:
+		return summarizeByTag(history, m.config)
+	case summaryByClient:
+		return summarizeByClient(history, m.config)
+	default:
+		return summarize(history, m.config, report.ByDay)
+	}
+}
+
+func (m model) updateSummary(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Back):
+		m.currentView = menuView
+		m.cursor = 0
+	case key.Matches(msg, m.keys.NextMode):
+		m.summaryMode = (m.summaryMode + 1) % summaryModeCount
+	case key.Matches(msg, m.keys.PrevMode):
+		m.summaryMode = (m.summaryMode + summaryModeCount - 1) % summaryModeCount
+	case key.Matches(msg, m.keys.DateRange):
+		m.rangeFilter = nextRangePreset(m.rangeFilter)
+	}
+	return m, nil
+}
+
+func (m model) viewSummary() string {
+	s := titleStyle.Render("📊 Summary") + "\n\n"
+
+	for mode := summaryMode(0); mode < summaryModeCount; mode++ {
+		label := " " + mode.String() + " "
+		if mode == m.summaryMode {
+			s += selectedStyle.Render("[" + label + "]")
+		} else {
+			s += normalStyle.Render(" " + label + " ")
+		}
+	}
+	s += "\n\n"
+	if filter := m.filterLabel(); filter != "" {
+		s += normalStyle.Render("Filter: "+filter) + "\n\n"
+	}
+	if label := m.config.Rounding.label(); label != "" {
+		s += normalStyle.Render("Time "+label) + "\n\n"
+	}
+
+	var balance flexBalance
+	if m.config.weeklyTarget() > 0 {
+		balance = m.flexBalance()
+		s += m.viewFlexBalance(balance)
+	}
+
+	rows := m.summaryRows()
+	if len(rows) == 0 {
+		s += normalStyle.Render("No tracking sessions yet.") + "\n"
+	}
+
+	// Rows by tag can overlap, so the total comes from the sessions.
+	history := m.filteredHistory()
+	total, earnings := m.config.billed(history)
+	billable, nonBillable := m.config.billableSplit(history)
+	// Billable time gets its own column once anything is not billable.
+	split := nonBillable > 0
+	shares := m.summaryMode == summaryByProject || m.summaryMode == summaryByTag || m.summaryMode == summaryByClient
+	var most time.Duration
+	for _, row := range rows {
+		most = max(most, row.total)
+	}
+	for i, row := range rows {
+		// Dates share one colour; projects, tags and clients each get their
+		// own, as in the timeline.
+		bar := selectedStyle
+		if shares {
+			bar = lipgloss.NewStyle().Foreground(lipgloss.Color(timelineColors[i%len(timelineColors)]))
+		}
+		label := historyItemStyle.Render(fmt.Sprintf("%-34s ", row.label)) + bar.Render(summaryBar(row.total, most, summaryBarWidth))
+		line := fmt.Sprintf(" %10s  %3d session(s)",
+			m.config.duration(row.total),
+			row.sessions,
+		)
+		if shares && total > 0 {
+			line += fmt.Sprintf("  %5.1f%%", 100*float64(row.total)/float64(total))
+		}
+		if split {
+			line += fmt.Sprintf("  %10s billable", m.config.duration(row.billable))
+		}
+		if m.config.hasRates() {
+			line += fmt.Sprintf("  %10s", m.config.money(row.earnings))
+		}
+		if week, ok := balance.weeks[row.key]; ok && m.summaryMode == summaryByWeek {
+			line += fmt.Sprintf("  %s  balance %s", formatBalance(week.diff), formatBalance(week.balance))
+		}
+		s += label + historyItemStyle.Render(line) + "\n"
+	}
+	if len(rows) > 0 {
+		line := fmt.Sprintf("%-34s %*s %10s  %3d session(s)", "Total", summaryBarWidth, "", m.config.duration(total), len(history))
+		if shares {
+			line += fmt.Sprintf("  %5.1f%%", 100.0)
+		}
+		if split {
+			line += fmt.Sprintf("  %10s billable", m.config.duration(billable))
+		}
+		if m.config.hasRates() {
+			line += fmt.Sprintf("  %10s", m.config.money(earnings))
+		}
+		s += "\n" + projectHeaderStyle.Render(line) + "\n"
+		if split {
+			s += normalStyle.Render(fmt.Sprintf("%s not billable", m.config.durationLong(nonBillable))) + "\n"
+		}
+		if u := m.config.utilizationIn(history, m.historyRange(), time.Now()).describe(m.config); u != "" {
+			s += normalStyle.Render(u) + "\n"
+		}
+	}
+
+	s += "\n" + helpLine(pairHelp(m.keys.NextMode, m.keys.PrevMode, "day • week • project • tag • client"), m.keys.DateRange, m.keys.Back, m.keys.Help, m.keys.Quit)
+
+	return s
+}
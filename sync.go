@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// syncFile holds the history as it was after the last sync, the base that
+// tells changes made on this device from those made on others.
+var syncFile = "sync.json"
+
+// syncTokenEnv overrides the token of serve and sync.
+const syncTokenEnv = "TIME_TRACKER_SYNC_TOKEN"
+
+// defaultServeAddr is where serve listens unless told otherwise: only this
+// machine, until -addr opens it up.
+const defaultServeAddr = "127.0.0.1:8765"
+
+// maxSyncBody bounds the history a sync may upload.
+const maxSyncBody = 64 << 20
+
+// errSyncConflict is returned when the history on the server changed between
+// fetching and uploading it.
+var errSyncConflict = errors.New("the history on the server changed during the sync")
+
+var syncClient = &http.Client{Timeout: 30 * time.Second}
+
+// serverConfig is the "server" section of config.json: the time-tracker
+// serve that sync keeps this history in step with.
+type serverConfig struct {
+	// URL is where serve listens, e.g. "http://nas.local:8765".
+	URL string `json:"url,omitempty"`
+	// Token has to match the one serve was started with;
+	// TIME_TRACKER_SYNC_TOKEN overrides it.
+	Token string `json:"token,omitempty"`
+}
+
+func (s serverConfig) token() string {
+	if token := os.Getenv(syncTokenEnv); token != "" {
+		return token
+	}
+	return s.Token
+}
+
+// historyTag identifies a version of the encoded history for If-Match.
+func historyTag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// historyServer answers sync requests from other devices with the history in
+// storage.
+type historyServer struct {
+	mu      sync.Mutex
+	storage Storage
+	token   string
+	cfg     config
+}
+
+// runServe shares the history over HTTP at /sessions for sync on other
+// devices until interrupted.
+func runServe(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", defaultServeAddr, "`host:port` to listen on")
+	token := fs.String("token", "", "token clients have to send (default $"+syncTokenEnv+")")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	if *token == "" {
+		*token = os.Getenv(syncTokenEnv)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	if *token == "" {
+		fmt.Fprintf(os.Stderr, "Warning: no token set, so anyone who can reach %s can read and replace the history\n", *addr)
+	}
+	fmt.Printf("Serving the history on http://%s/sessions\n", *addr)
+	return http.ListenAndServe(*addr, &historyServer{storage: storage, token: *token, cfg: cfg})
+}
+
+func (s *historyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/sessions" {
+		http.NotFound(w, r)
+		return
+	}
+	if s.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.token)) != 1 {
+		http.Error(w, "wrong or missing token", http.StatusUnauthorized)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history, err := s.storage.Load()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data, err := track.Encode(history)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", historyTag(data))
+		w.Write(data)
+	case http.MethodPut:
+		// Only a client that has seen the current history may replace it,
+		// so a sync from another device in between is never overwritten.
+		match := r.Header.Get("If-Match")
+		if match == "" {
+			http.Error(w, "If-Match is required", http.StatusPreconditionRequired)
+			return
+		}
+		if match != historyTag(data) {
+			http.Error(w, errSyncConflict.Error(), http.StatusPreconditionFailed)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSyncBody))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		uploaded, err := track.Decode("upload", body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, sess := range uploaded {
+			if err := sess.Check(); err != nil {
+				http.Error(w, fmt.Sprintf("%s: %v", s.cfg.describeRecord(track.ToRecord(sess)), err), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := s.storage.Save(uploaded); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := writeReport(historyFile, uploaded, s.cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		saved, err := track.Encode(uploaded)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("ETag", historyTag(saved))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// request sends a request for the history to the server.
+func (s serverConfig) request(method string, body []byte, tag string) (*http.Response, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(s.URL, "/")+"/sessions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if token := s.token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if tag != "" {
+		req.Header.Set("If-Match", tag)
+	}
+	resp, err := syncClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sync: %w", err)
+	}
+	return resp, nil
+}
+
+// fetch downloads the history on the server and the tag to upload it back
+// with.
+func (s serverConfig) fetch() ([]session, string, error) {
+	resp, err := s.request(http.MethodGet, nil, "")
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("sync: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("sync: GET %s: %s: %s", s.URL, resp.Status, bytes.TrimSpace(body[:min(len(body), 512)]))
+	}
+	history, err := track.Decode(s.URL, body)
+	return history, resp.Header.Get("ETag"), err
+}
+
+// upload replaces the history on the server, provided it is still the
+// version tagged tag.
+func (s serverConfig) upload(history []session, tag string) error {
+	data, err := track.Encode(history)
+	if err != nil {
+		return err
+	}
+	resp, err := s.request(http.MethodPut, data, tag)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return errSyncConflict
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("sync: PUT %s: %s: %s", s.URL, resp.Status, bytes.TrimSpace(body[:min(len(body), 512)]))
+	}
+	return nil
+}
+
+// syncState is what syncFile holds: the server last synced with and the
+// history both sides agreed on then.
+type syncState struct {
+	URL      string         `json:"url"`
+	Sessions []track.Record `json:"sessions"`
+}
+
+// loadSyncBase returns the history as of the last sync with url, or nothing
+// before the first one.
+func loadSyncBase(url string) ([]session, error) {
+	data, err := readData(syncFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state syncState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", syncFile, err)
+	}
+	if state.URL != url {
+		return nil, nil
+	}
+	var base []session
+	for _, rec := range state.Sessions {
+		base = append(base, track.FromRecord(rec))
+	}
+	return base, nil
+}
+
+func saveSyncBase(url string, history []session) error {
+	state := syncState{URL: url, Sessions: make([]track.Record, 0, len(history))}
+	for _, sess := range history {
+		state.Sessions = append(state.Sessions, track.ToRecord(sess))
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeData(syncFile, data)
+}
+
+// syncConflicts counts the sessions changed both here and on the server
+// since base, differently.
+func syncConflicts(base, local, remote []session) int {
+	n := 0
+	for _, b := range base {
+		l := findSession(local, b.ID)
+		r := findSession(remote, b.ID)
+		if l == nil || r == nil || identical(*l, b) || identical(*r, b) || identical(*l, *r) {
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+func findSession(history []session, id string) *session {
+	for i := range history {
+		if history[i].ID == id {
+			return &history[i]
+		}
+	}
+	return nil
+}
+
+// syncRetries is how many times sync fetches the history again when another
+// device synced between its fetch and upload.
+const syncRetries = 2
+
+// syncResult is the history both sides have after a sync, with the number
+// of changes it brought here and of sessions changed on both sides.
+type syncResult struct {
+	merged              []session
+	received, conflicts int
+}
+
+// sync merges local and the history on the server, applying the changes
+// each side made since base to the other, and uploads the result. A session
+// changed on both sides keeps the local version unless preferServer.
+func (s serverConfig) sync(base, local []session, preferServer bool) (syncResult, error) {
+	for attempt := 0; ; attempt++ {
+		remote, tag, err := s.fetch()
+		if err != nil {
+			return syncResult{}, err
+		}
+		result := syncResult{conflicts: syncConflicts(base, local, remote)}
+		if preferServer {
+			result.merged, _ = mergeHistory(base, local, remote)
+		} else {
+			result.merged, _ = mergeHistory(base, remote, local)
+		}
+		// What the server brought is whatever merging changed here.
+		_, result.received = mergeHistory(local, result.merged, local)
+		err = s.upload(result.merged, tag)
+		if errors.Is(err, errSyncConflict) && attempt < syncRetries {
+			continue
+		}
+		return result, err
+	}
+}
+
+// runSync brings the history here and the one on the server in step: the
+// sessions added, edited and deleted on either side since the last sync are
+// applied to both. A session changed on both sides keeps this device's
+// version, the last write, unless -prefer server says otherwise.
+func runSync(storageKind string, args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	url := fs.String("url", "", "URL of the time-tracker serve to sync with (default server.url in config.json)")
+	prefer := fs.String("prefer", "local", "whose version of a session changed on both sides to keep: local or server")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *prefer != "local" && *prefer != "server" {
+		return fmt.Errorf("invalid -prefer %q (want local or server)", *prefer)
+	}
+	if err := checkWritable(); err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	server := cfg.Server
+	if *url != "" {
+		server.URL = *url
+	}
+	if server.URL == "" {
+		return fmt.Errorf("no server to sync with; set server.url in %s or pass -url", configFile)
+	}
+
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	local, err := storage.Load()
+	if err != nil {
+		return err
+	}
+	base, err := loadSyncBase(server.URL)
+	if err != nil {
+		return err
+	}
+
+	result, err := server.sync(base, local, *prefer == "server")
+	if err != nil {
+		return err
+	}
+	if err := storage.Save(result.merged); err != nil {
+		return err
+	}
+	if err := saveSyncBase(server.URL, result.merged); err != nil {
+		return err
+	}
+	if err := writeReport(historyFile, result.merged, cfg); err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("Synced %d sessions with %s, %d changes received", len(result.merged), server.URL, result.received)
+	if conflicts := result.conflicts; conflicts > 0 {
+		kept := "this device"
+		if *prefer == "server" {
+			kept = "the server"
+		}
+		msg += fmt.Sprintf(", %d changed on both sides kept as on %s", conflicts, kept)
+	}
+	fmt.Println(msg)
+	return nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// syncSession is a one hour session on day of October 2026 with note.
+func syncSession(id, note string, day int) session {
+	start := time.Date(2026, 10, day, 9, 0, 0, 0, time.Local)
+	return session{ID: id, Project: "p", Note: note, Start: start, End: start.Add(time.Hour), Duration: time.Hour}
+}
+
+// notes maps the IDs of history to the notes of the sessions.
+func notes(history []session) map[string]string {
+	m := make(map[string]string)
+	for _, sess := range history {
+		m[sess.ID] = sess.Note
+	}
+	return m
+}
+
+func sameNotes(t *testing.T, what string, got []session, want map[string]string) {
+	t.Helper()
+	if g := notes(got); !maps.Equal(g, want) {
+		t.Errorf("%s = %v, want %v", what, g, want)
+	}
+}
+
+// serveHistory starts a historyServer on history, with before run ahead of
+// every upload it answers, and returns the config to sync with it.
+func serveHistory(t *testing.T, before func(*track.MemoryStorage), history ...session) (serverConfig, *track.MemoryStorage) {
+	t.Helper()
+	tempDataDir(t)
+	if err := useProfile(""); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(syncTokenEnv, "")
+	storage := &track.MemoryStorage{Sessions: history}
+	server := &historyServer{storage: storage, token: "secret"}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && before != nil {
+			before(storage)
+		}
+		server.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return serverConfig{URL: ts.URL, Token: "secret"}, storage
+}
+
+func TestSyncLostUpdate(t *testing.T) {
+	a, b, c := syncSession("a", "", 1), syncSession("b", "", 2), syncSession("c", "", 3)
+
+	// Another device syncs c between this one's fetch and upload, once.
+	raced := false
+	server, storage := serveHistory(t, func(s *track.MemoryStorage) {
+		if !raced {
+			raced = true
+			s.Sessions = append(s.Sessions, c)
+		}
+	}, a)
+
+	result, err := server.sync([]session{a}, []session{a, b}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a": "", "b": "", "c": ""}
+	sameNotes(t, "merged", result.merged, want)
+	sameNotes(t, "on the server", storage.Sessions, want)
+	if result.received != 1 {
+		t.Errorf("received %d changes, want 1", result.received)
+	}
+}
+
+func TestSyncGivesUpAfterRetries(t *testing.T) {
+	a := syncSession("a", "", 1)
+	n := 0
+	server, storage := serveHistory(t, func(s *track.MemoryStorage) {
+		n++
+		s.Sessions = append(s.Sessions, syncSession(string(rune('b'+n)), "", 2+n))
+	}, a)
+
+	_, err := server.sync(nil, []session{a, syncSession("mine", "", 20)}, false)
+	if !errors.Is(err, errSyncConflict) {
+		t.Fatalf("sync against a server changing every time = %v, want %v", err, errSyncConflict)
+	}
+	if n != syncRetries+1 {
+		t.Errorf("uploaded %d times, want %d", n, syncRetries+1)
+	}
+	if slices.ContainsFunc(storage.Sessions, func(sess session) bool { return sess.ID == "mine" }) {
+		t.Error("a rejected upload reached the server")
+	}
+}
+
+func TestSyncUploadNeedsCurrentTag(t *testing.T) {
+	server, _ := serveHistory(t, nil, syncSession("a", "", 1))
+	_, tag, err := server.fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.upload([]session{syncSession("a", "first", 1)}, tag); err != nil {
+		t.Fatal(err)
+	}
+	// A second upload against the same version would lose the first.
+	if err := server.upload([]session{syncSession("a", "second", 1)}, tag); !errors.Is(err, errSyncConflict) {
+		t.Errorf("upload with a stale tag = %v, want %v", err, errSyncConflict)
+	}
+}
+
+func TestSyncConflictingEdits(t *testing.T) {
+	tests := []struct {
+		name         string
+		preferServer bool
+		want         string
+		received     int
+	}{
+		{"local wins", false, "local", 0},
+		{"server wins", true, "server", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, storage := serveHistory(t, nil, syncSession("a", "server", 1))
+			base := []session{syncSession("a", "base", 1)}
+			local := []session{syncSession("a", "local", 1)}
+
+			result, err := server.sync(base, local, tt.preferServer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.conflicts != 1 {
+				t.Errorf("conflicts = %d, want 1", result.conflicts)
+			}
+			if result.received != tt.received {
+				t.Errorf("received %d changes, want %d", result.received, tt.received)
+			}
+			sameNotes(t, "merged", result.merged, map[string]string{"a": tt.want})
+			sameNotes(t, "on the server", storage.Sessions, map[string]string{"a": tt.want})
+		})
+	}
+}
+
+func TestSyncDeletes(t *testing.T) {
+	a, b := syncSession("a", "", 1), syncSession("b", "", 2)
+	base := []session{a, b}
+
+	t.Run("deleted here", func(t *testing.T) {
+		server, storage := serveHistory(t, nil, a, b)
+		result, err := server.sync(base, []session{a}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sameNotes(t, "on the server", storage.Sessions, map[string]string{"a": ""})
+		if result.received != 0 || result.conflicts != 0 {
+			t.Errorf("received %d, conflicts %d, want none", result.received, result.conflicts)
+		}
+	})
+	t.Run("deleted on the server", func(t *testing.T) {
+		server, _ := serveHistory(t, nil, a)
+		result, err := server.sync(base, []session{a, b}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sameNotes(t, "merged", result.merged, map[string]string{"a": ""})
+		if result.received != 1 {
+			t.Errorf("received %d changes, want 1", result.received)
+		}
+	})
+	t.Run("edited here, deleted on the server", func(t *testing.T) {
+		server, storage := serveHistory(t, nil, a)
+		result, err := server.sync(base, []session{a, syncSession("b", "edited", 2)}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The edit is kept, as local changes win.
+		want := map[string]string{"a": "", "b": "edited"}
+		sameNotes(t, "merged", result.merged, want)
+		sameNotes(t, "on the server", storage.Sessions, want)
+	})
+}
+
+// spaces reads as endless whitespace, which JSON would skip.
+type spaces struct{}
+
+func (spaces) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = ' '
+	}
+	return len(p), nil
+}
+
+func TestServeLimitsUploads(t *testing.T) {
+	history := []session{syncSession("a", "", 1)}
+	storage := &track.MemoryStorage{Sessions: history}
+	server := &historyServer{storage: storage}
+	data, err := track.Encode(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/sessions", io.LimitReader(spaces{}, maxSyncBody+1))
+	req.Header.Set("If-Match", historyTag(data))
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("upload over %d bytes answered %d, want %d", maxSyncBody, rec.Code, http.StatusBadRequest)
+	}
+	sameNotes(t, "on the server", storage.Sessions, map[string]string{"a": ""})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"time-tracking/pkg/report"
+	"time-tracking/pkg/track"
+)
+
+// templateFuncs are the functions report and invoice templates can call,
+// formatting as config.json says where it has a say.
+func templateFuncs(cfg config) template.FuncMap {
+	return template.FuncMap{
+		"duration": cfg.duration,
+		"long":     cfg.durationLong,
+		"clock":    track.FormatClock,
+		"hours":    formatHours,
+		"money":    cfg.money,
+		"currency": formatCurrency,
+		"date":     func(t time.Time) string { return t.Format(report.DateLayout) },
+		"time":     func(t time.Time) string { return t.Format("15:04") },
+		"project":  track.ProjectLabel,
+		"tags":     track.FormatTags,
+		"issue":    issueRef,
+		"lines":    addressLines,
+		"join":     strings.Join,
+		"upper":    strings.ToUpper,
+		"lower":    strings.ToLower,
+		"pad":      func(n int, s string) string { return s + strings.Repeat(" ", max(0, n-len([]rune(s)))) },
+	}
+}
+
+// loadTemplate parses the Go text/template at path for a report or invoice.
+func loadTemplate(path string, cfg config) (*template.Template, error) {
+	return template.New(filepath.Base(path)).Funcs(templateFuncs(cfg)).ParseFiles(path)
+}
+
+// templateSession is a session as report templates see it: its stored
+// fields plus what it earns.
+type templateSession struct {
+	session
+	Rounded  time.Duration // duration as billed, when rounding per session
+	Rate     float64
+	Amount   float64
+	Currency string
+	Client   string // empty if the project has none
+}
+
+// templateRow is one day, week, project, tag or client in the totals of a
+// report template.
+type templateRow struct {
+	Key      string
+	Label    string
+	Sessions int
+	Total    time.Duration // rounded as config.json says
+	Billable time.Duration
+	Earnings float64
+}
+
+// reportData is what a report template is executed on.
+type reportData struct {
+	Range     string    // e.g. "This month" or "All time"
+	From, To  time.Time // To is the last day included; zero for an open end
+	Generated time.Time
+	Sessions  []templateSession
+
+	Total       time.Duration
+	Billable    time.Duration
+	NonBillable time.Duration
+	Earnings    float64
+	Currency    string
+	Rounding    string // how totals are rounded, empty if they are not
+
+	ByDay     []templateRow
+	ByWeek    []templateRow
+	ByProject []templateRow
+	ByTag     []templateRow
+	ByClient  []templateRow
+	ByIssue   []templateRow // Key is the issue's URL
+}
+
+func templateRows(rows []summaryRow) []templateRow {
+	out := make([]templateRow, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, templateRow{
+			Key:      row.key,
+			Label:    row.label,
+			Sessions: row.sessions,
+			Total:    row.total,
+			Billable: row.billable,
+			Earnings: row.earnings,
+		})
+	}
+	return out
+}
+
+// newReportData gathers history, already limited to dates, for a report
+// template.
+func newReportData(history []session, dates dateRange, cfg config) reportData {
+	data := reportData{
+		Range:     dates.Label,
+		From:      dates.From,
+		Generated: time.Now(),
+		Currency:  cfg.homeCurrency(),
+		Rounding:  cfg.Rounding.label(),
+		ByDay:     templateRows(summarize(history, cfg, report.ByDay)),
+		ByWeek:    templateRows(summarize(history, cfg, report.ByWeek)),
+		ByProject: templateRows(summarizeByProject(history, cfg)),
+		ByTag:     templateRows(summarizeByTag(history, cfg)),
+		ByClient:  templateRows(summarizeByClient(history, cfg)),
+		ByIssue:   templateRows(summarizeByIssue(history, cfg)),
+	}
+	if !dates.To.IsZero() {
+		data.To = dates.To.AddDate(0, 0, -1)
+	}
+	data.Total, data.Earnings = cfg.billed(history)
+	data.Billable, data.NonBillable = cfg.billableSplit(history)
+	for _, sess := range history {
+		ts := templateSession{
+			session:  sess,
+			Rounded:  sess.Duration,
+			Rate:     cfg.rateFor(sess.Project),
+			Currency: cfg.currencyFor(sess.Project),
+		}
+		if cfg.Rounding.perSession() {
+			ts.Rounded = cfg.Rounding.round(sess.Duration)
+		}
+		if sess.Billable {
+			ts.Amount = amountFor(ts.Rounded, ts.Rate)
+		}
+		if key := cfg.clientOf(sess.Project); key != "" {
+			ts.Client = cfg.clientName(key)
+		}
+		data.Sessions = append(data.Sessions, ts)
+	}
+	return data
+}
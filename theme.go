@@ -0,0 +1,161 @@
+package main
+
+import "github.com/charmbracelet/lipgloss"
+
+// palette is the set of colors the UI is drawn with, as lipgloss color
+// strings (ANSI 256 numbers or "#rrggbb"). Themes in config.json use the same
+// shape and may leave colors out to keep the defaults.
+type palette struct {
+	Title           string `json:"title,omitempty"`
+	Selected        string `json:"selected,omitempty"`
+	Normal          string `json:"normal,omitempty"`
+	Timer           string `json:"timer,omitempty"`
+	TimerBackground string `json:"timer_background,omitempty"`
+	HistoryItem     string `json:"history_item,omitempty"`
+	Help            string `json:"help,omitempty"`
+	ProjectHeader   string `json:"project_header,omitempty"`
+	Match           string `json:"match,omitempty"`
+	MatchBackground string `json:"match_background,omitempty"`
+	Warning         string `json:"warning,omitempty"`
+}
+
+var darkPalette = palette{
+	Title:           "205",
+	Selected:        "86",
+	Normal:          "252",
+	Timer:           "212",
+	TimerBackground: "236",
+	HistoryItem:     "243",
+	Help:            "241",
+	ProjectHeader:   "147",
+	Match:           "0",
+	MatchBackground: "220",
+	Warning:         "208",
+}
+
+var lightPalette = palette{
+	Title:           "162",
+	Selected:        "30",
+	Normal:          "235",
+	Timer:           "125",
+	TimerBackground: "254",
+	HistoryItem:     "242",
+	Help:            "245",
+	ProjectHeader:   "61",
+	Match:           "0",
+	MatchBackground: "222",
+	Warning:         "166",
+}
+
+// builtinThemes can be selected by name without defining them in
+// config.json.
+var builtinThemes = map[string]palette{
+	"dark":  darkPalette,
+	"light": lightPalette,
+	"high-contrast": {
+		Title:           "15",
+		Selected:        "11",
+		Normal:          "15",
+		Timer:           "0",
+		TimerBackground: "11",
+		HistoryItem:     "7",
+		Help:            "7",
+		ProjectHeader:   "14",
+		Match:           "0",
+		MatchBackground: "14",
+		Warning:         "9",
+	},
+}
+
+var (
+	titleStyle         lipgloss.Style
+	selectedStyle      lipgloss.Style
+	normalStyle        lipgloss.Style
+	timerStyle         lipgloss.Style
+	historyItemStyle   lipgloss.Style
+	helpStyle          lipgloss.Style
+	projectHeaderStyle lipgloss.Style
+	matchStyle         lipgloss.Style
+	warningStyle       lipgloss.Style
+)
+
+func init() {
+	applyPalette(darkPalette)
+}
+
+// applyPalette rebuilds the shared styles with the colors in p. Views pick
+// up the change on their next render.
+func applyPalette(p palette) {
+	titleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(p.Title)).
+		MarginBottom(1)
+
+	selectedStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(p.Selected)).
+		Bold(true)
+
+	normalStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(p.Normal))
+
+	timerStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(p.Timer)).
+		Background(lipgloss.Color(p.TimerBackground)).
+		Padding(0, 1)
+
+	historyItemStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(p.HistoryItem))
+
+	helpStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(p.Help))
+
+	projectHeaderStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(p.ProjectHeader))
+
+	matchStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(p.Match)).
+		Background(lipgloss.Color(p.MatchBackground))
+
+	warningStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(p.Warning))
+}
+
+// palette returns the colors to draw with: the configured theme laid over
+// the dark or light palette picked by the Dark mode setting.
+func (c config) palette() palette {
+	base := lightPalette
+	if c.Settings[settingDarkMode] {
+		base = darkPalette
+	}
+	if theme, ok := c.Themes[c.Theme]; ok {
+		return base.with(theme)
+	}
+	if theme, ok := builtinThemes[c.Theme]; ok {
+		return theme
+	}
+	return base
+}
+
+// with returns p with every color set in overrides replaced.
+func (p palette) with(overrides palette) palette {
+	set := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+	set(&p.Title, overrides.Title)
+	set(&p.Selected, overrides.Selected)
+	set(&p.Normal, overrides.Normal)
+	set(&p.Timer, overrides.Timer)
+	set(&p.TimerBackground, overrides.TimerBackground)
+	set(&p.HistoryItem, overrides.HistoryItem)
+	set(&p.Help, overrides.Help)
+	set(&p.ProjectHeader, overrides.ProjectHeader)
+	set(&p.Match, overrides.Match)
+	set(&p.MatchBackground, overrides.MatchBackground)
+	set(&p.Warning, overrides.Warning)
+	return p
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"time-tracking/pkg/track"
+)
+
+// timelineColors tell projects apart in the timeline, assigned in order of
+// each project's first session of the day.
+var timelineColors = []string{"39", "208", "141", "78", "203", "220", "45", "171"}
+
+const (
+	timelineLabelWidth = 18
+	timelineDayLayout  = "2006-01-02"
+)
+
+// timelineBar is one session clipped to the day shown in the timeline.
+type timelineBar struct {
+	label    string
+	project  string
+	start    time.Time
+	end      time.Time
+	pauses   []pause
+	duration time.Duration
+}
+
+// timelineDate is the day shown in the timeline, today unless another was
+// chosen.
+func (m model) timelineDate() time.Time {
+	if !m.timelineDay.IsZero() {
+		return m.timelineDay
+	}
+	now := time.Now()
+	y, mo, d := now.Date()
+	return time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+}
+
+// timelineBars returns the sessions overlapping day, including the running
+// one, in start order.
+func (m model) timelineBars(day time.Time) []timelineBar {
+	next := day.AddDate(0, 0, 1)
+	var bars []timelineBar
+	add := func(project string, start, end time.Time, pauses []pause, d time.Duration) {
+		if !start.Before(next) || !end.After(day) {
+			return
+		}
+		bars = append(bars, timelineBar{
+			label:    start.Format("15:04") + " " + track.ProjectLabel(project),
+			project:  project,
+			start:    start,
+			end:      end,
+			pauses:   pauses,
+			duration: d,
+		})
+	}
+	for _, sess := range m.history {
+		add(sess.Project, sess.Start, sess.End, sess.Pauses, sess.Duration)
+	}
+	if m.active != nil {
+		add(m.active.project, m.active.start, time.Now(), m.active.pauses, m.elapsed)
+	}
+	sort.SliceStable(bars, func(i, j int) bool { return bars[i].start.Before(bars[j].start) })
+	return bars
+}
+
+// timelineCells returns, for each of n equal slices of the 24 hours on the
+// wall clock of day, whether bar was running (1) or paused (2) during any of
+// it, or 0. Slices lie on the hour axis even on daylight saving days: those
+// in an hour skipped in spring stay empty, and the slice before an hour
+// repeated in autumn stretches over its first pass.
+func timelineCells(bar timelineBar, day time.Time, n int) []int {
+	slot := 24 * time.Hour / time.Duration(n)
+	cells := make([]int, n)
+	for i := range cells {
+		from, to := track.ClockOn(day, time.Duration(i)*slot), track.ClockOn(day, time.Duration(i+1)*slot)
+		if track.SinceMidnight(from) != time.Duration(i)*slot {
+			continue
+		}
+		if !bar.start.Before(to) || !bar.end.After(from) {
+			continue
+		}
+		cells[i] = 2
+		// Running unless the overlap lies entirely inside a pause.
+		lo, hi := later(from, bar.start), earlier(to, bar.end)
+		paused := false
+		for _, p := range bar.pauses {
+			end := p.End
+			if end.IsZero() {
+				end = time.Now()
+			}
+			if !p.Start.After(lo) && !end.Before(hi) {
+				paused = true
+			}
+		}
+		if !paused {
+			cells[i] = 1
+		}
+	}
+	return cells
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// timelineWidth is the number of cells the day is divided into: three per
+// hour, or two on a narrow terminal.
+func (m model) timelineWidth() int {
+	if m.width > 0 && m.width < timelineLabelWidth+72 {
+		return 48
+	}
+	return 72
+}
+
+func (m model) updateTimeline(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Back):
+		m.currentView = menuView
+		m.cursor = 0
+	case key.Matches(msg, m.keys.PrevMode):
+		m.timelineDay = m.timelineDate().AddDate(0, 0, -1)
+	case key.Matches(msg, m.keys.NextMode):
+		m.timelineDay = m.timelineDate().AddDate(0, 0, 1)
+	case key.Matches(msg, m.keys.CustomRange):
+		m.editing = editDay
+		m.editInput.Placeholder = timelineDayLayout
+		m.editInput.SetValue(m.timelineDate().Format(timelineDayLayout))
+		m.editInput.CursorEnd()
+		return m, m.editInput.Focus()
+	}
+	return m, nil
+}
+
+// applyDay shows the day typed as YYYY-MM-DD in the timeline.
+func (m *model) applyDay(value string) {
+	day, err := time.ParseInLocation(timelineDayLayout, strings.TrimSpace(value), time.Local)
+	if err != nil {
+		m.status = fmt.Sprintf("invalid date %q (want YYYY-MM-DD)", value)
+		return
+	}
+	m.status = ""
+	m.timelineDay = day
+}
+
+// viewTimeline draws the sessions of one day as bars on an hour axis, with a
+// combined row underneath where gaps and overlaps stand out.
+func (m model) viewTimeline() string {
+	s := titleStyle.Render("🕒 Timeline") + "\n\n"
+
+	day := m.timelineDate()
+	s += selectedStyle.Render("◀ "+day.Format("Mon Jan 02, 2006")+" ▶") + "\n\n"
+
+	n := m.timelineWidth()
+	perHour := n / 24
+
+	axis := []rune(strings.Repeat(" ", n+3))
+	for h := 0; h <= 24; h += 3 {
+		copy(axis[h*perHour:], []rune(fmt.Sprint(h)))
+	}
+	s += historyItemStyle.Render(strings.Repeat(" ", timelineLabelWidth)+strings.TrimRight(string(axis), " ")) + "\n"
+
+	bars := m.timelineBars(day)
+	if len(bars) == 0 {
+		s += normalStyle.Render("Nothing tracked on this day.") + "\n"
+	}
+
+	colors := make(map[string]lipgloss.Style)
+	coverage := make([]int, n)
+	var total time.Duration
+	var last time.Time
+	for _, bar := range bars {
+		style, ok := colors[bar.project]
+		if !ok {
+			style = lipgloss.NewStyle().Foreground(lipgloss.Color(timelineColors[len(colors)%len(timelineColors)]))
+			colors[bar.project] = style
+		}
+		total += bar.duration
+		last = later(last, bar.end)
+
+		var line strings.Builder
+		for i, c := range timelineCells(bar, day, n) {
+			switch c {
+			case 1:
+				line.WriteString(style.Render("█"))
+				coverage[i]++
+			case 2:
+				line.WriteString(style.Render("░"))
+			default:
+				line.WriteString(historyItemStyle.Render(timelineBackground(i, perHour)))
+			}
+		}
+		s += normalStyle.Render(fmt.Sprintf("%-*s", timelineLabelWidth, truncate(bar.label, timelineLabelWidth-1))) + line.String() + "\n"
+	}
+
+	if len(bars) > 0 {
+		var line strings.Builder
+		overlaps := false
+		for i, c := range coverage {
+			switch {
+			case c > 1:
+				line.WriteString(matchStyle.Render("▓"))
+				overlaps = true
+			case c == 1:
+				line.WriteString(selectedStyle.Render("█"))
+			default:
+				line.WriteString(historyItemStyle.Render(timelineBackground(i, perHour)))
+			}
+		}
+		s += "\n" + projectHeaderStyle.Render(fmt.Sprintf("%-*s", timelineLabelWidth, "All")) + line.String() + "\n\n"
+
+		summary := fmt.Sprintf("Tracked %s • %s – %s", m.config.duration(total),
+			bars[0].start.Format("15:04"), last.Format("15:04"))
+		if overlaps {
+			summary += " • " + matchStyle.Render("▓") + " overlapping sessions"
+		}
+		s += normalStyle.Render(summary) + "\n"
+	}
+
+	if m.editing != editNone {
+		return s + "\n" + m.viewEdit()
+	}
+	if m.status != "" {
+		s += "\n" + normalStyle.Render(m.status) + "\n"
+	}
+
+	s += "\n" + helpLine(pairHelp(m.keys.PrevMode, m.keys.NextMode, "previous/next day"), m.keys.CustomRange, m.keys.Back, m.keys.Help, m.keys.Quit)
+
+	return s
+}
+
+// timelineBackground marks the start of each hour in empty cells.
+func timelineBackground(cell, perHour int) string {
+	if cell%perHour == 0 {
+		return "·"
+	}
+	return " "
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// berlin makes Europe/Berlin the local time zone for the test. In 2026 its
+// clocks go forward an hour at 02:00 on March 29 and back at 03:00 on
+// October 25.
+func berlin(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	local := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = local })
+	return loc
+}
+
+// localAt parses s, an RFC 3339 time, into the local time zone.
+func localAt(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t.Local()
+}
+
+// hourCells returns 24 cells set to v, with the ones at the given indices
+// set to other.
+func hourCells(v, other int, at ...int) []int {
+	cells := make([]int, 24)
+	for i := range cells {
+		cells[i] = v
+		if slices.Contains(at, i) {
+			cells[i] = other
+		}
+	}
+	return cells
+}
+
+func TestTimelineCellsAcrossDST(t *testing.T) {
+	loc := berlin(t)
+	spring := time.Date(2026, 3, 29, 12, 0, 0, 0, loc)
+	fall := time.Date(2026, 10, 25, 12, 0, 0, 0, loc)
+	wholeDay := func(day time.Time) timelineBar {
+		return timelineBar{start: track.ClockOn(day, 0), end: track.ClockOn(day, 24*time.Hour)}
+	}
+	tests := []struct {
+		name string
+		bar  timelineBar
+		day  time.Time
+		want []int
+	}{
+		{
+			name: "spring whole day skips 02:00",
+			bar:  wholeDay(spring), day: spring,
+			want: hourCells(1, 0, 2),
+		},
+		{
+			name: "spring across the skipped hour",
+			bar:  timelineBar{start: localAt("2026-03-29T00:30:00Z"), end: localAt("2026-03-29T01:30:00Z")}, // 01:30 CET–03:30 CEST
+			day:  spring,
+			want: hourCells(0, 1, 1, 3),
+		},
+		{
+			name: "fall whole day",
+			bar:  wholeDay(fall), day: fall,
+			want: hourCells(1, 1),
+		},
+		{
+			name: "fall first pass of 02:00 goes to the slice before",
+			bar:  timelineBar{start: localAt("2026-10-25T00:10:00Z"), end: localAt("2026-10-25T00:50:00Z")}, // 02:10–02:50 CEST
+			day:  fall,
+			want: hourCells(0, 1, 1),
+		},
+		{
+			name: "fall second pass of 02:00",
+			bar:  timelineBar{start: localAt("2026-10-25T01:10:00Z"), end: localAt("2026-10-25T01:50:00Z")}, // 02:10–02:50 CET
+			day:  fall,
+			want: hourCells(0, 1, 2),
+		},
+		{
+			name: "fall paused through the first pass",
+			bar: timelineBar{
+				start:  localAt("2026-10-25T00:00:00Z"), // 02:00 CEST
+				end:    localAt("2026-10-25T02:00:00Z"), // 03:00 CET
+				pauses: []pause{{Start: localAt("2026-10-25T00:00:00Z"), End: localAt("2026-10-25T01:00:00Z")}},
+			},
+			day: fall,
+			want: func() []int {
+				cells := hourCells(0, 0)
+				cells[1], cells[2] = 2, 1
+				return cells
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := timelineCells(tt.bar, tt.day, 24); !slices.Equal(got, tt.want) {
+				t.Errorf("timelineCells = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+// timesheet is a time tracking service that clients bill from, such as
+// Clockify or Harvest, which finished sessions are pushed to as entries.
+type timesheet interface {
+	// load fetches the entries already there within r and what sessions
+	// are mapped to, such as projects.
+	load(r dateRange) error
+	// has reports whether sess is among the entries load fetched.
+	has(sess session) bool
+	// check returns an error if sess cannot be pushed, e.g. because its
+	// project is missing there.
+	check(sess session) error
+	push(sess session) error
+}
+
+// remoteProject returns the name project goes by in a service, going by
+// the mapping of local to remote names in its config section. Unmapped
+// names are used as is.
+func remoteProject(projects map[string]string, project string) string {
+	if remote, ok := projects[project]; ok {
+		return remote
+	}
+	return project
+}
+
+// runTimesheetPush runs `NAME push` against the service connect opens,
+// title naming it to the user: it adds the finished sessions in -range not
+// there yet as entries.
+func runTimesheetPush(storageKind, name, title string, args []string, connect func(cfg config) (timesheet, error)) error {
+	if len(args) == 0 || args[0] != "push" {
+		return fmt.Errorf("usage: %s push [-range RANGE] [-dry-run]", name)
+	}
+	fs := flag.NewFlagSet(name+" push", flag.ExitOnError)
+	rangeSpec := fs.String("range", "month", "push today, week, month, last-month or FROM..TO")
+	dryRun := fs.Bool("dry-run", false, "only list what would be pushed")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	dates, err := parseRange(*rangeSpec, time.Now())
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	service, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	if err := service.load(dates); err != nil {
+		return err
+	}
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	pushed := 0
+	local := dates.Filter(history)
+	for _, sess := range local {
+		if service.has(sess) {
+			continue
+		}
+		if err := service.check(sess); err != nil {
+			return err
+		}
+		if *dryRun {
+			fmt.Printf("%s  %s  %s\n", sess.Start.Format(csvTimeLayout), track.FormatClock(sess.Duration), track.ProjectLabel(sess.Project))
+		} else if err := service.push(sess); err != nil {
+			return fmt.Errorf("pushed %d sessions before failing: %w", pushed, err)
+		}
+		pushed++
+	}
+	if *dryRun {
+		fmt.Printf("Would push %d of %d sessions\n", pushed, len(local))
+		return nil
+	}
+	fmt.Printf("Pushed %d of %d sessions to %s\n", pushed, len(local), title)
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func checkTimezone(name string) error {
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("invalid timezone %q (want e.g. Europe/Berlin)", name)
+	}
+	return nil
+}
+
+// systemLocation is the computer's own time zone as it was at startup, used
+// when config.json names none.
+var systemLocation = time.Local
+
+// useTimezone makes the time zone named by timezone in config.json the local
+// one, so history, reports and commands show times and count days in it
+// wherever the computer happens to be. It has to run before any sessions are
+// loaded. It starts over from systemLocation every time, so a profile
+// without a timezone does not keep the one of the profile used before. An
+// unreadable config.json is left for the caller's own loadConfig to report.
+func useTimezone() {
+	time.Local = systemLocation
+	cfg, err := loadConfig()
+	if err != nil || cfg.Timezone == "" {
+		return
+	}
+	// loadConfig has checked the name.
+	if loc, err := time.LoadLocation(cfg.Timezone); err == nil {
+		time.Local = loc
+	}
+}
+
+// timezoneLabel names the time zone times are shown in when timezone is
+// set, or returns "".
+func (c config) timezoneLabel() string {
+	if c.Timezone == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s (%s)", c.Timezone, time.Now().Format("MST"))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"time-tracking/pkg/report"
+)
+
+// tempDataDir keeps the data directory in a fresh temporary one for the
+// test, restoring the file paths and time zone in use afterwards.
+func tempDataDir(t *testing.T) {
+	t.Helper()
+	files := []*string{&dataFile, &sqliteFile, &activeFile, &configFile, &historyFile,
+		&auditFile, &trashFile, &journalFile, &lockFile, &socketFile, &gcalTokenFile, &syncFile}
+	saved := make([]string, len(files))
+	for i, file := range files {
+		saved[i] = *file
+	}
+	root, name, local := dataRoot, profile, time.Local
+	t.Cleanup(func() {
+		for i, file := range files {
+			*file = saved[i]
+		}
+		dataRoot, profile, time.Local = root, name, local
+	})
+	dataRoot = t.TempDir()
+}
+
+func writeConfig(t *testing.T, data string) {
+	t.Helper()
+	if err := os.WriteFile(configFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUseTimezoneAcrossProfiles(t *testing.T) {
+	tempDataDir(t)
+
+	if err := useProfile("tokyo"); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, `{"timezone": "Asia/Tokyo"}`)
+	useTimezone()
+	if got := time.Local.String(); got != "Asia/Tokyo" {
+		t.Fatalf("time zone in tokyo = %s, want Asia/Tokyo", got)
+	}
+
+	// A profile without a timezone goes back to the system's.
+	if err := useProfile("plain"); err != nil {
+		t.Fatal(err)
+	}
+	useTimezone()
+	if time.Local != systemLocation {
+		t.Errorf("time zone in plain = %s, want the system's %s", time.Local, systemLocation)
+	}
+
+	// So does one whose config.json cannot be read.
+	if err := useProfile("tokyo"); err != nil {
+		t.Fatal(err)
+	}
+	useTimezone()
+	if err := useProfile("broken"); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, `{`)
+	useTimezone()
+	if time.Local != systemLocation {
+		t.Errorf("time zone in broken = %s, want the system's %s", time.Local, systemLocation)
+	}
+}
+
+func TestHistoryAfterTimezoneChange(t *testing.T) {
+	tempDataDir(t)
+	if err := useProfile(""); err != nil {
+		t.Fatal(err)
+	}
+	// Recorded in Tokyo: 08:00–10:30 on Oct 2 with a half hour pause, which
+	// is still Oct 1 in New York.
+	err := os.WriteFile(dataFile, []byte(`{"version": 3, "sessions": [{
+		"id": "a", "project": "p",
+		"start": "2026-10-02T08:00:00+09:00", "end": "2026-10-02T10:30:00+09:00",
+		"pauses": [{"start": "2026-10-02T09:00:00+09:00", "end": "2026-10-02T09:30:00+09:00"}]
+	}]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		timezone   string
+		day, clock string
+	}{
+		{"Asia/Tokyo", "2026-10-02", "08:00–10:30"},
+		{"America/New_York", "2026-10-01", "19:00–21:30"},
+		{"Europe/Berlin", "2026-10-02", "01:00–03:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.timezone, func(t *testing.T) {
+			writeConfig(t, `{"timezone": "`+tt.timezone+`"}`)
+			useTimezone()
+			history, err := newJSONStorage(dataFile).Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			sess := history[0]
+			if got := sess.Start.Location().String(); got != tt.timezone {
+				t.Errorf("start in %s, want %s", got, tt.timezone)
+			}
+			if day, _ := report.ByDay(sess); day != tt.day {
+				t.Errorf("day = %s, want %s", day, tt.day)
+			}
+			if got := sess.Start.Format("15:04") + "–" + sess.End.Format("15:04"); got != tt.clock {
+				t.Errorf("times = %s, want %s", got, tt.clock)
+			}
+			if sess.Duration != 2*time.Hour {
+				t.Errorf("duration = %s, want 2h", sess.Duration)
+			}
+			if got := report.Total(history); got != 2*time.Hour {
+				t.Errorf("total = %s, want 2h", got)
+			}
+		})
+	}
+}
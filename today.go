@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/report"
+	"time-tracking/pkg/track"
+)
+
+func (m model) updateToday(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Back):
+		m.currentView = menuView
+		m.cursor = 0
+	case key.Matches(msg, m.keys.Snooze) && m.snoozeBreak():
+	case m.blockedReadOnly():
+	case key.Matches(msg, m.keys.Stop) && m.active != nil:
+		return m, m.stopTracking()
+	case key.Matches(msg, m.keys.Start):
+		return m.openStart()
+	case key.Matches(msg, m.keys.Resume):
+		return m.resumeLast()
+	case key.Matches(msg, m.keys.Pause):
+		if m.active != nil {
+			return m, m.togglePause()
+		}
+	}
+	return m, nil
+}
+
+// todaySessions returns the sessions of history started today, in start
+// order.
+func (m model) todaySessions() []session {
+	today, _ := report.ByDay(session{Start: time.Now()})
+	var sessions []session
+	for _, sess := range m.history {
+		if key, _ := report.ByDay(sess); key == today {
+			sessions = append(sessions, sess)
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].Start.Before(sessions[j].Start) })
+	return sessions
+}
+
+// viewToday is a dashboard of the day: the running timer, the sessions
+// tracked today, their total and how far off the daily goal is.
+func (m model) viewToday() string {
+	now := time.Now()
+	s := titleStyle.Render("📅 Today · "+now.Format("Mon Jan 02")) + "\n\n"
+
+	if m.active != nil {
+		state := "●"
+		if m.active.paused() {
+			state = "⏸"
+		}
+		s += timerStyle.Render(fmt.Sprintf("%s %s  %s", state, m.config.timer(m.elapsed), track.ProjectLabel(m.active.project))) + "\n\n"
+		s += m.viewLongSession()
+		s += m.viewBreak()
+	} else if reminder := m.viewStartReminder(); reminder != "" {
+		s += reminder
+	} else {
+		s += normalStyle.Render("Not tracking.") + "\n\n"
+	}
+
+	sessions := m.todaySessions()
+	if len(sessions) == 0 && m.active == nil {
+		s += historyItemStyle.Render("Nothing tracked yet today.") + "\n"
+	}
+	for _, sess := range sessions {
+		s += historyItemStyle.Render(fmt.Sprintf("  %s–%s  %10s  %-20s %s",
+			sess.Start.Format("15:04"), sess.End.Format("15:04"), m.config.duration(sess.Duration),
+			truncate(track.ProjectLabel(sess.Project), 20), truncate(sess.Note, 40))) + "\n"
+	}
+	if m.active != nil {
+		s += selectedStyle.Render(fmt.Sprintf("  %s–now    %10s  %-20s %s",
+			m.active.start.Format("15:04"), m.config.duration(m.elapsed),
+			truncate(track.ProjectLabel(m.active.project), 20), truncate(m.active.note, 40))) + "\n"
+	}
+
+	done := m.trackedToday(m.elapsed)
+	s += "\n" + projectHeaderStyle.Render(fmt.Sprintf("Total %s", m.config.duration(done))) + "\n\n"
+
+	if goal := m.config.dailyGoal(); goal > 0 {
+		s += m.viewGoal()
+		if left := goal - done; left > 0 {
+			line := fmt.Sprintf("%s to go", m.config.durationLong(left))
+			if m.active != nil && !m.active.paused() {
+				line += fmt.Sprintf(", done at %s if you keep going", now.Add(left).Format("15:04"))
+			}
+			s += normalStyle.Render(line) + "\n\n"
+		}
+	}
+
+	help := []key.Binding{m.keys.Start, m.keys.Resume}
+	if m.active != nil {
+		help = []key.Binding{m.keys.Stop, m.keys.Pause}
+	}
+	s += helpLine(append(help, m.keys.Back, m.keys.Help, m.keys.Quit)...)
+	return s
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	"time-tracking/pkg/track"
+)
+
+const togglAPI = "https://api.track.toggl.com/api/v9"
+
+// togglConfig is the "toggl" section of config.json.
+type togglConfig struct {
+	// APIToken is from the Toggl profile page; TOGGL_API_TOKEN overrides it.
+	APIToken    string `json:"api_token,omitempty"`
+	WorkspaceID int64  `json:"workspace_id,omitempty"`
+	// Projects maps local project names to Toggl project names where they
+	// differ, e.g. "website": "Acme – Website". Unmapped names are used as is.
+	Projects map[string]string `json:"projects,omitempty"`
+}
+
+// togglEntry is a Toggl time entry as returned and accepted by the API.
+type togglEntry struct {
+	ID          int64      `json:"id,omitempty"`
+	WorkspaceID int64      `json:"workspace_id"`
+	ProjectID   *int64     `json:"project_id,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Start       time.Time  `json:"start"`
+	Stop        *time.Time `json:"stop,omitempty"`
+	Duration    int64      `json:"duration"` // seconds; negative while running
+	Tags        []string   `json:"tags,omitempty"`
+	Billable    bool       `json:"billable"`
+	CreatedWith string     `json:"created_with,omitempty"`
+}
+
+type togglProject struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// togglClient talks to the Toggl Track API for one workspace.
+type togglClient struct {
+	jsonAPI
+	workspace int64
+	// Project names by ID and IDs by name, filled by loadProjects.
+	names map[int64]string
+	ids   map[string]int64
+}
+
+func newTogglClient(cfg togglConfig) (*togglClient, error) {
+	token := cfg.APIToken
+	if env := os.Getenv("TOGGL_API_TOKEN"); env != "" {
+		token = env
+	}
+	if token == "" {
+		return nil, errors.New("no Toggl API token: set toggl.api_token in config.json or TOGGL_API_TOKEN")
+	}
+	api := jsonAPI{name: "toggl", base: togglAPI, auth: func(req *http.Request) error {
+		req.SetBasicAuth(token, "api_token")
+		return nil
+	}}
+	return &togglClient{jsonAPI: api, workspace: cfg.WorkspaceID}, nil
+}
+
+// loadProjects fetches the workspace's projects, first looking up the
+// default workspace if none is configured.
+func (c *togglClient) loadProjects() error {
+	if c.workspace == 0 {
+		var me struct {
+			DefaultWorkspaceID int64 `json:"default_workspace_id"`
+		}
+		if err := c.do(http.MethodGet, "/me", nil, &me); err != nil {
+			return err
+		}
+		c.workspace = me.DefaultWorkspaceID
+	}
+
+	var projects []togglProject
+	if err := c.do(http.MethodGet, fmt.Sprintf("/workspaces/%d/projects", c.workspace), nil, &projects); err != nil {
+		return err
+	}
+	c.names = make(map[int64]string)
+	c.ids = make(map[string]int64)
+	for _, p := range projects {
+		c.names[p.ID] = p.Name
+		c.ids[p.Name] = p.ID
+	}
+	return nil
+}
+
+// entries returns the finished time entries that start within r.
+func (c *togglClient) entries(r dateRange) ([]togglEntry, error) {
+	q := url.Values{}
+	if !r.From.IsZero() {
+		q.Set("start_date", r.From.Format(time.RFC3339))
+	}
+	if !r.To.IsZero() {
+		q.Set("end_date", r.To.Format(time.RFC3339))
+	}
+	var all []togglEntry
+	if err := c.do(http.MethodGet, "/me/time_entries?"+q.Encode(), nil, &all); err != nil {
+		return nil, err
+	}
+	var entries []togglEntry
+	for _, e := range all {
+		if e.Stop != nil && e.Duration >= 0 && (c.workspace == 0 || e.WorkspaceID == c.workspace) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (c *togglClient) create(e togglEntry) error {
+	return c.do(http.MethodPost, fmt.Sprintf("/workspaces/%d/time_entries", c.workspace), e, nil)
+}
+
+// toSession converts e to a session, mapping its Toggl project back to the
+// local name.
+func (c *togglClient) toSession(e togglEntry, cfg togglConfig) session {
+	sess := session{
+		ID:       track.NewID(),
+		Note:     e.Description,
+		Tags:     e.Tags,
+		Start:    e.Start.Local(),
+		End:      e.Stop.Local(),
+		Billable: e.Billable,
+	}
+	if e.ProjectID != nil {
+		sess.Project = c.names[*e.ProjectID]
+		for local, remote := range cfg.Projects {
+			if remote == sess.Project {
+				sess.Project = local
+				break
+			}
+		}
+	}
+	// Toggl's duration field can disagree with start and stop, e.g. after
+	// an edit in its web app; the times are what the session has to match.
+	sess.Duration = sess.End.Sub(sess.Start)
+	return sess
+}
+
+// toEntry converts sess to a new Toggl entry. Pauses are not represented in
+// Toggl, so the entry ends once the tracked time has elapsed.
+func (c *togglClient) toEntry(sess session, cfg togglConfig) (togglEntry, error) {
+	start := sess.Start.Truncate(time.Second).UTC()
+	stop := start.Add(sess.Duration.Truncate(time.Second))
+	e := togglEntry{
+		WorkspaceID: c.workspace,
+		Description: sess.Note,
+		Start:       start,
+		Stop:        &stop,
+		Duration:    int64(sess.Duration / time.Second),
+		Tags:        sess.Tags,
+		Billable:    sess.Billable,
+		CreatedWith: "time-tracker",
+	}
+	if sess.Project != "" {
+		name := sess.Project
+		if remote, ok := cfg.Projects[name]; ok {
+			name = remote
+		}
+		id, ok := c.ids[name]
+		if !ok {
+			return e, fmt.Errorf("no Toggl project %q for %s; add it in Toggl or map it under toggl.projects", name, sess.Project)
+		}
+		e.ProjectID = &id
+	}
+	return e, nil
+}
+
+// sameTogglEntry reports whether sess and e record the same work: the same
+// start and as long from there to e's stop. Toggl keeps whole seconds only,
+// so times are compared at that precision.
+func sameTogglEntry(sess session, e togglEntry) bool {
+	start := e.Start.Truncate(time.Second)
+	return sess.Start.Truncate(time.Second).Equal(start) &&
+		sess.Duration.Truncate(time.Second) == e.Stop.Truncate(time.Second).Sub(start)
+}
+
+// runToggl pulls time entries from Toggl Track into the history or pushes
+// local sessions to it, skipping entries present on both sides.
+func runToggl(storageKind string, args []string) error {
+	if len(args) == 0 || (args[0] != "pull" && args[0] != "push") {
+		return errors.New("usage: toggl pull|push [-range RANGE] [-dry-run]")
+	}
+	fs := flag.NewFlagSet("toggl "+args[0], flag.ExitOnError)
+	rangeSpec := fs.String("range", "month", "sync today, week, month, last-month or FROM..TO")
+	dryRun := fs.Bool("dry-run", false, "only list what would be synced")
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	dates, err := parseRange(*rangeSpec, time.Now())
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newTogglClient(cfg.Toggl)
+	if err != nil {
+		return err
+	}
+	if err := client.loadProjects(); err != nil {
+		return err
+	}
+	remote, err := client.entries(dates)
+	if err != nil {
+		return err
+	}
+
+	storage, err := openStorage(storageKind)
+	if err != nil {
+		return err
+	}
+	history, err := storage.Load()
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "pull" {
+		var pulled []session
+		for _, e := range remote {
+			if !slices.ContainsFunc(history, func(sess session) bool { return sameTogglEntry(sess, e) }) {
+				sess := client.toSession(e, cfg.Toggl)
+				cfg.categorize(&sess, "", "")
+				pulled = append(pulled, sess)
+			}
+		}
+		history, result := mergeSessions(history, pulled, importTolerance, false)
+		if *dryRun {
+			result.preview(cfg)
+			fmt.Printf("Would pull %d of %d entries%s\n", len(result.added), len(remote), result.summary())
+			return nil
+		}
+		if err := storage.Save(history); err != nil {
+			return err
+		}
+		if err := writeReport(historyFile, history, cfg); err != nil {
+			return err
+		}
+		fmt.Printf("Pulled %d of %d entries from Toggl%s\n", len(result.added), len(remote), result.summary())
+		return nil
+	}
+
+	pushed := 0
+	local := dates.Filter(history)
+	for _, sess := range local {
+		if slices.ContainsFunc(remote, func(e togglEntry) bool { return sameTogglEntry(sess, e) }) {
+			continue
+		}
+		e, err := client.toEntry(sess, cfg.Toggl)
+		if err != nil {
+			return err
+		}
+		if *dryRun {
+			fmt.Printf("%s  %s  %s\n", sess.Start.Format(csvTimeLayout), track.FormatClock(sess.Duration), track.ProjectLabel(sess.Project))
+		} else if err := client.create(e); err != nil {
+			return fmt.Errorf("pushed %d sessions before failing: %w", pushed, err)
+		}
+		pushed++
+	}
+	if *dryRun {
+		fmt.Printf("Would push %d of %d sessions\n", pushed, len(local))
+		return nil
+	}
+	fmt.Printf("Pushed %d of %d sessions to Toggl\n", pushed, len(local))
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTogglEntryTimes(t *testing.T) {
+	start := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+	// The duration field says two hours, start and stop one.
+	e := togglEntry{Start: start, Stop: &stop, Duration: 7200}
+
+	c := &togglClient{}
+	sess := c.toSession(e, togglConfig{})
+	if sess.Duration != time.Hour {
+		t.Errorf("duration = %s, want 1h from start to stop", sess.Duration)
+	}
+	if err := sess.Check(); err != nil {
+		t.Errorf("pulled session does not check: %v", err)
+	}
+	if !sameTogglEntry(sess, e) {
+		t.Error("pulled session does not match its entry")
+	}
+
+	// A pushed session matches its entry again when pulled, paused or not.
+	local := session{
+		Start:    start.Add(700 * time.Millisecond),
+		End:      start.Add(2 * time.Hour),
+		Duration: 90*time.Minute + 600*time.Millisecond,
+	}
+	pushed, err := c.toEntry(local, togglConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameTogglEntry(local, pushed) {
+		t.Errorf("session %s from %s does not match its entry %s–%s", local.Duration, local.Start, pushed.Start, pushed.Stop)
+	}
+}
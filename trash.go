@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"time-tracking/pkg/track"
+)
+
+// trashKeep is how long deleted sessions stay in the trash before they are
+// purged for good.
+const trashKeep = 30 * 24 * time.Hour
+
+// trashRecord is a deleted session in trashFile, with when it was deleted.
+type trashRecord struct {
+	Deleted time.Time    `json:"deleted"`
+	Session track.Record `json:"session"`
+}
+
+// expired reports whether rec has been in the trash longer than trashKeep.
+func (rec trashRecord) expired(now time.Time) bool {
+	return now.Sub(rec.Deleted) > trashKeep
+}
+
+// same reports whether rec and other are the same deletion.
+func (rec trashRecord) same(other trashRecord) bool {
+	return rec.Deleted.Equal(other.Deleted) && rec.Session.ID == other.Session.ID && rec.Session.Start.Equal(other.Session.Start)
+}
+
+// loadTrash reads the trash at path, leaving out sessions deleted longer
+// than trashKeep ago. A missing file is an empty trash.
+func loadTrash(path string, now time.Time) ([]trashRecord, error) {
+	data, err := readData(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var trash []trashRecord
+	if err := json.Unmarshal(data, &trash); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return slices.DeleteFunc(trash, func(rec trashRecord) bool { return rec.expired(now) }), nil
+}
+
+func saveTrash(path string, trash []trashRecord) error {
+	data, err := json.MarshalIndent(trash, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeData(path, data)
+}
+
+// deleted records that sessions were deleted in the audit log and queues
+// them for the trash, which like the audit log is written along with the
+// history.
+func (m *model) deleted(action string, sessions []session) {
+	m.audit(action, sessions, nil)
+	now := time.Now().Round(0)
+	for _, sess := range sessions {
+		m.untrashed = append(m.untrashed, trashRecord{Deleted: now, Session: track.ToRecord(sess)})
+	}
+}
+
+// flushTrash moves the queued deleted sessions into trashFile and takes the
+// restored ones out, purging any that have expired on the way.
+func (m *model) flushTrash() error {
+	if len(m.untrashed) == 0 && len(m.restored) == 0 {
+		return nil
+	}
+	restored := m.restored
+	m.restored = nil
+	err := purgeTrash(func(rec trashRecord) bool {
+		return slices.ContainsFunc(restored, rec.same)
+	}, m.untrashed...)
+	m.untrashed = nil
+	return err
+}
+
+// purgeTrash deletes the sessions in trashFile that match for good, and adds
+// any given.
+func purgeTrash(match func(trashRecord) bool, add ...trashRecord) error {
+	trash, err := loadTrash(trashFile, time.Now())
+	if err != nil {
+		return err
+	}
+	return saveTrash(trashFile, append(slices.DeleteFunc(trash, match), add...))
+}
+
+// openTrash shows the trash, newest deletion first, as it will be once
+// unsaved changes are written.
+func (m model) openTrash() (tea.Model, tea.Cmd) {
+	trash, err := loadTrash(trashFile, time.Now())
+	if err != nil {
+		m.status = err.Error()
+		return m, nil
+	}
+	trash = slices.DeleteFunc(trash, func(rec trashRecord) bool { return slices.ContainsFunc(m.restored, rec.same) })
+	trash = append(trash, m.untrashed...)
+	slices.Reverse(trash)
+	m.trash = trash
+	m.trashCursor = 0
+	m.currentView = trashView
+	m.status = ""
+	return m, nil
+}
+
+// dropFromView takes the entry under the cursor out of the trash view.
+func (m *model) dropFromView() {
+	m.trash = slices.Delete(m.trash, m.trashCursor, m.trashCursor+1)
+	m.trashCursor = max(0, min(m.trashCursor, len(m.trash)-1))
+}
+
+// restore puts the session under the cursor back into the history. It
+// leaves trashFile when the history is written, so it is never lost from
+// both.
+func (m model) restore() (tea.Model, tea.Cmd) {
+	rec := m.trash[m.trashCursor]
+	sess := track.FromRecord(rec.Session)
+	if spec, r, ok := m.config.closedPeriod(sess.Start); ok {
+		return m.unlockPrompt(spec, r)
+	}
+	m.flushDelete()
+	m.dropFromView()
+	m.restored = append(m.restored, rec)
+	at, _ := slices.BinarySearchFunc(m.history, sess.Start, func(s session, t time.Time) int { return s.Start.Compare(t) })
+	m.history = slices.Insert(m.history, at, sess)
+	m.audit(auditRestore, nil, []session{sess})
+	m.changed()
+	m.status = fmt.Sprintf("Restored %s, %s", track.ProjectLabel(sess.Project), sess.Start.Format("Jan 02 15:04"))
+	return m, nil
+}
+
+func (m model) updateTrash(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.status = ""
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m.quit()
+	case key.Matches(msg, m.keys.Back):
+		m.currentView = menuView
+		m.cursor = 0
+	case !key.Matches(msg, m.keys.Up, m.keys.Down) && m.blockedReadOnly():
+	case key.Matches(msg, m.keys.Up):
+		if m.trashCursor > 0 {
+			m.trashCursor--
+		}
+	case key.Matches(msg, m.keys.Down):
+		if m.trashCursor < len(m.trash)-1 {
+			m.trashCursor++
+		}
+	case key.Matches(msg, m.keys.Select), key.Matches(msg, m.keys.Undo):
+		if m.trashCursor < len(m.trash) {
+			return m.restore()
+		}
+	case key.Matches(msg, m.keys.Delete):
+		if m.trashCursor < len(m.trash) {
+			rec := m.trash[m.trashCursor]
+			return m.confirm("🗑  Purge session?",
+				m.config.describeRecord(rec.Session)+"\nwill be deleted for good. This cannot be undone.",
+				confirmOption{"n", "Cancel", cancelDialog},
+				confirmOption{"y", "Purge", func(m model) (tea.Model, tea.Cmd) {
+					if err := purgeTrash(rec.same); err != nil {
+						m.status = fmt.Sprintf("Purge failed: %v", err)
+						return m, nil
+					}
+					m.dropFromView()
+					m.audit(auditPurge, []session{track.FromRecord(rec.Session)}, nil)
+					m.flushAudit()
+					return m, nil
+				}},
+			)
+		}
+	case key.Matches(msg, m.keys.ClearAll):
+		if len(m.trash) > 0 {
+			return m.confirm("🗑  Empty trash?",
+				fmt.Sprintf("All %d sessions in the trash will be deleted for good. This cannot be undone.", len(m.trash)),
+				confirmOption{"n", "Cancel", cancelDialog},
+				confirmOption{"y", "Empty trash", func(m model) (tea.Model, tea.Cmd) {
+					var purged []session
+					for _, rec := range m.trash {
+						purged = append(purged, track.FromRecord(rec.Session))
+					}
+					if err := purgeTrash(func(trashRecord) bool { return true }); err != nil {
+						m.status = fmt.Sprintf("Purge failed: %v", err)
+						return m, nil
+					}
+					m.trash = nil
+					m.trashCursor = 0
+					m.audit(auditPurge, purged, nil)
+					m.flushAudit()
+					m.status = "Trash emptied"
+					return m, nil
+				}},
+			)
+		}
+	}
+	return m, nil
+}
+
+func (m model) viewTrash() string {
+	s := titleStyle.Render("🗑  Trash") + "\n\n"
+
+	if len(m.trash) == 0 {
+		s += normalStyle.Render("The trash is empty.") + "\n"
+	}
+	for i, rec := range m.trash {
+		line := fmt.Sprintf("%s · deleted %s", m.config.describeRecord(rec.Session), rec.Deleted.Local().Format("Jan 02 15:04"))
+		if i == m.trashCursor {
+			s += selectedStyle.Render("> "+line) + "\n"
+		} else {
+			s += historyItemStyle.Render("  "+line) + "\n"
+		}
+	}
+	s += "\n" + helpStyle.Render(fmt.Sprintf("Deleted sessions are kept for %d days.", int(trashKeep.Hours()/24))) + "\n"
+
+	if m.status != "" {
+		s += "\n" + normalStyle.Render(m.status) + "\n"
+	}
+	s += "\n" + helpLine(pairHelp(m.keys.Up, m.keys.Down, "navigate"), withDesc(m.keys.Select, "restore"),
+		withDesc(m.keys.Delete, "purge"), withDesc(m.keys.ClearAll, "empty trash"), m.keys.Back, m.keys.Help, m.keys.Quit)
+	return s
+}
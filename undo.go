@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// undoWindow is how long a deleted session can be restored with u before the
+// deletion is written to storage.
+const undoWindow = 5 * time.Second
+
+// pendingDelete is a session removed from the history view but not yet from
+// storage.
+type pendingDelete struct {
+	index int
+	sess  session
+}
+
+// flushDeleteMsg asks for the pending delete numbered seq to be written out.
+type flushDeleteMsg struct {
+	seq int
+}
+
+// deleteSession removes the session at index i from the in-memory history and
+// holds the deletion back for undoWindow so it can be undone. Any earlier
+// pending deletion is flushed first.
+func (m *model) deleteSession(i int) tea.Cmd {
+	m.flushDelete()
+
+	m.pending = &pendingDelete{index: i, sess: m.history[i]}
+	m.history = slices.Delete(m.history, i, i+1)
+	if !m.autoSave() {
+		m.dirty = true
+	}
+	m.deleteSeq++
+	m.status = fmt.Sprintf("Session deleted — press %s to undo", m.keys.Undo.Help().Key)
+
+	seq := m.deleteSeq
+	return tea.Tick(undoWindow, func(time.Time) tea.Msg {
+		return flushDeleteMsg{seq: seq}
+	})
+}
+
+// undoDelete puts the pending deleted session back where it was.
+func (m *model) undoDelete() bool {
+	if m.pending == nil {
+		return false
+	}
+	m.history = slices.Insert(m.history, m.pending.index, m.pending.sess)
+	m.pending = nil
+	m.status = "Deletion undone"
+	return true
+}
+
+// flushDelete writes the pending deletion, if any, to storage. With Auto-save
+// off it is left as an unsaved change instead.
+func (m *model) flushDelete() {
+	if m.pending == nil {
+		return
+	}
+	m.deleted(auditDelete, []session{m.pending.sess})
+	if !m.autoSave() {
+		m.pending = nil
+		m.dirty = true
+		return
+	}
+	m.storage.Delete(m.pending.sess.ID)
+	m.pending = nil
+	m.flushTrash()
+	m.flushAudit()
+	m.writeReport()
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Webhook events.
+const (
+	eventStart     = "start"
+	eventStop      = "stop"
+	eventThreshold = "threshold"
+)
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// webhook is a URL in config.json that is POSTed a webhookEvent as JSON when
+// tracking starts, stops, or a running session reaches one of Thresholds.
+type webhook struct {
+	URL string `json:"url"`
+	// Events limits the hook to some of "start", "stop" and "threshold";
+	// empty means all of them.
+	Events []string `json:"events,omitempty"`
+	// Thresholds are tracked times such as "4h" or "90m" at which a
+	// "threshold" event is sent.
+	Thresholds []string `json:"thresholds,omitempty"`
+
+	thresholds []time.Duration // parsed Thresholds
+}
+
+// parse checks the hook and fills in its parsed thresholds.
+func (h *webhook) parse() error {
+	if h.URL == "" {
+		return errors.New("webhook without a url")
+	}
+	for _, event := range h.Events {
+		if event != eventStart && event != eventStop && event != eventThreshold {
+			return fmt.Errorf("webhook %s: unknown event %q (want start, stop or threshold)", h.URL, event)
+		}
+	}
+	h.thresholds = nil
+	for _, s := range h.Thresholds {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("webhook %s: invalid threshold %q (want e.g. 4h or 90m)", h.URL, s)
+		}
+		h.thresholds = append(h.thresholds, d)
+	}
+	return nil
+}
+
+func (h webhook) wants(ev webhookEvent) bool {
+	if len(h.Events) > 0 && !slices.Contains(h.Events, ev.Event) {
+		return false
+	}
+	if ev.Event == eventThreshold {
+		return slices.Contains(h.thresholds, time.Duration(ev.ThresholdSeconds)*time.Second)
+	}
+	return true
+}
+
+// webhookEvent is the JSON payload sent to webhooks.
+type webhookEvent struct {
+	Event            string     `json:"event"`
+	Project          string     `json:"project,omitempty"`
+	Note             string     `json:"note,omitempty"`
+	Tags             []string   `json:"tags,omitempty"`
+	Start            time.Time  `json:"start"`
+	End              *time.Time `json:"end,omitempty"`
+	ElapsedSeconds   int64      `json:"elapsed_seconds"`
+	ThresholdSeconds int64      `json:"threshold_seconds,omitempty"`
+}
+
+func startEvent(a activeSession) webhookEvent {
+	return webhookEvent{
+		Event:   eventStart,
+		Project: a.project,
+		Note:    a.note,
+		Tags:    a.tags,
+		Start:   a.start,
+	}
+}
+
+func stopEvent(sess session) webhookEvent {
+	return webhookEvent{
+		Event:          eventStop,
+		Project:        sess.Project,
+		Note:           sess.Note,
+		Tags:           sess.Tags,
+		Start:          sess.Start,
+		End:            &sess.End,
+		ElapsedSeconds: int64(sess.Duration.Seconds()),
+	}
+}
+
+func thresholdEvent(a activeSession, elapsed, threshold time.Duration) webhookEvent {
+	return webhookEvent{
+		Event:            eventThreshold,
+		Project:          a.project,
+		Note:             a.note,
+		Tags:             a.tags,
+		Start:            a.start,
+		ElapsedSeconds:   int64(elapsed.Seconds()),
+		ThresholdSeconds: int64(threshold.Seconds()),
+	}
+}
+
+// sendWebhooks posts ev to every configured webhook that wants it.
+func (c config) sendWebhooks(ev webhookEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, h := range c.Webhooks {
+		if !h.wants(ev) {
+			continue
+		}
+		if err := postWebhook(h.URL, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func postWebhook(url string, body []byte) error {
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// crossedThresholds returns the webhook thresholds passed when a session's
+// tracked time went from before to now.
+func (c config) crossedThresholds(before, now time.Duration) []time.Duration {
+	var crossed []time.Duration
+	for _, h := range c.Webhooks {
+		for _, d := range h.thresholds {
+			if before < d && d <= now && !slices.Contains(crossed, d) {
+				crossed = append(crossed, d)
+			}
+		}
+	}
+	slices.Sort(crossed)
+	return crossed
+}
+
+// webhookErrMsg reports a failed webhook delivery from the TUI.
+type webhookErrMsg struct {
+	err error
+}
+
+// webhookCmd sends ev to the webhooks in the background, or is nil if none
+// are configured.
+func (m model) webhookCmd(ev webhookEvent) tea.Cmd {
+	if len(m.config.Webhooks) == 0 {
+		return nil
+	}
+	cfg := m.config
+	return func() tea.Msg {
+		if err := cfg.sendWebhooks(ev); err != nil {
+			return webhookErrMsg{err}
+		}
+		return nil
+	}
+}
+
+// thresholdWebhooks returns commands for the webhook thresholds the running
+// session passed since its tracked time was before. The daemon sends these
+// itself when it is running.
+func (m model) thresholdWebhooks(before time.Duration) tea.Cmd {
+	crossed := m.config.crossedThresholds(before, m.elapsed)
+	if len(crossed) == 0 || daemonRunning() {
+		return nil
+	}
+	var cmds []tea.Cmd
+	for _, d := range crossed {
+		cmds = append(cmds, m.webhookCmd(thresholdEvent(*m.active, m.elapsed, d)))
+	}
+	return tea.Batch(cmds...)
+}